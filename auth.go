@@ -0,0 +1,185 @@
+package cql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// RegisterAuthenticator registers an authenticator factory that can be used with the auth=name config string key.
+// The auth.name=value config string keys are passed to the factory as params.
+// The built-in password authenticator takes auth.username, auth.password, and auth.allowed.
+func RegisterAuthenticator(name string, factory AuthenticatorFactory) error {
+	if name == "" || strings.ContainsAny(name, "&=?") {
+		return ErrAuthenticatorNameInvalid
+	}
+	if factory == nil {
+		return ErrAuthenticatorFactoryIsNil
+	}
+
+	authenticatorRegistry.mutex.Lock()
+	authenticatorRegistry.factories[name] = factory
+	authenticatorRegistry.mutex.Unlock()
+	return nil
+}
+
+// DeregisterAuthenticator removes a registered authenticator factory
+func DeregisterAuthenticator(name string) {
+	authenticatorRegistry.mutex.Lock()
+	delete(authenticatorRegistry.factories, name)
+	authenticatorRegistry.mutex.Unlock()
+}
+
+// NewTokenAuthenticatorFactory returns an authenticator factory that calls callback for the username and token
+// every time a new connection authenticates, so the token can be refreshed.
+// The auth params, other than auth.allowed, are passed to callback.
+func NewTokenAuthenticatorFactory(callback func(params map[string]string) (username string, token string, err error)) AuthenticatorFactory {
+	return func(params map[string]string) (gocql.Authenticator, error) {
+		callbackParams := make(map[string]string, len(params))
+		for key, value := range params {
+			if key != "allowed" {
+				callbackParams[key] = value
+			}
+		}
+		allowed, err := allowedAuthenticators(params)
+		if err != nil {
+			return nil, err
+		}
+		return &plainAuthenticatorStruct{
+			allowed: allowed,
+			credentials: func() (string, string, error) {
+				return callback(callbackParams)
+			},
+		}, nil
+	}
+}
+
+// newAuthenticator returns the registered authenticator for the name and params
+func newAuthenticator(name string, params map[string]string) (gocql.Authenticator, error) {
+	authenticatorRegistry.mutex.RLock()
+	factory, ok := authenticatorRegistry.factories[name]
+	authenticatorRegistry.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("failed for: auth = %v", name)
+	}
+
+	authenticator, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("auth %v error: %v", name, err)
+	}
+	return &registeredAuthenticatorStruct{Authenticator: authenticator, name: name, params: params}, nil
+}
+
+// authenticatorToConfig returns the config string of an authenticator created by newAuthenticator
+func authenticatorToConfig(authenticator gocql.Authenticator) string {
+	registeredAuthenticator, ok := authenticator.(*registeredAuthenticatorStruct)
+	if !ok {
+		return ""
+	}
+
+	stringConfig := "auth=" + registeredAuthenticator.name
+	if paramsConfig := authParamsToConfig(registeredAuthenticator.params); paramsConfig != "" {
+		stringConfig += "&" + paramsConfig
+	}
+	return stringConfig
+}
+
+// authParamsToConfig returns the auth.name=value config string of the auth params sorted by name.
+// The values are not masked, use redactAuthenticator first for a config string that is logged.
+func authParamsToConfig(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	settings := make([]string, len(keys))
+	for i, key := range keys {
+		settings[i] = "auth." + key + "=" + escapeValue("auth."+key, params[key])
+	}
+	return strings.Join(settings, "&")
+}
+
+// redactAuthenticator returns a copy of an authenticator created by newAuthenticator with the params,
+// other than username and allowed, masked
+func redactAuthenticator(authenticator gocql.Authenticator) gocql.Authenticator {
+	registeredAuthenticator, ok := authenticator.(*registeredAuthenticatorStruct)
+	if !ok {
+		return authenticator
+	}
+
+	params := make(map[string]string, len(registeredAuthenticator.params))
+	for key, value := range registeredAuthenticator.params {
+		if key != "username" && key != "allowed" {
+			value = redactedValue
+		}
+		params[key] = value
+	}
+	return &registeredAuthenticatorStruct{name: registeredAuthenticator.name, params: params}
+}
+
+// passwordAuthenticatorFactory is the built-in password authenticator
+func passwordAuthenticatorFactory(params map[string]string) (gocql.Authenticator, error) {
+	for key := range params {
+		if key != "username" && key != "password" && key != "allowed" {
+			return nil, fmt.Errorf("invalid key: auth.%v", key)
+		}
+	}
+	allowed, err := allowedAuthenticators(params)
+	if err != nil {
+		return nil, err
+	}
+	username, password := params["username"], params["password"]
+	return &plainAuthenticatorStruct{
+		allowed: allowed,
+		credentials: func() (string, string, error) {
+			return username, password, nil
+		},
+	}, nil
+}
+
+// allowedAuthenticators returns the comma separated auth.allowed param or a copy of DefaultAllowedAuthenticators,
+// an auth.allowed without any names is an error since no server authenticator would be answered
+func allowedAuthenticators(params map[string]string) ([]string, error) {
+	value, ok := params["allowed"]
+	if !ok {
+		return append([]string(nil), DefaultAllowedAuthenticators...), nil
+	}
+	var allowed []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed = append(allowed, name)
+		}
+	}
+	if len(allowed) < 1 {
+		return nil, fmt.Errorf("invalid value: auth.allowed")
+	}
+	return allowed, nil
+}
+
+// Challenge returns the SASL PLAIN response if the server authenticator is allowed
+func (plainAuthenticator *plainAuthenticatorStruct) Challenge(req []byte) ([]byte, gocql.Authenticator, error) {
+	allowed := false
+	for _, name := range plainAuthenticator.allowed {
+		if name == string(req) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, nil, fmt.Errorf("unexpected authenticator %q", req)
+	}
+
+	username, password, err := plainAuthenticator.credentials()
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte("\x00" + username + "\x00" + password), nil, nil
+}
+
+// Success is called when the authentication succeeded
+func (plainAuthenticator *plainAuthenticatorStruct) Success(data []byte) error {
+	return nil
+}
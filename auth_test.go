@@ -0,0 +1,215 @@
+package cql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/MichaelS11/go-cql-driver/cqltest"
+	"github.com/gocql/gocql"
+)
+
+func TestRegisterAuthenticator(t *testing.T) {
+	factory := func(params map[string]string) (gocql.Authenticator, error) {
+		return gocql.PasswordAuthenticator{Username: params["username"]}, nil
+	}
+
+	err := RegisterAuthenticator("", factory)
+	if err != ErrAuthenticatorNameInvalid {
+		t.Fatalf("RegisterAuthenticator error - received: %v - expected: %v ", err, ErrAuthenticatorNameInvalid)
+	}
+	err = RegisterAuthenticator("a&b", factory)
+	if err != ErrAuthenticatorNameInvalid {
+		t.Fatalf("RegisterAuthenticator error - received: %v - expected: %v ", err, ErrAuthenticatorNameInvalid)
+	}
+	err = RegisterAuthenticator("test", nil)
+	if err != ErrAuthenticatorFactoryIsNil {
+		t.Fatalf("RegisterAuthenticator error - received: %v - expected: %v ", err, ErrAuthenticatorFactoryIsNil)
+	}
+
+	err = RegisterAuthenticator("test", factory)
+	if err != nil {
+		t.Fatalf("RegisterAuthenticator error - received: %v - expected: %v ", err, nil)
+	}
+	clusterConfig, err := ConfigStringToClusterConfig("?auth=test&auth.username=alice")
+	if err != nil {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, nil)
+	}
+	registeredAuthenticator, ok := clusterConfig.Authenticator.(*registeredAuthenticatorStruct)
+	if !ok {
+		t.Fatalf("Authenticator - received: %T - expected: %v ", clusterConfig.Authenticator, "*registeredAuthenticatorStruct")
+	}
+	expected := gocql.PasswordAuthenticator{Username: "alice"}
+	if registeredAuthenticator.Authenticator != expected {
+		t.Fatalf("Authenticator - received: %v - expected: %v ", registeredAuthenticator.Authenticator, expected)
+	}
+
+	DeregisterAuthenticator("test")
+	_, err = ConfigStringToClusterConfig("?auth=test")
+	expectedError := "failed for: auth = test"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, expectedError)
+	}
+}
+
+func TestConfigStringAuth(t *testing.T) {
+	tests := []struct {
+		info           string
+		configString   string
+		toConfigString string
+		redacted       string
+		err            error
+	}{
+		{info: "empty auth", configString: "?auth=", err: fmt.Errorf("failed for: auth = ")},
+		{info: "unknown auth", configString: "?auth=foo", err: fmt.Errorf("failed for: auth = foo")},
+		{info: "invalid key", configString: "?auth.=foo", err: fmt.Errorf("invalid key: auth.")},
+		{info: "invalid param", configString: "?auth=password&auth.password=%GG", err: fmt.Errorf("failed for: auth.password")},
+		{info: "params without auth", configString: "?auth.username=alice", err: fmt.Errorf("auth settings require auth")},
+		{info: "auth with username", configString: "?username=alice&auth=password", err: fmt.Errorf("auth can not be used with username or password")},
+		{info: "auth with URL user", configString: "cql://alice@one?auth=password", err: fmt.Errorf("auth can not be used with username or password")},
+		{info: "factory error", configString: "?auth=password&auth.foo=bar", err: fmt.Errorf("auth password error: invalid key: auth.foo")},
+		{info: "empty allowed", configString: "?auth=password&auth.allowed=", err: fmt.Errorf("auth password error: invalid value: auth.allowed")},
+		{info: "allowed without names", configString: "?auth=password&auth.allowed=%2C+", err: fmt.Errorf("auth password error: invalid value: auth.allowed")},
+
+		{info: "password", configString: "?auth=password&auth.username=alice%40bob.com&auth.password=top%24ecret",
+			toConfigString: "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&auth=password&auth.password=top%24ecret&auth.username=alice%40bob.com",
+			redacted:       "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&auth=password&auth.password=xxxxx&auth.username=alice%40bob.com"},
+		{info: "password allowed", configString: "?auth=password&auth.allowed=com.example.Authenticator",
+			toConfigString: "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&auth=password&auth.allowed=com.example.Authenticator",
+			redacted:       "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&auth=password&auth.allowed=com.example.Authenticator"},
+	}
+
+	for _, test := range tests {
+		clusterConfig, err := ConfigStringToClusterConfig(test.configString)
+		if err == nil || test.err == nil {
+			if err != test.err {
+				t.Errorf("error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+				continue
+			}
+		} else if err.Error() != test.err.Error() {
+			t.Errorf("error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+			continue
+		}
+		if test.err != nil {
+			continue
+		}
+
+		configString := ClusterConfigToConfigString(clusterConfig)
+		if configString != test.toConfigString {
+			t.Errorf("configString - received: %v - expected: %v - info: %v", configString, test.toConfigString, test.info)
+		}
+		configString = RedactedConfigString(clusterConfig)
+		if configString != test.redacted {
+			t.Errorf("RedactedConfigString - received: %v - expected: %v - info: %v", configString, test.redacted, test.info)
+		}
+	}
+}
+
+func TestPlainAuthenticator(t *testing.T) {
+	clusterConfig, err := ConfigStringToClusterConfig("?auth=password&auth.username=alice&auth.password=secret")
+	if err != nil {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, nil)
+	}
+
+	response, _, err := clusterConfig.Authenticator.Challenge([]byte("com.scylladb.auth.TransitionalAuthenticator"))
+	if err != nil {
+		t.Fatalf("Challenge error - received: %v - expected: %v ", err, nil)
+	}
+	if string(response) != "\x00alice\x00secret" {
+		t.Fatalf("Challenge - received: %q - expected: %q ", response, "\x00alice\x00secret")
+	}
+
+	// the default allowed authenticators are copied
+	allowed, err := allowedAuthenticators(map[string]string{})
+	if err != nil {
+		t.Fatalf("allowedAuthenticators error - received: %v - expected: %v ", err, nil)
+	}
+	expected := DefaultAllowedAuthenticators[0]
+	allowed[0] = "com.example.Authenticator"
+	if DefaultAllowedAuthenticators[0] != expected {
+		t.Fatalf("DefaultAllowedAuthenticators - received: %v - expected: %v ", DefaultAllowedAuthenticators[0], expected)
+	}
+
+	clusterConfig, err = ConfigStringToClusterConfig("?auth=password&auth.allowed=com.example.Authenticator")
+	if err != nil {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, nil)
+	}
+	_, _, err = clusterConfig.Authenticator.Challenge([]byte("org.apache.cassandra.auth.PasswordAuthenticator"))
+	expectedError := `unexpected authenticator "org.apache.cassandra.auth.PasswordAuthenticator"`
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("Challenge error - received: %v - expected: %v ", err, expectedError)
+	}
+	_, _, err = clusterConfig.Authenticator.Challenge([]byte("com.example.Authenticator"))
+	if err != nil {
+		t.Fatalf("Challenge error - received: %v - expected: %v ", err, nil)
+	}
+
+	factory := NewTokenAuthenticatorFactory(func(params map[string]string) (string, string, error) {
+		return "app", "token", nil
+	})
+	_, err = factory(map[string]string{"allowed": " , "})
+	expectedError = "invalid value: auth.allowed"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("factory error - received: %v - expected: %v ", err, expectedError)
+	}
+}
+
+func TestSqlAuthenticator(t *testing.T) {
+	server, err := cqltest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+	server.SetAuthenticator("com.scylladb.auth.TransitionalAuthenticator")
+
+	token := "token1"
+	calls := 0
+	err = RegisterAuthenticator("testToken", NewTokenAuthenticatorFactory(func(params map[string]string) (string, string, error) {
+		calls++
+		return params["role"], token, nil
+	}))
+	if err != nil {
+		t.Fatalf("RegisterAuthenticator error - received: %v - expected: %v ", err, nil)
+	}
+	defer DeregisterAuthenticator("testToken")
+
+	queryLocal := func(settings string) error {
+		db, err := sql.Open("cql", server.Addr()+"?timeout=2s&connectTimeout=2s&"+settings)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+		defer cancel()
+		var key string
+		return db.QueryRowContext(ctx, "select key from system.local where key = ?", "local").Scan(&key)
+	}
+
+	server.SetCredentials("alice", "secret")
+	err = queryLocal("username=alice&password=secret")
+	if err == nil {
+		t.Fatalf("queryLocal error - received: %v - expected: %v ", err, "unexpected authenticator")
+	}
+	err = queryLocal("auth=password&auth.username=alice&auth.password=secret")
+	if err != nil {
+		t.Fatalf("queryLocal error - received: %v - expected: %v ", err, nil)
+	}
+
+	server.SetCredentials("app", "token1")
+	err = queryLocal("auth=testToken&auth.role=app")
+	if err != nil {
+		t.Fatalf("queryLocal error - received: %v - expected: %v ", err, nil)
+	}
+	callsBefore := calls
+
+	token = "token2"
+	server.SetCredentials("app", "token2")
+	err = queryLocal("auth=testToken&auth.role=app")
+	if err != nil {
+		t.Fatalf("queryLocal error - received: %v - expected: %v ", err, nil)
+	}
+	if calls <= callsBefore {
+		t.Fatalf("calls - received: %v - expected: > %v ", calls, callsBefore)
+	}
+}
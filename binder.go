@@ -0,0 +1,51 @@
+package cql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// bind is the gocql binding callback, it maps the named values to the prepared statement bind markers.
+// Values without a name are bound by ordinal position.
+func (binder *namedBinderStruct) bind(queryInfo *gocql.QueryInfo) ([]interface{}, error) {
+	values := make([]interface{}, len(queryInfo.Args))
+	used := make([]bool, len(binder.namedValues))
+
+	for i := 0; i < len(queryInfo.Args); i++ {
+		found := false
+		for j := 0; j < len(binder.namedValues); j++ {
+			namedValue := binder.namedValues[j]
+			if len(namedValue.Name) > 0 {
+				if namedValue.Name != queryInfo.Args[i].Name && strings.ToLower(namedValue.Name) != queryInfo.Args[i].Name {
+					continue
+				}
+			} else if namedValue.Ordinal != i+1 {
+				continue
+			}
+			values[i] = namedValue.Value
+			used[j] = true
+			found = true
+			break
+		}
+		if !found {
+			binder.err = fmt.Errorf("%w: %v", ErrNamedValueMissing, queryInfo.Args[i].Name)
+			return nil, binder.err
+		}
+	}
+
+	for j := 0; j < len(binder.namedValues); j++ {
+		if used[j] {
+			continue
+		}
+		if len(binder.namedValues[j].Name) > 0 {
+			binder.err = fmt.Errorf("%w: %v", ErrNamedValueUnknown, binder.namedValues[j].Name)
+		} else {
+			binder.err = ErrOrdinalOutOfRange
+		}
+		return nil, binder.err
+	}
+
+	return values, nil
+}
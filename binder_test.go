@@ -0,0 +1,45 @@
+package cql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+func TestNamedBinderBind(t *testing.T) {
+	queryInfo := &gocql.QueryInfo{
+		Args: []gocql.ColumnInfo{{Name: "id"}, {Name: "name"}, {Name: "id"}},
+	}
+
+	tests := []struct {
+		info        string
+		namedValues []driver.NamedValue
+		values      []interface{}
+		err         error
+	}{
+		{info: "named", namedValues: []driver.NamedValue{{Name: "name", Ordinal: 1, Value: "a"}, {Name: "id", Ordinal: 2, Value: 1}}, values: []interface{}{1, "a", 1}},
+		{info: "named upper case", namedValues: []driver.NamedValue{{Name: "Name", Ordinal: 1, Value: "a"}, {Name: "ID", Ordinal: 2, Value: 1}}, values: []interface{}{1, "a", 1}},
+		{info: "named and ordinal", namedValues: []driver.NamedValue{{Ordinal: 1, Value: 2}, {Name: "name", Ordinal: 2, Value: "b"}, {Ordinal: 3, Value: 3}}, values: []interface{}{2, "b", 3}},
+		{info: "missing", namedValues: []driver.NamedValue{{Name: "id", Ordinal: 1, Value: 1}}, err: ErrNamedValueMissing},
+		{info: "unknown", namedValues: []driver.NamedValue{{Name: "id", Ordinal: 1, Value: 1}, {Name: "name", Ordinal: 2, Value: "a"}, {Name: "other", Ordinal: 3, Value: "c"}}, err: ErrNamedValueUnknown},
+		{info: "ordinal out of range", namedValues: []driver.NamedValue{{Name: "id", Ordinal: 1, Value: 1}, {Name: "name", Ordinal: 2, Value: "a"}, {Ordinal: 4, Value: "c"}}, err: ErrOrdinalOutOfRange},
+	}
+
+	for _, test := range tests {
+		binder := &namedBinderStruct{namedValues: test.namedValues}
+		values, err := binder.bind(queryInfo)
+		if !errors.Is(err, test.err) {
+			t.Errorf("bind error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+			continue
+		}
+		if binder.err != err {
+			t.Errorf("binder err - received: %v - expected: %v - info: %v", binder.err, err, test.info)
+		}
+		if !reflect.DeepEqual(values, test.values) {
+			t.Errorf("values - received: %#v - expected: %#v - info: %v", values, test.values, test.info)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package cql
+
+import (
+	"encoding/binary"
+
+	"github.com/MichaelS11/go-cql-driver/internal/lz4"
+)
+
+// Name returns the native protocol compression name
+func (lz4Compressor LZ4Compressor) Name() string {
+	return "lz4"
+}
+
+// Encode compresses a frame body, the native protocol prefixes the lz4 block with the uncompressed length
+func (lz4Compressor LZ4Compressor) Encode(data []byte) ([]byte, error) {
+	block, err := lz4.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, 4, 4+len(block))
+	binary.BigEndian.PutUint32(body, uint32(len(data)))
+	return append(body, block...), nil
+}
+
+// Decode decompresses a frame body
+func (lz4Compressor LZ4Compressor) Decode(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, ErrLZ4BodyTooShort
+	}
+	return lz4.Decode(data[4:], int(binary.BigEndian.Uint32(data)))
+}
@@ -0,0 +1,42 @@
+package cql
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLZ4Compressor(t *testing.T) {
+	compressor := LZ4Compressor{}
+	if compressor.Name() != "lz4" {
+		t.Fatalf("Name - received: %v - expected: %v ", compressor.Name(), "lz4")
+	}
+
+	data := bytes.Repeat([]byte("select * from cqltest.table where id = ?"), 100)
+	body, err := compressor.Encode(data)
+	if err != nil {
+		t.Fatalf("Encode error - received: %v - expected: %v ", err, nil)
+	}
+	if len(body) >= len(data) {
+		t.Fatalf("Encode length - received: %v - expected less than: %v ", len(body), len(data))
+	}
+	if !bytes.Equal(body[:4], []byte{0, 0, 0x0F, 0xA0}) {
+		t.Fatalf("Encode length prefix - received: %v - expected: %v ", body[:4], []byte{0, 0, 0x0F, 0xA0})
+	}
+
+	decoded, err := compressor.Decode(body)
+	if err != nil {
+		t.Fatalf("Decode error - received: %v - expected: %v ", err, nil)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatalf("Decode - received: %s - expected: %s ", decoded, data)
+	}
+
+	_, err = compressor.Decode([]byte{0, 0})
+	if err != ErrLZ4BodyTooShort {
+		t.Fatalf("Decode error - received: %v - expected: %v ", err, ErrLZ4BodyTooShort)
+	}
+	_, err = compressor.Decode([]byte{0, 0, 0, 10, 0x10})
+	if err == nil {
+		t.Fatalf("Decode error - received: %v - expected: %v ", err, "lz4: corrupt input")
+	}
+}
@@ -2,29 +2,102 @@ package cql
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+
+	"github.com/gocql/gocql"
 )
 
-// Close a database connection
+// Close a database connection, the shared session is closed when the last connection using it is closed
 func (cqlConn *cqlConnStruct) Close() error {
-	if cqlConn.session != nil {
-		cqlConn.session.Close()
-		cqlConn.session = nil
+	cqlConn.releaseSession()
+	if cqlConn.cancel != nil {
+		cqlConn.cancel()
+	}
+	return nil
+}
+
+// releaseSession releases the shared session, the connection context is not canceled
+// so the connection can acquire a session again
+func (cqlConn *cqlConnStruct) releaseSession() {
+	if cqlConn.sharedSession != nil {
+		sessionRegistry.release(cqlConn.sharedSession)
+		cqlConn.sharedSession = nil
+	}
+	cqlConn.session = nil
+	cqlConn.pingQuery = nil
+	cqlConn.tx = nil
+}
+
+// IsValid returns false when the connection session is nil or closed so database/sql discards the connection
+func (cqlConn *cqlConnStruct) IsValid() bool {
+	return cqlConn.session != nil && !cqlConn.session.Closed()
+}
+
+// ResetSession clears the connection state before the connection is reused.
+// It returns driver.ErrBadConn when the connection context is canceled or the session is closed.
+// A keyspace changed with a use statement is changed back to the config keyspace,
+// statements prepared while the keyspace was changed return driver.ErrBadConn.
+func (cqlConn *cqlConnStruct) ResetSession(ctx context.Context) error {
+	if cqlConn.context != nil && cqlConn.context.Err() != nil {
+		return driver.ErrBadConn
+	}
+	if cqlConn.session != nil && cqlConn.session.Closed() {
+		return driver.ErrBadConn
+	}
+	cqlConn.tx = nil
+	if cqlConn.useConfig != nil {
+		cqlConn.releaseSession()
+		cqlConn.useConfig = nil
 	}
 	return nil
 }
 
-// Ping a database connection
+// useKeyspace changes the connection keyspace, gocql does not support use statements
+// so the connection uses a shared session with the keyspace until ResetSession
+func (cqlConn *cqlConnStruct) useKeyspace(ctx context.Context, query string) error {
+	if cqlConn.tx != nil {
+		return ErrTxStatementNotSupported
+	}
+	keyspace := useStatementKeyspace(query)
+	if keyspace == "" {
+		return ErrUseKeyspaceMissing
+	}
+
+	useConfig, err := keyspaceClusterConfig(cqlConn.clusterConfig, keyspace)
+	if err != nil {
+		return err
+	}
+	sharedSession, err := sessionRegistry.acquire(ctx, useConfig)
+	if err != nil {
+		return err
+	}
+
+	cqlConn.releaseSession()
+	cqlConn.sharedSession = sharedSession
+	cqlConn.session = sharedSession.session
+	cqlConn.pingQuery = cqlConn.session.Query("select cql_version from system.local")
+	cqlConn.useConfig = useConfig
+	return nil
+}
+
+// Ping a database connection.
+// On error the session is released and driver.ErrBadConn returned, the connection context is not canceled.
 func (cqlConn *cqlConnStruct) Ping(ctx context.Context) error {
 	var err error
 
 	if cqlConn.session == nil {
-		cqlConn.session, err = cqlConn.clusterConfig.CreateSession()
+		sessionConfig := cqlConn.clusterConfig
+		if cqlConn.useConfig != nil {
+			sessionConfig = cqlConn.useConfig
+		}
+		cqlConn.sharedSession, err = sessionRegistry.acquire(ctx, sessionConfig)
 		if err != nil {
-			cqlConn.Close()
+			cqlConn.releaseSession()
 			cqlConn.logger.Print("Ping CreateSession error: ", err)
 			return driver.ErrBadConn
 		}
+		cqlConn.session = cqlConn.sharedSession.session
 		cqlConn.pingQuery = cqlConn.session.Query("select cql_version from system.local")
 	}
 
@@ -33,42 +106,38 @@ func (cqlConn *cqlConnStruct) Ping(ctx context.Context) error {
 	rowData, err := iter.RowData()
 	if err != nil {
 		iter.Close()
-		cqlConn.Close()
+		cqlConn.releaseSession()
 		cqlConn.logger.Print("Ping RowData error: ", err)
 		return driver.ErrBadConn
 	}
 	if len(rowData.Values) != 1 {
 		iter.Close()
-		cqlConn.Close()
+		cqlConn.releaseSession()
 		cqlConn.logger.Print("Ping len(Values) != 1")
 		return driver.ErrBadConn
 	}
 
 	if !iter.Scan(rowData.Values...) {
 		err = iter.Close()
-		if err != nil {
-			cqlConn.Close()
-		} else {
-			err = cqlConn.Close()
-		}
+		cqlConn.releaseSession()
 		cqlConn.logger.Print("Ping Scan error: ", err)
 		return driver.ErrBadConn
 	}
 	err = iter.Close()
 	if err != nil {
-		cqlConn.Close()
+		cqlConn.releaseSession()
 		cqlConn.logger.Print("Ping iter Close error: ", err)
 		return driver.ErrBadConn
 	}
 
 	data, ok := rowData.Values[0].(*string)
 	if !ok {
-		cqlConn.Close()
+		cqlConn.releaseSession()
 		cqlConn.logger.Print("Ping Value not *string")
 		return driver.ErrBadConn
 	}
 	if len(*data) < 1 {
-		cqlConn.Close()
+		cqlConn.releaseSession()
 		cqlConn.logger.Print("Ping len(data) < 1")
 		return driver.ErrBadConn
 	}
@@ -78,10 +147,11 @@ func (cqlConn *cqlConnStruct) Ping(ctx context.Context) error {
 
 // Prepare a query, uses connection conntext
 func (cqlConn *cqlConnStruct) Prepare(query string) (driver.Stmt, error) {
-	return cqlConn.PrepareContext(cqlConn.context, query)
+	return cqlConn.PrepareContext(cqlConn.baseContext(), query)
 }
 
-// Prepare a query with context
+// Prepare a query with context.
+// Statements that can be prepared are prepared on the cluster, so invalid statements return an error here.
 func (cqlConn *cqlConnStruct) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
 	var err error
 
@@ -92,17 +162,160 @@ func (cqlConn *cqlConnStruct) PrepareContext(ctx context.Context, query string)
 		}
 	}
 
+	prepareCtx, cancel := cqlConn.queryTimeoutContext(ctx)
+	queryInfo, err := cqlConn.prepare(prepareCtx, query)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CqlStmt{
+		CqlQuery:    cqlConn.session.Query(query).WithContext(ctx),
+		QueryInfo:   queryInfo,
+		session:     cqlConn.session,
+		conn:        cqlConn,
+		numInput:    numInput(query),
+		conditional: isConditional(query),
+	}, nil
+}
+
+// ExecContext executes a query with context without a prepared statement handle,
+// in a transaction the query is added to the transaction batch.
+// A use statement changes the keyspace of the connection until it is returned to the pool.
+func (cqlConn *cqlConnStruct) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if cqlConn.session == nil {
+		err := cqlConn.Ping(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if statementType(query) == "use" {
+		err := cqlConn.useKeyspace(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return cqlResultStruct{}, nil
+	}
+
+	cqlStmt, err := cqlConn.directStmt(query, args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := cqlStmt.execContext(ctx, args)
+	cqlStmt.Close()
+	return result, err
+}
+
+// QueryContext queries a query with context without a prepared statement handle
+func (cqlConn *cqlConnStruct) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if cqlConn.session == nil {
+		err := cqlConn.Ping(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cqlStmt, err := cqlConn.directStmt(query, args)
+	if err != nil {
+		return nil, err
+	}
+	// the query is not released, the rows iter uses it for paging
+	return cqlStmt.queryContext(ctx, args)
+}
+
+// directStmt returns a statement for the query that is not prepared on the cluster,
+// gocql prepares it when executed if the statement type can be prepared.
+// Returns driver.ErrSkip when the number of positional args is wrong so database/sql returns its prepared statement error,
+// named args are checked by name when they are bound.
+func (cqlConn *cqlConnStruct) directStmt(query string, args []driver.NamedValue) (*CqlStmt, error) {
+	markerCount := bindMarkerCount(query)
+	if markerCount >= 0 && markerCount != len(args) && !hasNamedValues(args) {
+		return nil, driver.ErrSkip
+	}
+
 	return &CqlStmt{
-		CqlQuery: cqlConn.session.Query(query).WithContext(ctx),
+		CqlQuery:    cqlConn.session.Query(query),
+		session:     cqlConn.session,
+		conn:        cqlConn,
+		numInput:    numInput(query),
+		conditional: isConditional(query),
 	}, nil
 }
 
-// Begin not supported
+// CheckNamedValue converts named values with the driver ValueConverter, same as the statement CheckNamedValue
+func (cqlConn *cqlConnStruct) CheckNamedValue(namedValue *driver.NamedValue) error {
+	var err error
+	namedValue.Value, err = converter{}.ConvertValue(namedValue.Value)
+	return err
+}
+
+// Begin starts a transaction, uses connection context
 func (cqlConn *cqlConnStruct) Begin() (driver.Tx, error) {
-	return nil, ErrNotSupported
+	return cqlConn.BeginTx(cqlConn.baseContext(), driver.TxOptions{})
 }
 
-// BeginTx not supported
+// BeginTx starts a transaction with context.
+// Statements executed in the transaction are buffered and sent as one batch on commit.
+// The batch type defaults to logged and can be changed with WithBatchType or the TxOptions Isolation
+// using LevelLoggedBatch, LevelUnloggedBatch, or LevelCounterBatch.
+// Read only transactions and other isolation levels are not supported.
 func (cqlConn *cqlConnStruct) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	return nil, ErrNotSupported
+	if opts.ReadOnly {
+		return nil, ErrNotSupported
+	}
+
+	batchType := gocql.LoggedBatch
+	queryOptions := queryOptionsFromContext(ctx)
+	if queryOptions.batchType != nil {
+		batchType = *queryOptions.batchType
+	}
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault:
+	case LevelLoggedBatch:
+		batchType = gocql.LoggedBatch
+	case LevelUnloggedBatch:
+		batchType = gocql.UnloggedBatch
+	case LevelCounterBatch:
+		batchType = gocql.CounterBatch
+	default:
+		return nil, ErrNotSupported
+	}
+
+	if cqlConn.session == nil {
+		err := cqlConn.Ping(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	batch := cqlConn.session.NewBatch(batchType).WithContext(ctx)
+	applyBatchOptions(ctx, batch)
+
+	cqlConn.tx = &cqlTxStruct{
+		conn:    cqlConn,
+		session: cqlConn.session,
+		batch:   batch,
+	}
+
+	return cqlConn.tx, nil
+}
+
+// baseContext returns the connection context, it is canceled when the connection is closed
+func (cqlConn *cqlConnStruct) baseContext() context.Context {
+	if cqlConn == nil || cqlConn.context == nil {
+		return context.Background()
+	}
+	return cqlConn.context
+}
+
+// queryTimeoutContext returns ctx with the default query timeout when ctx does not have a deadline
+func (cqlConn *cqlConnStruct) queryTimeoutContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cqlConn == nil || cqlConn.defaultQueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, cqlConn.defaultQueryTimeout)
 }
@@ -2,10 +2,19 @@ package cql
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/MichaelS11/go-cql-driver/cqltest"
+	"github.com/gocql/gocql"
 )
 
 func TestConnectionPing(t *testing.T) {
@@ -102,6 +111,452 @@ func TestConnectionPingInvalid(t *testing.T) {
 	}
 }
 
+func TestConnectionSharedSession(t *testing.T) {
+	conn1 := testGetConnectionHostValid(t)
+	if conn1 == nil {
+		t.Fatal("conn1 is nil")
+	}
+	cqlConn1 := conn1.(*cqlConnStruct)
+	conn2 := testGetConnectionHostValid(t)
+	if conn2 == nil {
+		t.Fatal("conn2 is nil")
+	}
+	cqlConn2 := conn2.(*cqlConnStruct)
+
+	err := cqlConn1.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Ping error - received: %v - expected: %v ", err, nil)
+	}
+	err = cqlConn2.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Ping error - received: %v - expected: %v ", err, nil)
+	}
+
+	if cqlConn1.session != cqlConn2.session {
+		t.Fatal("sessions are not shared")
+	}
+	session := cqlConn1.session
+	if cqlConn1.sharedSession.refs != 2 {
+		t.Fatalf("refs - received: %v - expected: %v ", cqlConn1.sharedSession.refs, 2)
+	}
+
+	err = conn1.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+	if session.Closed() {
+		t.Fatal("session closed while still in use")
+	}
+	err = cqlConn2.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Ping error - received: %v - expected: %v ", err, nil)
+	}
+
+	err = conn2.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+	if !session.Closed() {
+		t.Fatal("session not closed after last connection closed")
+	}
+}
+
+func TestConnectionSharedSessionInvalid(t *testing.T) {
+	conn := testGetConnectionHostInvalid(t)
+	if conn == nil {
+		t.Fatal("conn is nil")
+	}
+	cqlConn := conn.(*cqlConnStruct)
+
+	err := cqlConn.Ping(context.Background())
+	if err == nil || err != driver.ErrBadConn {
+		t.Fatalf("Ping error - received: %v - expected: %v ", err, driver.ErrBadConn)
+	}
+
+	key := sessionKey(cqlConn.clusterConfig)
+	sessionRegistry.mutex.Lock()
+	_, ok := sessionRegistry.sessions[key]
+	sessionRegistry.mutex.Unlock()
+	if ok {
+		t.Fatal("failed session still in registry")
+	}
+
+	err = conn.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+}
+
+func TestConnectionSessionKey(t *testing.T) {
+	tests := []struct {
+		info          string
+		clusterConfig func() *gocql.ClusterConfig
+		shared        bool
+	}{
+		{info: "default", clusterConfig: func() *gocql.ClusterConfig { return NewClusterConfig() }, shared: true},
+		{info: "config string", clusterConfig: func() *gocql.ClusterConfig {
+			clusterConfig, _ := ConfigStringToClusterConfig("?username=alice&password=secret&hostSelection=tokenAware&retryPolicy=simple&compression=lz4")
+			return clusterConfig
+		}, shared: true},
+		{info: "authenticator", clusterConfig: func() *gocql.ClusterConfig {
+			clusterConfig := NewClusterConfig()
+			clusterConfig.Authenticator = &gocql.PasswordAuthenticator{Username: "alice"}
+			return clusterConfig
+		}},
+		{info: "host filter", clusterConfig: func() *gocql.ClusterConfig {
+			clusterConfig := NewClusterConfig()
+			clusterConfig.HostFilter = gocql.WhiteListHostFilter("127.0.0.1")
+			return clusterConfig
+		}},
+		{info: "host selection policy", clusterConfig: func() *gocql.ClusterConfig {
+			clusterConfig := NewClusterConfig()
+			clusterConfig.PoolConfig.HostSelectionPolicy = gocql.RoundRobinHostPolicy()
+			return clusterConfig
+		}},
+		{info: "tls config", clusterConfig: func() *gocql.ClusterConfig {
+			clusterConfig := NewClusterConfig()
+			clusterConfig.SslOpts = &gocql.SslOptions{Config: &tls.Config{ServerName: "one"}, EnableHostVerification: true}
+			return clusterConfig
+		}},
+		{info: "page size", clusterConfig: func() *gocql.ClusterConfig {
+			clusterConfig := NewClusterConfig()
+			clusterConfig.PageSize = 10
+			return clusterConfig
+		}},
+	}
+
+	for _, test := range tests {
+		clusterConfig := test.clusterConfig()
+		key := sessionKey(clusterConfig)
+		if key != sessionKey(clusterConfig) {
+			t.Errorf("sessionKey - received: %v - expected: %v - info: %v", sessionKey(clusterConfig), key, test.info)
+		}
+		shared := key == sessionKey(test.clusterConfig())
+		if shared != test.shared {
+			t.Errorf("shared - received: %v - expected: %v - info: %v", shared, test.shared, test.info)
+		}
+		if strings.Contains(key, "secret") {
+			t.Errorf("sessionKey - received: %v - expected: %v - info: %v", key, "password not in key", test.info)
+		}
+	}
+
+	// values the config string leaves out are part of the key
+	clusterConfig := NewClusterConfig()
+	clusterConfig.NumConns = 1
+	if sessionKey(clusterConfig) == sessionKey(NewClusterConfig()) {
+		t.Fatalf("sessionKey - received: %v - expected: %v ", "same key", "different key for NumConns 1")
+	}
+}
+
+func TestConnectionAcquireContext(t *testing.T) {
+	// a listener that never answers makes the session creation hang until the connect timeout
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error - received: %v - expected: %v ", err, nil)
+	}
+	defer listener.Close()
+
+	clusterConfig, err := ConfigStringToClusterConfig(listener.Addr().String() + "?timeout=5s&connectTimeout=5s")
+	if err != nil {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = sessionRegistry.acquire(ctx, clusterConfig)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("acquire error - received: %v - expected: %v ", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("acquire time - received: %v - expected: %v ", elapsed, "less than 2s")
+	}
+
+	sessionRegistry.mutex.Lock()
+	_, ok := sessionRegistry.sessions[sessionKey(clusterConfig)]
+	sessionRegistry.mutex.Unlock()
+	if ok {
+		t.Fatal("released session still in registry")
+	}
+}
+
+func TestConnectionIsValidResetSession(t *testing.T) {
+	conn := testGetConnectionHostValid(t)
+	if conn == nil {
+		t.Fatal("conn is nil")
+	}
+	cqlConn := conn.(*cqlConnStruct)
+
+	if cqlConn.IsValid() {
+		t.Fatalf("IsValid - received: %v - expected: %v ", true, false)
+	}
+	err := cqlConn.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Ping error - received: %v - expected: %v ", err, nil)
+	}
+	if !cqlConn.IsValid() {
+		t.Fatalf("IsValid - received: %v - expected: %v ", false, true)
+	}
+
+	// the connection context is owned by the connection, not the context passed by database/sql
+	ctx, cancel := context.WithCancel(context.Background())
+	cqlConn.tx = &cqlTxStruct{}
+	err = cqlConn.ResetSession(ctx)
+	cancel()
+	if err != nil {
+		t.Fatalf("ResetSession error - received: %v - expected: %v ", err, nil)
+	}
+	if cqlConn.context.Err() != nil {
+		t.Fatalf("context Err - received: %v - expected: %v ", cqlConn.context.Err(), nil)
+	}
+	if cqlConn.tx != nil {
+		t.Fatalf("tx - received: %v - expected: %v ", cqlConn.tx, nil)
+	}
+
+	// ping failure releases the session but does not cancel the connection context
+	cqlConn.logger = log.New(ioutil.Discard, "", 0)
+	cqlConn.pingQuery = cqlConn.session.Query("")
+	err = cqlConn.Ping(context.Background())
+	if err != driver.ErrBadConn {
+		t.Fatalf("Ping error - received: %v - expected: %v ", err, driver.ErrBadConn)
+	}
+	if cqlConn.IsValid() {
+		t.Fatalf("IsValid - received: %v - expected: %v ", true, false)
+	}
+	if cqlConn.context.Err() != nil {
+		t.Fatalf("context Err - received: %v - expected: %v ", cqlConn.context.Err(), nil)
+	}
+
+	// closed session
+	err = cqlConn.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Ping error - received: %v - expected: %v ", err, nil)
+	}
+	cqlConn.session.Close()
+	if cqlConn.IsValid() {
+		t.Fatalf("IsValid - received: %v - expected: %v ", true, false)
+	}
+	err = cqlConn.ResetSession(context.Background())
+	if err != driver.ErrBadConn {
+		t.Fatalf("ResetSession error - received: %v - expected: %v ", err, driver.ErrBadConn)
+	}
+
+	err = conn.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+
+	// canceled connection context
+	err = cqlConn.ResetSession(context.Background())
+	if err != driver.ErrBadConn {
+		t.Fatalf("ResetSession error - received: %v - expected: %v ", err, driver.ErrBadConn)
+	}
+}
+
+func TestConnectionUseKeyspace(t *testing.T) {
+	server, err := cqltest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+	for _, statement := range []string{
+		"create keyspace one with replication = {'class': 'SimpleStrategy', 'replication_factor': 1}",
+		"create keyspace two with replication = {'class': 'SimpleStrategy', 'replication_factor': 1}",
+		"create table one.data (id int primary key, name text)",
+		"create table two.data (id int primary key, name text)",
+		"insert into one.data (id, name) values (1, 'one')",
+		"insert into two.data (id, name) values (1, 'two')",
+	} {
+		err = server.Exec(statement)
+		if err != nil {
+			t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+		}
+	}
+
+	db, err := sql.Open("cql", server.Addr()+"?keyspace=one&timeout=2s&connectTimeout=2s")
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	defer cancel()
+	queryName := func(conn *sql.Conn) string {
+		var name string
+		err := conn.QueryRowContext(ctx, "select name from data where id = 1").Scan(&name)
+		if err != nil {
+			t.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+		}
+		return name
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn error - received: %v - expected: %v ", err, nil)
+	}
+	var usedConn *cqlConnStruct
+	err = conn.Raw(func(driverConn interface{}) error {
+		usedConn = driverConn.(*cqlConnStruct)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Raw error - received: %v - expected: %v ", err, nil)
+	}
+	name := queryName(conn)
+	if name != "one" {
+		t.Fatalf("name - received: %v - expected: %v ", name, "one")
+	}
+
+	_, err = conn.ExecContext(ctx, `use ""`)
+	if err != ErrUseKeyspaceMissing {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, ErrUseKeyspaceMissing)
+	}
+	_, err = conn.ExecContext(ctx, "USE Two;")
+	if err != nil {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+	}
+	name = queryName(conn)
+	if name != "two" {
+		t.Fatalf("name - received: %v - expected: %v ", name, "two")
+	}
+	err = conn.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+
+	// the next user of the connection sees the config keyspace
+	conn, err = db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn error - received: %v - expected: %v ", err, nil)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(driverConn interface{}) error {
+		if driverConn.(*cqlConnStruct) != usedConn {
+			return fmt.Errorf("connection not reused")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Raw error - received: %v - expected: %v ", err, nil)
+	}
+	name = queryName(conn)
+	if name != "one" {
+		t.Fatalf("name - received: %v - expected: %v ", name, "one")
+	}
+
+	// use is not supported in a transaction
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx error - received: %v - expected: %v ", err, nil)
+	}
+	_, err = tx.ExecContext(ctx, "use two")
+	if err != ErrTxStatementNotSupported {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, ErrTxStatementNotSupported)
+	}
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback error - received: %v - expected: %v ", err, nil)
+	}
+
+	// a statement prepared while the keyspace was changed is not used after ResetSession
+	driverConn, err := CqlDriver.Open(server.Addr() + "?keyspace=one&timeout=2s&connectTimeout=2s&hostSelection=tokenAware&retryPolicy=simple&retries=1")
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer driverConn.Close()
+	cqlConn := driverConn.(*cqlConnStruct)
+	_, err = cqlConn.ExecContext(ctx, "use two", nil)
+	if err != nil {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+	}
+	if cqlConn.useConfig.PoolConfig.HostSelectionPolicy == cqlConn.clusterConfig.PoolConfig.HostSelectionPolicy ||
+		cqlConn.useConfig.RetryPolicy == cqlConn.clusterConfig.RetryPolicy {
+		t.Fatal("use session shares policies with the config session")
+	}
+	stmt, err := cqlConn.PrepareContext(ctx, "select name from data where id = 1")
+	if err != nil {
+		t.Fatalf("PrepareContext error - received: %v - expected: %v ", err, nil)
+	}
+	defer stmt.Close()
+	err = cqlConn.ResetSession(ctx)
+	if err != nil {
+		t.Fatalf("ResetSession error - received: %v - expected: %v ", err, nil)
+	}
+	_, err = stmt.(*CqlStmt).QueryContext(ctx, nil)
+	if err != driver.ErrBadConn {
+		t.Fatalf("QueryContext error - received: %v - expected: %v ", err, driver.ErrBadConn)
+	}
+	_, err = stmt.(*CqlStmt).ExecContext(ctx, nil)
+	if err != driver.ErrBadConn {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, driver.ErrBadConn)
+	}
+
+	// custom policies can not be created again for the use session
+	clusterConfig := NewClusterConfig()
+	clusterConfig.PoolConfig.HostSelectionPolicy = gocql.RoundRobinHostPolicy()
+	_, err = keyspaceClusterConfig(clusterConfig, "two")
+	if err != ErrUseNotSupported {
+		t.Fatalf("keyspaceClusterConfig error - received: %v - expected: %v ", err, ErrUseNotSupported)
+	}
+}
+
+func TestConnectionPoolDiscardsClosedSession(t *testing.T) {
+	openString := TestHostValid + "?timeout=" + TimeoutValid.String() + "&connectTimeout=" + ConnectTimeoutValid.String()
+	if EnableAuthentication {
+		openString += "&username=" + Username + "&password=" + Password
+	}
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	defer cancel()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn error - received: %v - expected: %v ", err, nil)
+	}
+	err = conn.PingContext(ctx)
+	if err != nil {
+		t.Fatalf("PingContext error - received: %v - expected: %v ", err, nil)
+	}
+	var closedConn *cqlConnStruct
+	err = conn.Raw(func(driverConn interface{}) error {
+		closedConn = driverConn.(*cqlConnStruct)
+		closedConn.session.Close()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Raw error - received: %v - expected: %v ", err, nil)
+	}
+	err = conn.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+
+	conn, err = db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn error - received: %v - expected: %v ", err, nil)
+	}
+	defer conn.Close()
+	err = conn.PingContext(ctx)
+	if err != nil {
+		t.Fatalf("PingContext error - received: %v - expected: %v ", err, nil)
+	}
+	err = conn.Raw(func(driverConn interface{}) error {
+		if driverConn.(*cqlConnStruct) == closedConn {
+			return fmt.Errorf("connection with closed session reused")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Raw error - received: %v - expected: %v ", err, nil)
+	}
+}
+
 func TestConnectionPrepare(t *testing.T) {
 	conn := testGetConnectionHostValid(t)
 	if conn == nil {
@@ -177,13 +632,28 @@ func TestConnectionBegin(t *testing.T) {
 	if conn == nil {
 		t.Fatal("conn is nil")
 	}
+	cqlConn := conn.(*cqlConnStruct)
 
 	tx, err := conn.Begin()
-	if err == nil || err != ErrNotSupported {
-		t.Fatalf("Begin error - received: %v - expected: %v ", err, ErrNotSupported)
+	if err != nil {
+		t.Fatalf("Begin error - received: %v - expected: %v ", err, nil)
+	}
+	if tx == nil {
+		t.Fatal("tx is nil")
 	}
-	if tx != nil {
-		t.Fatal("tx is not nil")
+	if cqlConn.tx != tx {
+		t.Fatal("cqlConn.tx is not tx")
+	}
+	if cqlConn.tx.batch.Type != gocql.LoggedBatch {
+		t.Fatalf("batch Type - received: %v - expected: %v ", cqlConn.tx.batch.Type, gocql.LoggedBatch)
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback error - received: %v - expected: %v ", err, nil)
+	}
+	if cqlConn.tx != nil {
+		t.Fatal("cqlConn.tx is not nil")
 	}
 
 	err = conn.Close()
@@ -199,15 +669,43 @@ func TestConnectionBeginTx(t *testing.T) {
 	}
 	cqlConn := conn.(*cqlConnStruct)
 
-	tx, err := cqlConn.BeginTx(context.Background(), driver.TxOptions{})
-	if err == nil || err != ErrNotSupported {
-		t.Fatalf("BeginTx error - received: %v - expected: %v ", err, ErrNotSupported)
+	tests := []struct {
+		info      string
+		ctx       context.Context
+		opts      driver.TxOptions
+		batchType gocql.BatchType
+		err       error
+	}{
+		{info: "default", ctx: context.Background(), batchType: gocql.LoggedBatch},
+		{info: "context unlogged", ctx: WithBatchType(context.Background(), gocql.UnloggedBatch), batchType: gocql.UnloggedBatch},
+		{info: "isolation logged", ctx: WithBatchType(context.Background(), gocql.UnloggedBatch), opts: driver.TxOptions{Isolation: driver.IsolationLevel(LevelLoggedBatch)}, batchType: gocql.LoggedBatch},
+		{info: "isolation unlogged", ctx: context.Background(), opts: driver.TxOptions{Isolation: driver.IsolationLevel(LevelUnloggedBatch)}, batchType: gocql.UnloggedBatch},
+		{info: "isolation counter", ctx: context.Background(), opts: driver.TxOptions{Isolation: driver.IsolationLevel(LevelCounterBatch)}, batchType: gocql.CounterBatch},
+		{info: "isolation serializable", ctx: context.Background(), opts: driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSerializable)}, err: ErrNotSupported},
+		{info: "read only", ctx: context.Background(), opts: driver.TxOptions{ReadOnly: true}, err: ErrNotSupported},
 	}
-	if tx != nil {
-		t.Fatal("tx is not nil")
+
+	for _, test := range tests {
+		tx, err := cqlConn.BeginTx(test.ctx, test.opts)
+		if err != test.err {
+			t.Fatalf("BeginTx error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+		}
+		if err != nil {
+			if tx != nil {
+				t.Fatalf("tx is not nil - info: %v", test.info)
+			}
+			continue
+		}
+		if cqlConn.tx.batch.Type != test.batchType {
+			t.Fatalf("batch Type - received: %v - expected: %v - info: %v", cqlConn.tx.batch.Type, test.batchType, test.info)
+		}
+		err = tx.Rollback()
+		if err != nil {
+			t.Fatalf("Rollback error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
 	}
 
-	err = conn.Close()
+	err := conn.Close()
 	if err != nil {
 		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
 	}
@@ -227,3 +725,103 @@ func testGetStatementHostValid(t *testing.T, query string) (driver.Conn, driver.
 	}
 	return conn, stmt
 }
+
+func TestConnectionExecQueryContext(t *testing.T) {
+	conn := testGetConnectionHostValid(t)
+	if conn == nil {
+		t.Fatal("conn is nil")
+	}
+	cqlConn := conn.(*cqlConnStruct)
+
+	result, err := cqlConn.ExecContext(context.Background(), "select key from system.local where key = ?", []driver.NamedValue{{Ordinal: 1, Value: "local"}})
+	if err != nil {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+	}
+	if result == nil {
+		t.Fatal("result is nil")
+	}
+
+	result, err = cqlConn.ExecContext(context.Background(), "select key from system.local where key = ?", []driver.NamedValue{})
+	if err != driver.ErrSkip {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, driver.ErrSkip)
+	}
+	if result != nil {
+		t.Fatal("result is not nil")
+	}
+
+	rows, err := cqlConn.QueryContext(context.Background(), "select key from system.local where key = :key", []driver.NamedValue{{Name: "key", Ordinal: 1, Value: "local"}})
+	if err != nil {
+		t.Fatalf("QueryContext error - received: %v - expected: %v ", err, nil)
+	}
+	dest := make([]driver.Value, 1)
+	err = rows.Next(dest)
+	if err != nil {
+		t.Fatalf("Next error - received: %v - expected: %v ", err, nil)
+	}
+	if dest[0] != "local" {
+		t.Fatalf("Next value - received: %v - expected: %v ", dest[0], "local")
+	}
+	err = rows.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+
+	rows, err = cqlConn.QueryContext(context.Background(), "select key from system.local", []driver.NamedValue{{Ordinal: 1, Value: "local"}})
+	if err != driver.ErrSkip {
+		t.Fatalf("QueryContext error - received: %v - expected: %v ", err, driver.ErrSkip)
+	}
+	if rows != nil {
+		t.Fatal("rows is not nil")
+	}
+
+	// in a transaction exec is added to the batch and query is not supported
+	tx, err := cqlConn.BeginTx(context.Background(), driver.TxOptions{})
+	if err != nil {
+		t.Fatalf("BeginTx error - received: %v - expected: %v ", err, nil)
+	}
+	_, err = cqlConn.ExecContext(context.Background(), "update system.local set cql_version = ? where key = ?", []driver.NamedValue{{Ordinal: 1, Value: "3"}, {Ordinal: 2, Value: "local"}})
+	if err != nil {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+	}
+	if len(cqlConn.tx.batch.Entries) != 1 {
+		t.Fatalf("batch Entries - received: %v - expected: %v ", len(cqlConn.tx.batch.Entries), 1)
+	}
+	_, err = cqlConn.QueryContext(context.Background(), "select key from system.local", nil)
+	if err != ErrTxStatementNotSupported {
+		t.Fatalf("QueryContext error - received: %v - expected: %v ", err, ErrTxStatementNotSupported)
+	}
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback error - received: %v - expected: %v ", err, nil)
+	}
+
+	err = conn.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+}
+
+func TestConnectionCheckNamedValue(t *testing.T) {
+	cqlConn := &cqlConnStruct{}
+
+	namedValue := &driver.NamedValue{Value: []string{"a"}}
+	err := cqlConn.CheckNamedValue(namedValue)
+	if err != nil {
+		t.Fatalf("CheckNamedValue error - received: %v - expected: %v ", err, nil)
+	}
+
+	namedValue = &driver.NamedValue{Value: int32(1)}
+	err = cqlConn.CheckNamedValue(namedValue)
+	if err != nil {
+		t.Fatalf("CheckNamedValue error - received: %v - expected: %v ", err, nil)
+	}
+	if namedValue.Value != int64(1) {
+		t.Fatalf("Value - received: %v - expected: %v ", namedValue.Value, int64(1))
+	}
+
+	namedValue = &driver.NamedValue{Value: struct{}{}}
+	err = cqlConn.CheckNamedValue(namedValue)
+	if err == nil {
+		t.Fatalf("CheckNamedValue error - received: %v - expected: %v ", err, "error")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 )
 
 // NewConnector returns a new database connector
@@ -18,21 +19,53 @@ func NewConnector(hosts ...string) driver.Connector {
 	}
 }
 
+// OpenConnectorFromEnv returns a new database connector configured by CQL_ environment variables.
+// CQL_HOSTS is the comma separated hosts. Every config string key has a variable
+// named CQL_ followed by the key in upper snake case, for example
+// CQL_KEYSPACE, CQL_CONSISTENCY, CQL_NUM_CONNS, CQL_USERNAME, CQL_PASSWORD, CQL_LOCAL_DC, and CQL_TLS_SERVER_NAME.
+// Values are used as is, they do not need to be query escaped.
+func OpenConnectorFromEnv() (driver.Connector, error) {
+	return CqlDriver.OpenConnector(envConfigString())
+}
+
 // Driver returns the cql driver
 func (cqlConnector *CqlConnector) Driver() driver.Driver {
 	return CqlDriver
 }
 
-// Connect returns a new database connection
+// Connect returns a new database connection.
+// The ctx is not kept, the connection has its own context that is canceled when the connection is closed.
 func (cqlConnector *CqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
 	cqlConn := &cqlConnStruct{
-		logger:        cqlConnector.Logger,
-		context:       ctx,
-		clusterConfig: cqlConnector.ClusterConfig,
+		logger:              cqlConnector.Logger,
+		clusterConfig:       cqlConnector.ClusterConfig,
+		defaultQueryTimeout: cqlConnector.DefaultQueryTimeout,
+		trace:               cqlConnector.Trace,
 	}
 	if cqlConn.logger == nil {
 		cqlConn.logger = log.New(ioutil.Discard, "", 0)
 	}
+	cqlConn.context, cqlConn.cancel = context.WithCancel(context.Background())
 
 	return cqlConn, nil
 }
+
+// String returns the connector config string with secrets masked
+func (cqlConnector *CqlConnector) String() string {
+	if cqlConnector.ClusterConfig == nil {
+		return ""
+	}
+	configString := RedactedConfigString(cqlConnector.ClusterConfig)
+	separator := "&"
+	if !strings.Contains(configString, "?") {
+		separator = "?"
+	}
+	if cqlConnector.DefaultQueryTimeout > 0 {
+		configString += separator + "defaultQueryTimeout=" + cqlConnector.DefaultQueryTimeout.String()
+		separator = "&"
+	}
+	if cqlConnector.Trace {
+		configString += separator + "trace=true"
+	}
+	return configString
+}
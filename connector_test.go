@@ -4,7 +4,16 @@ package cql
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/MichaelS11/go-cql-driver/cqltest"
+	"github.com/gocql/gocql"
 )
 
 func TestConnectorDriver(t *testing.T) {
@@ -67,3 +76,194 @@ func TestConnectorConnect(t *testing.T) {
 		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
 	}
 }
+
+func TestConnectorString(t *testing.T) {
+	connector, err := CqlDriver.OpenConnector("127.0.0.1?username=alice&password=top%24ecret")
+	if err != nil {
+		t.Fatalf("OpenConnector error - received: %v - expected: %v ", err, nil)
+	}
+
+	configString := fmt.Sprint(connector)
+	if strings.Contains(configString, "ecret") {
+		t.Fatalf("String - received: %v - expected: %v ", configString, "password redacted")
+	}
+	expected := "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&username=alice&password=xxxxx"
+	if configString != expected {
+		t.Fatalf("String - received: %v - expected: %v ", configString, expected)
+	}
+}
+
+func TestConnectorDefaultQueryTimeout(t *testing.T) {
+	connector, err := CqlDriver.OpenConnector("127.0.0.1?defaultQueryTimeout=2s&trace=true")
+	if err != nil {
+		t.Fatalf("OpenConnector error - received: %v - expected: %v ", err, nil)
+	}
+	cqlConnector := connector.(*CqlConnector)
+	if cqlConnector.DefaultQueryTimeout != 2*time.Second {
+		t.Fatalf("DefaultQueryTimeout - received: %v - expected: %v ", cqlConnector.DefaultQueryTimeout, 2*time.Second)
+	}
+	if !cqlConnector.Trace {
+		t.Fatalf("Trace - received: %v - expected: %v ", cqlConnector.Trace, true)
+	}
+	expected := "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&defaultQueryTimeout=2s&trace=true"
+	if cqlConnector.String() != expected {
+		t.Fatalf("String - received: %v - expected: %v ", cqlConnector.String(), expected)
+	}
+
+	_, err = CqlDriver.OpenConnector("127.0.0.1?defaultQueryTimeout=-1s")
+	expectedError := "ConfigStringToClusterConfig error: failed for: defaultQueryTimeout = -1s"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("OpenConnector error - received: %v - expected: %v ", err, expectedError)
+	}
+
+	// the expanded value is parsed once, it is not expanded again
+	os.Setenv("CQL_TEST_DEFAULT_QUERY_TIMEOUT", "3s")
+	defer os.Unsetenv("CQL_TEST_DEFAULT_QUERY_TIMEOUT")
+	ExpandValues = true
+	defer func() { ExpandValues = false }()
+	connector, err = CqlDriver.OpenConnector("127.0.0.1?defaultQueryTimeout=${env:CQL_TEST_DEFAULT_QUERY_TIMEOUT}")
+	if err != nil {
+		t.Fatalf("OpenConnector error - received: %v - expected: %v ", err, nil)
+	}
+	if connector.(*CqlConnector).DefaultQueryTimeout != 3*time.Second {
+		t.Fatalf("DefaultQueryTimeout - received: %v - expected: %v ", connector.(*CqlConnector).DefaultQueryTimeout, 3*time.Second)
+	}
+}
+
+func TestConnectorConnectContext(t *testing.T) {
+	conn := testGetConnectionHostValid(t)
+	if conn == nil {
+		t.Fatal("conn is nil")
+	}
+	connector := &CqlConnector{ClusterConfig: conn.(*cqlConnStruct).clusterConfig}
+	err := conn.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+
+	// the Connect context is canceled after the connection is made, it must not be used by the connection
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, err = connector.Connect(ctx)
+	cancel()
+	if err != nil {
+		t.Fatalf("Connect error - received: %v - expected: %v ", err, nil)
+	}
+
+	stmt, err := conn.Prepare("select cql_version from system.local")
+	if err != nil {
+		t.Fatalf("Prepare error - received: %v - expected: %v ", err, nil)
+	}
+	rows, err := stmt.Query(nil)
+	if err != nil {
+		t.Fatalf("Query error - received: %v - expected: %v ", err, nil)
+	}
+	err = rows.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+	err = stmt.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+
+	// the connection context is canceled when the connection is closed
+	cqlConn := conn.(*cqlConnStruct)
+	err = conn.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+	if cqlConn.context.Err() != context.Canceled {
+		t.Fatalf("context Err - received: %v - expected: %v ", cqlConn.context.Err(), context.Canceled)
+	}
+}
+
+func TestSqlDefaultQueryTimeout(t *testing.T) {
+	server, err := cqltest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+	server.Script("select release_version from system.local", cqltest.Response{
+		Columns: []cqltest.Column{{Name: "release_version", Type: "text"}},
+		Rows:    [][]interface{}{{"3.11"}},
+		Delay:   500 * time.Millisecond,
+	})
+
+	db, err := sql.Open("cql", server.Addr()+"?timeout=5s&connectTimeout=2s&defaultQueryTimeout=100ms")
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer db.Close()
+
+	var version string
+	err = db.QueryRow("select release_version from system.local").Scan(&version)
+	if err == nil || !strings.HasSuffix(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("Scan error - received: %v - expected: %v ", err, context.DeadlineExceeded)
+	}
+	_, err = db.Exec("select release_version from system.local")
+	if err == nil || !strings.HasSuffix(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, context.DeadlineExceeded)
+	}
+
+	// a context deadline is used instead of the default query timeout
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	defer cancel()
+	err = db.QueryRowContext(ctx, "select release_version from system.local").Scan(&version)
+	if err != nil {
+		t.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+	}
+	if version != "3.11" {
+		t.Fatalf("version - received: %v - expected: %v ", version, "3.11")
+	}
+}
+
+func TestOpenConnectorFromEnv(t *testing.T) {
+	env := map[string]string{
+		"CQL_HOSTS":           "one,two",
+		"CQL_KEYSPACE":        "system",
+		"CQL_NUM_CONNS":       "3",
+		"CQL_USERNAME":        "alice@bob.com",
+		"CQL_PASSWORD":        "top%$ecret&",
+		"CQL_LOCAL_DC":        "dc 1",
+		"CQL_HOST_SELECTION":  "tokenAware",
+		"CQL_TLS_SERVER_NAME": "cassandra",
+	}
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	connector, err := OpenConnectorFromEnv()
+	if err != nil {
+		t.Fatalf("OpenConnectorFromEnv error - received: %v - expected: %v ", err, nil)
+	}
+	clusterConfig := connector.(*CqlConnector).ClusterConfig
+
+	if !reflect.DeepEqual(clusterConfig.Hosts, []string{"one", "two"}) {
+		t.Fatalf("Hosts - received: %v - expected: %v ", clusterConfig.Hosts, []string{"one", "two"})
+	}
+	if clusterConfig.Keyspace != "system" {
+		t.Fatalf("Keyspace - received: %v - expected: %v ", clusterConfig.Keyspace, "system")
+	}
+	if clusterConfig.NumConns != 3 {
+		t.Fatalf("NumConns - received: %v - expected: %v ", clusterConfig.NumConns, 3)
+	}
+	expectedAuthenticator := gocql.PasswordAuthenticator{Username: "alice@bob.com", Password: "top%$ecret&"}
+	if clusterConfig.Authenticator != expectedAuthenticator {
+		t.Fatalf("Authenticator - received: %v - expected: %v ", clusterConfig.Authenticator, expectedAuthenticator)
+	}
+	hostSelection, localDC, _, _ := hostSelectionPolicyToConfig(clusterConfig.PoolConfig.HostSelectionPolicy)
+	if hostSelection != "tokenAware" || localDC != "dc 1" {
+		t.Fatalf("hostSelection - received: %v %v - expected: %v %v ", hostSelection, localDC, "tokenAware", "dc 1")
+	}
+	if clusterConfig.SslOpts == nil || clusterConfig.SslOpts.ServerName != "cassandra" {
+		t.Fatalf("SslOpts - received: %v - expected: %v ", clusterConfig.SslOpts, "cassandra")
+	}
+
+	os.Setenv("CQL_NUM_CONNS", "foo")
+	_, err = OpenConnectorFromEnv()
+	expectedError := "ConfigStringToClusterConfig error: failed for: numConns = foo"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("OpenConnectorFromEnv error - received: %v - expected: %v ", err, expectedError)
+	}
+}
@@ -0,0 +1,154 @@
+package cql
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// WithConsistency returns a copy of the context that sets the consistency level
+// for statements executed or queried with the context
+func WithConsistency(ctx context.Context, consistency gocql.Consistency) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.consistency = &consistency
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithSerialConsistency returns a copy of the context that sets the serial consistency level
+// for statements executed or queried with the context
+func WithSerialConsistency(ctx context.Context, serialConsistency gocql.SerialConsistency) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.serialConsistency = &serialConsistency
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithPageSize returns a copy of the context that sets the page size
+// for statements queried with the context
+func WithPageSize(ctx context.Context, pageSize int) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.pageSize = &pageSize
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithIdempotent returns a copy of the context that marks statements executed or queried with the context
+// as idempotent or not. Only idempotent statements are retried by speculative execution policies.
+func WithIdempotent(ctx context.Context, idempotent bool) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.idempotent = &idempotent
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithTimestamp returns a copy of the context that sets the write timestamp
+// for statements executed with the context
+func WithTimestamp(ctx context.Context, timestamp time.Time) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	microseconds := timestamp.UnixNano() / 1000
+	queryOptions.timestamp = &microseconds
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithBatchType returns a copy of the context that sets the batch type
+// for transactions started with the context. The default is gocql.LoggedBatch.
+func WithBatchType(ctx context.Context, batchType gocql.BatchType) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.batchType = &batchType
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithCASResult returns a copy of the context that fills in the CASResult
+// when a lightweight transaction is executed with the context
+func WithCASResult(ctx context.Context, casResult *CASResult) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.casResult = casResult
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithPageState returns a copy of the context that queries one page starting at the paging state
+// for statements queried with the context. A nil paging state queries the first page.
+// Rows only return the one page, use WithPageResult to get the paging state of the next page.
+func WithPageState(ctx context.Context, pageState []byte) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.pageState = &pageState
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithPageResult returns a copy of the context that fills in the PageResult
+// when the rows of a statement queried with the context are closed
+func WithPageResult(ctx context.Context, pageResult *PageResult) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.pageResult = pageResult
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// WithTrace returns a copy of the context that traces statements executed or queried with the context.
+// The collector is called with the trace read from system_traces after each traced request is done,
+// a statement that gocql has not prepared yet also has a trace for the prepare request.
+func WithTrace(ctx context.Context, collector TraceCollector) context.Context {
+	queryOptions := queryOptionsFromContext(ctx)
+	queryOptions.traceCollector = &collector
+	return context.WithValue(ctx, queryOptionsKey{}, queryOptions)
+}
+
+// EncodePageState returns the paging state as an URL safe base64 string, for example for an API page token.
+// An empty paging state returns an empty string.
+func EncodePageState(pageState []byte) string {
+	return base64.RawURLEncoding.EncodeToString(pageState)
+}
+
+// DecodePageState returns the paging state of a string returned by EncodePageState.
+// An empty string returns a nil paging state, which is the first page.
+func DecodePageState(pageToken string) ([]byte, error) {
+	if pageToken == "" {
+		return nil, nil
+	}
+	pageState, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, ErrPageStateInvalid
+	}
+	return pageState, nil
+}
+
+// queryOptionsFromContext returns a copy of the query options in the context
+func queryOptionsFromContext(ctx context.Context) queryOptionsStruct {
+	queryOptions, _ := ctx.Value(queryOptionsKey{}).(queryOptionsStruct)
+	return queryOptions
+}
+
+// applyQueryOptions applies the query options in the context to the query
+func applyQueryOptions(ctx context.Context, query *gocql.Query) {
+	queryOptions := queryOptionsFromContext(ctx)
+	if queryOptions.consistency != nil {
+		query.SetConsistency(*queryOptions.consistency)
+	}
+	if queryOptions.serialConsistency != nil {
+		query.SerialConsistency(*queryOptions.serialConsistency)
+	}
+	if queryOptions.pageSize != nil {
+		query.PageSize(*queryOptions.pageSize)
+	}
+	if queryOptions.idempotent != nil {
+		query.Idempotent(*queryOptions.idempotent)
+	}
+	if queryOptions.timestamp != nil {
+		query.WithTimestamp(*queryOptions.timestamp)
+	}
+	if queryOptions.pageState != nil {
+		query.PageState(*queryOptions.pageState)
+	}
+}
+
+// applyBatchOptions applies the query options in the context that are supported by batches to the batch
+func applyBatchOptions(ctx context.Context, batch *gocql.Batch) {
+	queryOptions := queryOptionsFromContext(ctx)
+	if queryOptions.consistency != nil {
+		batch.SetConsistency(*queryOptions.consistency)
+	}
+	if queryOptions.serialConsistency != nil {
+		batch.SerialConsistency(*queryOptions.serialConsistency)
+	}
+	if queryOptions.timestamp != nil {
+		batch.WithTimestamp(*queryOptions.timestamp)
+	}
+}
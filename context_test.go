@@ -0,0 +1,110 @@
+package cql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+func TestContextQueryOptions(t *testing.T) {
+	ctx := context.Background()
+	queryOptions := queryOptionsFromContext(ctx)
+	if queryOptions != (queryOptionsStruct{}) {
+		t.Fatalf("queryOptions - received: %#v - expected: %#v", queryOptions, queryOptionsStruct{})
+	}
+
+	timestamp := time.Unix(1, 2000)
+	ctx = WithConsistency(ctx, gocql.LocalQuorum)
+	ctxSerial := WithSerialConsistency(ctx, gocql.LocalSerial)
+	ctxSerial = WithPageSize(ctxSerial, 10)
+	ctxSerial = WithIdempotent(ctxSerial, true)
+	ctxSerial = WithTimestamp(ctxSerial, timestamp)
+
+	queryOptions = queryOptionsFromContext(ctx)
+	if queryOptions.consistency == nil || *queryOptions.consistency != gocql.LocalQuorum {
+		t.Fatalf("consistency - received: %v - expected: %v", queryOptions.consistency, gocql.LocalQuorum)
+	}
+	if queryOptions.serialConsistency != nil {
+		t.Fatalf("serialConsistency - received: %v - expected: %v", queryOptions.serialConsistency, nil)
+	}
+
+	queryOptions = queryOptionsFromContext(ctxSerial)
+	if queryOptions.consistency == nil || *queryOptions.consistency != gocql.LocalQuorum {
+		t.Fatalf("consistency - received: %v - expected: %v", queryOptions.consistency, gocql.LocalQuorum)
+	}
+	if queryOptions.serialConsistency == nil || *queryOptions.serialConsistency != gocql.LocalSerial {
+		t.Fatalf("serialConsistency - received: %v - expected: %v", queryOptions.serialConsistency, gocql.LocalSerial)
+	}
+	if queryOptions.pageSize == nil || *queryOptions.pageSize != 10 {
+		t.Fatalf("pageSize - received: %v - expected: %v", queryOptions.pageSize, 10)
+	}
+	if queryOptions.idempotent == nil || !*queryOptions.idempotent {
+		t.Fatalf("idempotent - received: %v - expected: %v", queryOptions.idempotent, true)
+	}
+	if queryOptions.timestamp == nil || *queryOptions.timestamp != 1000002 {
+		t.Fatalf("timestamp - received: %v - expected: %v", queryOptions.timestamp, 1000002)
+	}
+
+	query := &gocql.Query{}
+	applyQueryOptions(ctxSerial, query)
+	if query.GetConsistency() != gocql.LocalQuorum {
+		t.Fatalf("GetConsistency - received: %v - expected: %v", query.GetConsistency(), gocql.LocalQuorum)
+	}
+	if !query.IsIdempotent() {
+		t.Fatalf("IsIdempotent - received: %v - expected: %v", query.IsIdempotent(), true)
+	}
+}
+
+func TestContextPageState(t *testing.T) {
+	pageResult := &PageResult{}
+	ctx := WithPageState(context.Background(), nil)
+	ctx = WithPageResult(ctx, pageResult)
+
+	queryOptions := queryOptionsFromContext(ctx)
+	if queryOptions.pageState == nil || *queryOptions.pageState != nil {
+		t.Fatalf("pageState - received: %v - expected: %v", queryOptions.pageState, "first page")
+	}
+	if queryOptions.pageResult != pageResult {
+		t.Fatalf("pageResult - received: %v - expected: %v", queryOptions.pageResult, pageResult)
+	}
+
+	ctx = WithPageState(ctx, []byte{1, 2})
+	queryOptions = queryOptionsFromContext(ctx)
+	if queryOptions.pageState == nil || !bytes.Equal(*queryOptions.pageState, []byte{1, 2}) {
+		t.Fatalf("pageState - received: %v - expected: %v", queryOptions.pageState, []byte{1, 2})
+	}
+}
+
+func TestEncodeDecodePageState(t *testing.T) {
+	tests := []struct {
+		info      string
+		pageState []byte
+		pageToken string
+	}{
+		{info: "empty", pageState: nil, pageToken: ""},
+		{info: "bytes", pageState: []byte{0, 0, 0, 2}, pageToken: "AAAAAg"},
+		{info: "url safe", pageState: []byte{0xfb, 0xff}, pageToken: "-_8"},
+	}
+
+	for _, test := range tests {
+		pageToken := EncodePageState(test.pageState)
+		if pageToken != test.pageToken {
+			t.Errorf("EncodePageState - received: %v - expected: %v - info: %v", pageToken, test.pageToken, test.info)
+		}
+		pageState, err := DecodePageState(pageToken)
+		if err != nil {
+			t.Errorf("DecodePageState error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+		if !bytes.Equal(pageState, test.pageState) {
+			t.Errorf("DecodePageState - received: %v - expected: %v - info: %v", pageState, test.pageState, test.info)
+		}
+	}
+
+	_, err := DecodePageState("not a page token!")
+	if err != ErrPageStateInvalid {
+		t.Fatalf("DecodePageState error - received: %v - expected: %v", err, ErrPageStateInvalid)
+	}
+}
@@ -12,17 +12,23 @@ import (
 func (cqlDriver *CqlDriverStruct) Open(configString string) (driver.Conn, error) {
 	var err error
 	cqlConn := &cqlConnStruct{
-		logger:  cqlDriver.Logger,
-		context: context.Background(),
+		logger: cqlDriver.Logger,
 	}
 	if cqlConn.logger == nil {
 		cqlConn.logger = log.New(ioutil.Discard, "", 0)
 	}
 
-	cqlConn.clusterConfig, err = ConfigStringToClusterConfig(configString)
+	// parsed once, the connection settings are not part of the ClusterConfig
+	config, err := ParseConfig(configString)
+	if err == nil {
+		cqlConn.clusterConfig, err = config.configStringClusterConfig()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("ConfigStringToClusterConfig error: %v", err)
 	}
+	cqlConn.defaultQueryTimeout = config.DefaultQueryTimeout
+	cqlConn.trace = config.Trace
+	cqlConn.context, cqlConn.cancel = context.WithCancel(context.Background())
 
 	return cqlConn, nil
 }
@@ -14,10 +14,16 @@ func (cqlDriver *CqlDriverStruct) OpenConnector(configString string) (driver.Con
 		Logger: cqlDriver.Logger,
 	}
 
-	cqlConnector.ClusterConfig, err = ConfigStringToClusterConfig(configString)
+	// parsed once, the connection settings are not part of the ClusterConfig
+	config, err := ParseConfig(configString)
+	if err == nil {
+		cqlConnector.ClusterConfig, err = config.configStringClusterConfig()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("ConfigStringToClusterConfig error: %v", err)
 	}
+	cqlConnector.DefaultQueryTimeout = config.DefaultQueryTimeout
+	cqlConnector.Trace = config.Trace
 
 	return cqlConnector, nil
 }
@@ -3,9 +3,14 @@ package cql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"net"
+	"net/url"
 	"reflect"
 	"testing"
-	// "time"
+	"time"
+
+	"github.com/MichaelS11/go-cql-driver/cqltest"
 )
 
 func TestSqlOpen(t *testing.T) {
@@ -46,6 +51,73 @@ func TestSqlOpen(t *testing.T) {
 	}
 }
 
+func TestSqlOpenURL(t *testing.T) {
+	openString := "cql://"
+	if EnableAuthentication {
+		openString += url.UserPassword(Username, Password).String() + "@"
+	}
+	openString += TestHostValid + "/system?timeout=10s&connectTimeout=10s"
+
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		t.Fatal("Open error: ", err)
+	}
+	if db == nil {
+		t.Fatal("db is nil")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	var key string
+	err = db.QueryRowContext(ctx, "select key from local").Scan(&key)
+	cancel()
+	if err != nil {
+		t.Fatal("QueryRowContext error: ", err)
+	}
+	if key != "local" {
+		t.Fatalf("key - received: %v - expected: %v ", key, "local")
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("Close error: ", err)
+	}
+}
+
+func TestSqlOpenProtocolOptions(t *testing.T) {
+	host, port, err := net.SplitHostPort(TestHostValid)
+	if err != nil {
+		host, port = TestHostValid, "9042"
+	}
+
+	for _, compression := range []string{"none", "snappy", "lz4"} {
+		openString := host + "?port=" + port + "&protoVersion=3&cqlVersion=3.0.0&compression=" + compression + "&timeout=10s&connectTimeout=10s"
+		if EnableAuthentication {
+			openString += "&username=" + Username + "&password=" + Password
+		}
+
+		db, err := sql.Open("cql", openString)
+		if err != nil {
+			t.Fatal("Open error: ", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+		var key string
+		err = db.QueryRowContext(ctx, "select key from system.local where key = ?", "local").Scan(&key)
+		cancel()
+		if err != nil {
+			t.Fatalf("QueryRowContext error - received: %v - expected: %v - compression: %v", err, nil, compression)
+		}
+		if key != "local" {
+			t.Fatalf("key - received: %v - expected: %v - compression: %v", key, "local", compression)
+		}
+
+		err = db.Close()
+		if err != nil {
+			t.Fatal("Close error: ", err)
+		}
+	}
+}
+
 func TestSqlCreate(t *testing.T) {
 	if DisableDestructiveTests {
 		t.SkipNow()
@@ -518,27 +590,511 @@ func TestSqlInsertUpdateSelectDelete(t *testing.T) {
 	if err != nil {
 		t.Fatal("QueryContext error: ", err)
 	}
-	if rows.Close() == nil {
+	closeErr := rows.Close()
+	if closeErr == nil {
 		t.Fatal("QueryContext no error")
 	}
 	cancel()
+	// newer versions of database/sql also return the Close error from Err,
+	// gocql errors can not be compared with == so the messages are compared
 	err = rows.Err()
-	if err != nil {
+	if err != nil && err.Error() != closeErr.Error() {
 		t.Fatal("Err error: ", err)
 	}
 
 	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
-	rows, err = db.QueryContext(ctx, "select int_data from "+KeyspaceName+"."+TableName+" where int_data = ?")
+	_, err = db.QueryContext(ctx, "select int_data from "+KeyspaceName+"."+TableName+" where int_data = ?")
+	cancel()
+	expectedError := "sql: expected 1 arguments, got 0"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("QueryContext error - received: %v - expected: %v", err, expectedError)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("Close error: ", err)
+	}
+}
+
+func TestSqlNull(t *testing.T) {
+	if DisableDestructiveTests {
+		t.SkipNow()
+	}
+
+	openString := TestHostValid + "?timeout=10s&connectTimeout=10s"
+	if EnableAuthentication {
+		openString += "&username=" + Username + "&password=" + Password
+	}
+
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		t.Fatal("Open error: ", err)
+	}
+	if db == nil {
+		t.Fatal("db is nil")
+	}
+
+	// insert null
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	result, err := db.ExecContext(ctx, "insert into "+KeyspaceName+"."+TableName+" (text_data) values (?)", "null")
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	if result == nil {
+		t.Fatal("result is nil")
+	}
+
+	// select null
+	var text sql.NullString
+	var number sql.NullInt64
+	var aMap interface{}
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	err = db.QueryRowContext(ctx, "select text_data, int_data, map_data from "+KeyspaceName+"."+TableName+" where text_data = ?", "null").Scan(&text, &number, &aMap)
+	cancel()
+	if err != nil {
+		t.Fatal("Scan error: ", err)
+	}
+	if !text.Valid || text.String != "null" {
+		t.Fatalf("text_data - received: %v - expected: %v", text, "null")
+	}
+	if number.Valid {
+		t.Fatalf("int_data - received: %v - expected: %v", number, nil)
+	}
+	if aMap != nil {
+		t.Fatalf("map_data - received: %v - expected: %v", aMap, nil)
+	}
+
+	// delete null
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	result, err = db.ExecContext(ctx, "delete from "+KeyspaceName+"."+TableName+" where text_data = ?", "null")
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	if result == nil {
+		t.Fatal("result is nil")
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("Close error: ", err)
+	}
+}
+
+func TestSqlNamed(t *testing.T) {
+	if DisableDestructiveTests {
+		t.SkipNow()
+	}
+
+	openString := TestHostValid + "?timeout=10s&connectTimeout=10s"
+	if EnableAuthentication {
+		openString += "&username=" + Username + "&password=" + Password
+	}
+
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		t.Fatal("Open error: ", err)
+	}
+	if db == nil {
+		t.Fatal("db is nil")
+	}
+
+	// insert named
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	result, err := db.ExecContext(ctx, "insert into "+KeyspaceName+"."+TableName+" (text_data, int_data) values (:text, :number)", sql.Named("number", 6), sql.Named("text", "six"))
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	if result == nil {
+		t.Fatal("result is nil")
+	}
+
+	// select named
+	var number int
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	err = db.QueryRowContext(ctx, "select int_data from "+KeyspaceName+"."+TableName+" where text_data = ?", sql.Named("text_data", "six")).Scan(&number)
+	cancel()
+	if err != nil {
+		t.Fatal("Scan error: ", err)
+	}
+	if number != 6 {
+		t.Fatalf("int_data - received: %v - expected: %v", number, 6)
+	}
+
+	// select named errors
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	_, err = db.QueryContext(ctx, "select int_data from "+KeyspaceName+"."+TableName+" where text_data = :text", sql.Named("blah", "six"))
+	cancel()
+	if err == nil || !errors.Is(err, ErrNamedValueMissing) {
+		t.Fatalf("QueryContext error - received: %v - expected: %v", err, ErrNamedValueMissing)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	_, err = db.ExecContext(ctx, "delete from "+KeyspaceName+"."+TableName+" where text_data = :text", sql.Named("text", "six"), sql.Named("blah", "six"))
+	cancel()
+	if err == nil || !errors.Is(err, ErrNamedValueUnknown) {
+		t.Fatalf("ExecContext error - received: %v - expected: %v", err, ErrNamedValueUnknown)
+	}
+
+	// delete named
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	result, err = db.ExecContext(ctx, "delete from "+KeyspaceName+"."+TableName+" where text_data = :text", sql.Named("text", "six"))
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	if result == nil {
+		t.Fatal("result is nil")
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("Close error: ", err)
+	}
+}
+
+func TestSqlNamedRepeated(t *testing.T) {
+	server, err := cqltest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+	for _, statement := range []string{
+		"create keyspace named with replication = {'class': 'SimpleStrategy', 'replication_factor': 1}",
+		"create table named.data (id int primary key, value int)",
+		"insert into named.data (id, value) values (3, 0)",
+	} {
+		err = server.Exec(statement)
+		if err != nil {
+			t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+		}
+	}
+
+	db, err := sql.Open("cql", server.Addr()+"?timeout=2s&connectTimeout=2s")
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer db.Close()
+
+	// a named value binds every bind marker with the name
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	defer cancel()
+	stmt, err := db.PrepareContext(ctx, "update named.data set value = :v where id = :v")
+	if err != nil {
+		t.Fatalf("PrepareContext error - received: %v - expected: %v ", err, nil)
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, sql.Named("v", 3))
+	if err != nil {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+	}
+
+	var value int
+	err = db.QueryRowContext(ctx, "select value from named.data where id = ?", 3).Scan(&value)
+	if err != nil {
+		t.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+	}
+	if value != 3 {
+		t.Fatalf("value - received: %v - expected: %v ", value, 3)
+	}
+
+	_, err = stmt.ExecContext(ctx, sql.Named("w", 3))
+	if err == nil || !errors.Is(err, ErrNamedValueMissing) {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, ErrNamedValueMissing)
+	}
+}
+
+func TestSqlTx(t *testing.T) {
+	if DisableDestructiveTests {
+		t.SkipNow()
+	}
+
+	openString := TestHostValid + "?timeout=10s&connectTimeout=10s"
+	if EnableAuthentication {
+		openString += "&username=" + Username + "&password=" + Password
+	}
+
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		t.Fatal("Open error: ", err)
+	}
+	if db == nil {
+		t.Fatal("db is nil")
+	}
+
+	// rollback
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal("BeginTx error: ", err)
+	}
+	_, err = tx.ExecContext(ctx, "insert into "+KeyspaceName+"."+TableName+" (text_data, int_data) values (?, ?)", "tx1", 1)
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	_, err = tx.QueryContext(ctx, "select int_data from "+KeyspaceName+"."+TableName+" where text_data = ?", "tx1")
+	if err != ErrTxStatementNotSupported {
+		t.Fatalf("QueryContext error - received: %v - expected: %v", err, ErrTxStatementNotSupported)
+	}
+	err = tx.Rollback()
+	cancel()
+	if err != nil {
+		t.Fatal("Rollback error: ", err)
+	}
+
+	var count int64
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	err = db.QueryRowContext(ctx, "select count(*) from "+KeyspaceName+"."+TableName+" where text_data in (?, ?)", "tx1", "tx2").Scan(&count)
+	cancel()
+	if err != nil {
+		t.Fatal("Scan error: ", err)
+	}
+	if count != 0 {
+		t.Fatalf("count - received: %v - expected: %v", count, 0)
+	}
+
+	// commit
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal("BeginTx error: ", err)
+	}
+	_, err = tx.ExecContext(ctx, "insert into "+KeyspaceName+"."+TableName+" (text_data, int_data) values (?, ?)", "tx1", 1)
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	_, err = tx.ExecContext(ctx, "insert into "+KeyspaceName+"."+TableName+" (text_data, int_data) values (:text, :number)", sql.Named("text", "tx2"), sql.Named("number", 2))
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	err = tx.Commit()
+	cancel()
+	if err != nil {
+		t.Fatal("Commit error: ", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	err = db.QueryRowContext(ctx, "select count(*) from "+KeyspaceName+"."+TableName+" where text_data in (?, ?)", "tx1", "tx2").Scan(&count)
+	cancel()
+	if err != nil {
+		t.Fatal("Scan error: ", err)
+	}
+	if count != 2 {
+		t.Fatalf("count - received: %v - expected: %v", count, 2)
+	}
+
+	// delete
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	_, err = db.ExecContext(ctx, "delete from "+KeyspaceName+"."+TableName+" where text_data in (?, ?)", "tx1", "tx2")
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("Close error: ", err)
+	}
+}
+
+func TestSqlLightweightTransaction(t *testing.T) {
+	if DisableDestructiveTests {
+		t.SkipNow()
+	}
+
+	openString := TestHostValid + "?timeout=10s&connectTimeout=10s"
+	if EnableAuthentication {
+		openString += "&username=" + Username + "&password=" + Password
+	}
+
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		t.Fatal("Open error: ", err)
+	}
+	if db == nil {
+		t.Fatal("db is nil")
+	}
+
+	// insert if not exists applied
+	casResult := &CASResult{}
+	ctx, cancel := context.WithTimeout(WithCASResult(context.Background(), casResult), TimeoutValid)
+	result, err := db.ExecContext(ctx, "insert into "+KeyspaceName+"."+TableName+" (text_data, int_data) values (?, ?) if not exists", "lwt", 1)
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		t.Fatal("RowsAffected error: ", err)
+	}
+	if num != 1 {
+		t.Fatalf("RowsAffected - received: %v - expected: %v", num, 1)
+	}
+	if !casResult.Applied {
+		t.Fatalf("Applied - received: %v - expected: %v", casResult.Applied, true)
+	}
+
+	// insert if not exists not applied
+	ctx, cancel = context.WithTimeout(WithCASResult(context.Background(), casResult), TimeoutValid)
+	result, err = db.ExecContext(ctx, "insert into "+KeyspaceName+"."+TableName+" (text_data, int_data) values (?, ?) if not exists", "lwt", 2)
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	num, err = result.RowsAffected()
+	if err != nil {
+		t.Fatal("RowsAffected error: ", err)
+	}
+	if num != 0 {
+		t.Fatalf("RowsAffected - received: %v - expected: %v", num, 0)
+	}
+	if casResult.Applied {
+		t.Fatalf("Applied - received: %v - expected: %v", casResult.Applied, false)
+	}
+	if casResult.Existing["int_data"] != 1 {
+		t.Fatalf("Existing int_data - received: %v - expected: %v", casResult.Existing["int_data"], 1)
+	}
+
+	// update if
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	result, err = db.ExecContext(ctx, "update "+KeyspaceName+"."+TableName+" set int_data = ? where text_data = ? if int_data = ?", 3, "lwt", 1)
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	num, err = result.RowsAffected()
+	if err != nil {
+		t.Fatal("RowsAffected error: ", err)
+	}
+	if num != 1 {
+		t.Fatalf("RowsAffected - received: %v - expected: %v", num, 1)
+	}
+
+	// delete if exists
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	result, err = db.ExecContext(ctx, "delete from "+KeyspaceName+"."+TableName+" where text_data = ? if exists", "lwt")
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	num, err = result.RowsAffected()
+	if err != nil {
+		t.Fatal("RowsAffected error: ", err)
+	}
+	if num != 1 {
+		t.Fatalf("RowsAffected - received: %v - expected: %v", num, 1)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("Close error: ", err)
+	}
+}
+
+func TestSqlColumnTypes(t *testing.T) {
+	if DisableDestructiveTests {
+		t.SkipNow()
+	}
+
+	openString := TestHostValid + "?timeout=10s&connectTimeout=10s"
+	if EnableAuthentication {
+		openString += "&username=" + Username + "&password=" + Password
+	}
+
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		t.Fatal("Open error: ", err)
+	}
+	if db == nil {
+		t.Fatal("db is nil")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	rows, err := db.QueryContext(ctx, "select text_data, int_data, timestamp_data, map_data from "+KeyspaceName+"."+TableName)
 	if err != nil {
 		t.Fatal("QueryContext error: ", err)
 	}
-	if rows.Close() == nil {
-		t.Fatal("QueryContext no error")
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal("ColumnTypes error: ", err)
 	}
+	err = rows.Close()
 	cancel()
-	err = rows.Err()
 	if err != nil {
-		t.Fatal("Err error: ", err)
+		t.Fatal("Close error: ", err)
+	}
+
+	names := []string{"TEXT", "INT", "TIMESTAMP", "MAP<TEXT, TEXT>"}
+	scanTypes := []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(0), reflect.TypeOf(time.Time{}), reflect.TypeOf(map[string]string{})}
+	if len(columnTypes) != len(names) {
+		t.Fatalf("ColumnTypes len - received: %v - expected: %v", len(columnTypes), len(names))
+	}
+	for i := 0; i < len(columnTypes); i++ {
+		if columnTypes[i].DatabaseTypeName() != names[i] {
+			t.Fatalf("DatabaseTypeName - received: %v - expected: %v", columnTypes[i].DatabaseTypeName(), names[i])
+		}
+		if columnTypes[i].ScanType() != scanTypes[i] {
+			t.Fatalf("ScanType - received: %v - expected: %v", columnTypes[i].ScanType(), scanTypes[i])
+		}
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("Close error: ", err)
+	}
+}
+
+func TestSqlCollections(t *testing.T) {
+	if DisableDestructiveTests {
+		t.SkipNow()
+	}
+
+	openString := TestHostValid + "?timeout=10s&connectTimeout=10s"
+	if EnableAuthentication {
+		openString += "&username=" + Username + "&password=" + Password
+	}
+
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		t.Fatal("Open error: ", err)
+	}
+	if db == nil {
+		t.Fatal("db is nil")
+	}
+
+	// insert Map
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	result, err := db.ExecContext(ctx, "insert into "+KeyspaceName+"."+TableName+" (text_data, map_data) values (?, ?)", "map", Map{"a": "b"})
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	if result == nil {
+		t.Fatal("result is nil")
+	}
+
+	// select Map
+	var aMap Map
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	err = db.QueryRowContext(ctx, "select map_data from "+KeyspaceName+"."+TableName+" where text_data = ?", "map").Scan(&aMap)
+	cancel()
+	if err != nil {
+		t.Fatal("Scan error: ", err)
+	}
+	if !reflect.DeepEqual(aMap, Map{"a": "b"}) {
+		t.Fatalf("map_data - received: %v - expected: %v", aMap, Map{"a": "b"})
+	}
+
+	// delete Map
+	ctx, cancel = context.WithTimeout(context.Background(), TimeoutValid)
+	result, err = db.ExecContext(ctx, "delete from "+KeyspaceName+"."+TableName+" where text_data = ?", "map")
+	cancel()
+	if err != nil {
+		t.Fatal("ExecContext error: ", err)
+	}
+	if result == nil {
+		t.Fatal("result is nil")
 	}
 
 	err = db.Close()
@@ -738,3 +1294,103 @@ func TestSqlDrop(t *testing.T) {
 		t.Fatal("Close error: ", err)
 	}
 }
+
+func TestSqlPaging(t *testing.T) {
+	server, err := cqltest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+	for _, statement := range []string{
+		"create keyspace paging with replication = {'class': 'SimpleStrategy', 'replication_factor': 1}",
+		"create table paging.data (id int, seq int, primary key (id, seq))",
+		"insert into paging.data (id, seq) values (1, 0)",
+		"insert into paging.data (id, seq) values (1, 1)",
+		"insert into paging.data (id, seq) values (1, 2)",
+		"insert into paging.data (id, seq) values (1, 3)",
+		"insert into paging.data (id, seq) values (1, 4)",
+	} {
+		err = server.Exec(statement)
+		if err != nil {
+			t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+		}
+	}
+
+	db, err := sql.Open("cql", server.Addr()+"?timeout=2s&connectTimeout=2s")
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer db.Close()
+
+	// query a page at a time like an API using page tokens
+	queryPage := func(pageToken string) ([]int, string) {
+		pageState, err := DecodePageState(pageToken)
+		if err != nil {
+			t.Fatalf("DecodePageState error - received: %v - expected: %v ", err, nil)
+		}
+		pageResult := &PageResult{}
+		ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+		defer cancel()
+		ctx = WithPageResult(WithPageState(WithPageSize(ctx, 2), pageState), pageResult)
+
+		rows, err := db.QueryContext(ctx, "select seq from paging.data where id = ?", 1)
+		if err != nil {
+			t.Fatalf("QueryContext error - received: %v - expected: %v ", err, nil)
+		}
+		var seqs []int
+		for rows.Next() {
+			var seq int
+			err = rows.Scan(&seq)
+			if err != nil {
+				t.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+			}
+			seqs = append(seqs, seq)
+		}
+		err = rows.Close()
+		if err != nil {
+			t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+		}
+		return seqs, EncodePageState(pageResult.PageState)
+	}
+
+	var pages [][]int
+	pageToken := ""
+	for {
+		seqs, nextPageToken := queryPage(pageToken)
+		pages = append(pages, seqs)
+		if nextPageToken == "" {
+			break
+		}
+		if len(pages) > 3 {
+			t.Fatalf("pages - received: %v - expected: %v ", pages, 3)
+		}
+		pageToken = nextPageToken
+	}
+	expected := [][]int{{0, 1}, {2, 3}, {4}}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Fatalf("pages - received: %v - expected: %v ", pages, expected)
+	}
+
+	// without a page state all the pages are returned and there is no next page
+	pageResult := &PageResult{PageState: []byte{1}}
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	defer cancel()
+	rows, err := db.QueryContext(WithPageResult(WithPageSize(ctx, 2), pageResult), "select seq from paging.data where id = ?", 1)
+	if err != nil {
+		t.Fatalf("QueryContext error - received: %v - expected: %v ", err, nil)
+	}
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	err = rows.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+	if count != 5 {
+		t.Fatalf("count - received: %v - expected: %v ", count, 5)
+	}
+	if pageResult.PageState != nil {
+		t.Fatalf("PageState - received: %v - expected: %v ", pageResult.PageState, nil)
+	}
+}
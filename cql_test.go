@@ -9,6 +9,8 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/MichaelS11/go-cql-driver/cqltest"
 )
 
 var (
@@ -27,6 +29,7 @@ var (
 	Username                  string
 	Password                  string
 	TestTimeNow               time.Time
+	TestServer                *cqltest.Server
 )
 
 func TestMain(m *testing.M) {
@@ -35,11 +38,14 @@ func TestMain(m *testing.M) {
 		os.Exit(code)
 	}
 	code = m.Run()
+	if TestServer != nil {
+		TestServer.Close()
+	}
 	os.Exit(code)
 }
 
 func setupForTesting() int {
-	flag.StringVar(&TestHostValid, "hostValid", "127.0.0.1", "a host where a Cassandra database is running")
+	flag.StringVar(&TestHostValid, "hostValid", "", "a host where a Cassandra database is running, empty uses the in-process cqltest server")
 	flag.StringVar(&TestHostInvalid, "hostInvalid", "169.254.200.200", "a host where a Cassandra database is not running")
 	flag.StringVar(&ConnectTimeoutValidString, "connectTimeoutValid", "20s", "the connect timeout time duration for host valid tests (ClusterConfig.ConnectTimeout)")
 	connectTimeoutInvalidString := flag.String("connectTimeoutInvalid", "1ms", "the connect timeout time duration for host invalid tests (ClusterConfig.ConnectTimeout)")
@@ -67,6 +73,18 @@ func setupForTesting() int {
 		return 6
 	}
 
+	if TestHostValid == "" {
+		TestServer, err = cqltest.NewServer()
+		if err != nil {
+			fmt.Println("cqltest NewServer error:", err)
+			return 8
+		}
+		if EnableAuthentication {
+			TestServer.SetCredentials(Username, Password)
+		}
+		TestHostValid = TestServer.Addr()
+	}
+
 	TestTimeNow = time.Now().UTC().Truncate(time.Millisecond)
 	TableName += TestTimeNow.Format("20060102150405")
 
@@ -0,0 +1,594 @@
+package cqltest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Error returns the error message
+func (err *Error) Error() string {
+	return err.Message
+}
+
+func newError(code int, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func syntaxError(message string) *Error {
+	return newError(ErrorCodeSyntax, message)
+}
+
+func invalidError(message string) *Error {
+	return newError(ErrorCodeInvalid, message)
+}
+
+func unauthorizedError(message string) *Error {
+	return newError(ErrorCodeUnauthorized, message)
+}
+
+// toError converts an error to a native protocol error
+func toError(err error) *Error {
+	if cqlError, ok := err.(*Error); ok {
+		return cqlError
+	}
+	return newError(ErrorCodeServer, err.Error())
+}
+
+// prepare parses a statement and resolves its bind markers and result columns against the schema
+func (store *storeStruct) prepare(statement string, keyspace string) (*preparedStruct, error) {
+	hash := md5.Sum([]byte(keyspace + "\x00" + statement))
+	prepared := &preparedStruct{
+		id:        hash[:],
+		statement: statement,
+		keyspace:  keyspace,
+	}
+
+	parsed, markers, err := parseStatement(statement)
+	if err != nil {
+		return prepared, err
+	}
+	prepared.parsed = parsed
+	prepared.markers = markers
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, marker := range markers {
+		marker.typeInfo, err = store.markerType(marker, keyspace)
+		if err != nil {
+			return prepared, err
+		}
+	}
+
+	var table *tableStruct
+	switch parsed.kind {
+	case statementSelect, statementInsert, statementUpdate, statementDelete:
+		table, err = store.table(parsed.keyspace, parsed.name, keyspace)
+		if err != nil {
+			return prepared, err
+		}
+	}
+
+	switch parsed.kind {
+	case statementSelect:
+		prepared.columns, _, err = selectColumns(table, parsed.selectors)
+		if err != nil {
+			return prepared, err
+		}
+		for _, relation := range parsed.relations {
+			if _, ok := table.columnMap[relation.column]; !ok {
+				return prepared, invalidError("Undefined column name " + relation.column)
+			}
+		}
+	case statementInsert:
+		for _, column := range parsed.columns {
+			if _, ok := table.columnMap[column]; !ok {
+				return prepared, invalidError("Undefined column name " + column)
+			}
+		}
+	}
+
+	if table != nil {
+		for _, column := range table.partitionKey {
+			index := -1
+			for i, marker := range markers {
+				if marker.kind == markerValue && marker.column == column.name {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				prepared.pkIndexes = nil
+				break
+			}
+			prepared.pkIndexes = append(prepared.pkIndexes, index)
+		}
+	}
+
+	return prepared, nil
+}
+
+// keyspace returns a keyspace by name, using the connection keyspace when empty
+func (store *storeStruct) keyspace(name string, current string) (*keyspaceStruct, error) {
+	if name == "" {
+		name = current
+	}
+	if name == "" {
+		return nil, invalidError("No keyspace has been specified. USE a keyspace, or explicitly specify keyspace.tablename")
+	}
+	keyspace, ok := store.keyspaces[name]
+	if !ok {
+		return nil, invalidError("Keyspace " + name + " does not exist")
+	}
+	return keyspace, nil
+}
+
+// table returns a table by keyspace and name
+func (store *storeStruct) table(keyspaceName string, name string, current string) (*tableStruct, error) {
+	keyspace, err := store.keyspace(keyspaceName, current)
+	if err != nil {
+		return nil, err
+	}
+	table, ok := keyspace.tables[name]
+	if !ok {
+		return nil, invalidError("unconfigured table " + name)
+	}
+	return table, nil
+}
+
+// markerType returns the type of a bind marker
+func (store *storeStruct) markerType(marker *markerStruct, current string) (*typeStruct, error) {
+	switch marker.kind {
+	case markerLimit, markerTTL:
+		return &typeStruct{id: typeInt}, nil
+	case markerTimestamp:
+		return &typeStruct{id: typeBigInt}, nil
+	}
+
+	table, err := store.table(marker.keyspace, marker.table, current)
+	if err != nil {
+		return nil, err
+	}
+	column, ok := table.columnMap[marker.column]
+	if !ok {
+		return nil, invalidError("Undefined column name " + marker.column)
+	}
+
+	typeInfo := column.typeInfo
+	switch marker.kind {
+	case markerInList:
+		return &typeStruct{id: typeList, elems: []*typeStruct{typeInfo}}, nil
+	case markerMapKey:
+		if typeInfo.id == typeList {
+			return &typeStruct{id: typeInt}, nil
+		}
+		if typeInfo.id != typeMap {
+			return nil, invalidError("Invalid operation for non map column " + marker.column)
+		}
+		return typeInfo.elems[0], nil
+	case markerMapValue:
+		if typeInfo.id == typeList {
+			return typeInfo.elems[0], nil
+		}
+		if typeInfo.id != typeMap {
+			return nil, invalidError("Invalid operation for non map column " + marker.column)
+		}
+		return typeInfo.elems[1], nil
+	}
+	if typeInfo.id == typeCounter {
+		return &typeStruct{id: typeBigInt}, nil
+	}
+	return typeInfo, nil
+}
+
+// selectColumns returns the result columns of selectors and the table column index of each, -1 for count
+func selectColumns(table *tableStruct, selectors []selectorStruct) ([]*resultColumnStruct, []int, error) {
+	var columns []*resultColumnStruct
+	var indexes []int
+	if len(selectors) == 0 {
+		for _, column := range table.columns {
+			columns = append(columns, &resultColumnStruct{keyspace: table.keyspace, table: table.name, name: column.name, typeInfo: column.typeInfo})
+			indexes = append(indexes, column.index)
+		}
+		return columns, indexes, nil
+	}
+
+	for _, selector := range selectors {
+		name := selector.alias
+		if selector.count {
+			if name == "" {
+				name = "count"
+			}
+			columns = append(columns, &resultColumnStruct{keyspace: table.keyspace, table: table.name, name: name, typeInfo: &typeStruct{id: typeBigInt}})
+			indexes = append(indexes, -1)
+			continue
+		}
+		column, ok := table.columnMap[selector.column]
+		if !ok {
+			return nil, nil, invalidError("Undefined column name " + selector.column)
+		}
+		if name == "" {
+			name = column.name
+		}
+		columns = append(columns, &resultColumnStruct{keyspace: table.keyspace, table: table.name, name: name, typeInfo: column.typeInfo})
+		indexes = append(indexes, column.index)
+	}
+	return columns, indexes, nil
+}
+
+// bindValues orders values by bind marker, using names when the client sent them
+func bindValues(prepared *preparedStruct, parameters *queryParametersStruct) ([]boundValueStruct, error) {
+	values := parameters.values
+	if len(parameters.names) > 0 {
+		values = make([]boundValueStruct, len(prepared.markers))
+		for i, marker := range prepared.markers {
+			found := false
+			for j, name := range parameters.names {
+				if strings.EqualFold(name, marker.name) {
+					values[i] = parameters.values[j]
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, invalidError("Invalid amount of bind variables")
+			}
+		}
+	}
+	if len(values) != len(prepared.markers) {
+		return nil, invalidError(fmt.Sprintf("Invalid amount of bind variables: expected %v, received %v", len(prepared.markers), len(values)))
+	}
+	return values, nil
+}
+
+// execute runs a prepared statement with bound values
+func (store *storeStruct) execute(prepared *preparedStruct, values []boundValueStruct, parameters *queryParametersStruct, conn *serverConnStruct) (*resultStruct, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	parsed := prepared.parsed
+	executor := &executorStruct{store: store, values: values, keyspace: prepared.keyspace}
+	if conn != nil && conn.keyspace != "" && prepared.keyspace == "" {
+		executor.keyspace = conn.keyspace
+	}
+
+	switch parsed.kind {
+	case statementSelect:
+		return executor.executeSelect(parsed, parameters)
+	case statementInsert, statementUpdate, statementDelete:
+		return executor.executeModifications([]*statementStruct{parsed}, [][]boundValueStruct{values})
+	case statementBatch:
+		valueSets := make([][]boundValueStruct, len(parsed.statements))
+		for i := range valueSets {
+			valueSets[i] = values
+		}
+		return executor.executeModifications(parsed.statements, valueSets)
+	case statementUse:
+		_, err := store.keyspace(parsed.keyspace, "")
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			conn.keyspace = parsed.keyspace
+		}
+		return &resultStruct{kind: resultSetKeyspace, keyspace: parsed.keyspace}, nil
+	case statementCreateKeyspace, statementDropKeyspace:
+		return executor.executeKeyspace(parsed)
+	case statementTruncate:
+		table, err := store.table(parsed.keyspace, parsed.name, executor.keyspace)
+		if err != nil {
+			return nil, err
+		}
+		if store.keyspaces[table.keyspace].system {
+			return nil, unauthorizedError("system keyspace is not user-modifiable.")
+		}
+		table.rows = make(map[string]*rowStruct)
+		return &resultStruct{kind: resultVoid}, nil
+	}
+	return executor.executeSchema(parsed)
+}
+
+// executeBatch runs the statements of a BATCH message
+func (store *storeStruct) executeBatch(prepared []*preparedStruct, values [][]boundValueStruct, conn *serverConnStruct) (*resultStruct, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	statements := make([]*statementStruct, len(prepared))
+	for i := range prepared {
+		statements[i] = prepared[i].parsed
+	}
+	executor := &executorStruct{store: store}
+	if conn != nil {
+		executor.keyspace = conn.keyspace
+	}
+	executor.prepared = prepared
+	return executor.executeModifications(statements, values)
+}
+
+// evaluate returns the bytes of a term for a type, unset is true for unset bind values
+func (executor *executorStruct) evaluate(term *termStruct, typeInfo *typeStruct) ([]byte, bool, error) {
+	if term.marker >= 0 {
+		value := executor.values[term.marker]
+		return value.data, value.unset, nil
+	}
+
+	switch term.function {
+	case "":
+		data, err := encodeValue(typeInfo, term.value)
+		return data, false, err
+	case "now", "currenttimeuuid":
+		uuid := newTimeUUID()
+		return uuid[:], false, nil
+	case "uuid":
+		uuid := newUUID()
+		return uuid[:], false, nil
+	case "currentdate":
+		data, err := encodeValue(&typeStruct{id: typeDate}, time.Now().UTC())
+		return data, false, err
+	}
+	return encodeInt64(time.Now().UnixNano() / int64(time.Millisecond)), false, nil
+}
+
+// relationValues returns the values a relation compares with
+func (executor *executorStruct) relationValues(relation *relationStruct, column *columnStruct) ([][]byte, error) {
+	if relation.list != nil {
+		data, unset, err := executor.evaluate(relation.list, &typeStruct{id: typeList, elems: []*typeStruct{column.typeInfo}})
+		if err != nil {
+			return nil, err
+		}
+		if unset {
+			return nil, invalidError("Invalid unset value for column " + column.name)
+		}
+		return decodeCollection(data, false)
+	}
+
+	values := make([][]byte, 0, len(relation.terms))
+	for _, term := range relation.terms {
+		data, unset, err := executor.evaluate(term, column.typeInfo)
+		if err != nil {
+			return nil, err
+		}
+		if unset {
+			return nil, invalidError("Invalid unset value for column " + column.name)
+		}
+		values = append(values, data)
+	}
+	return values, nil
+}
+
+// matches returns true if a row value satisfies a relation
+func matches(typeInfo *typeStruct, operator string, value []byte, values [][]byte) bool {
+	switch operator {
+	case "=", "in":
+		for _, compare := range values {
+			if (value == nil) == (compare == nil) && compareValues(typeInfo, value, compare) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(values) != 1 {
+		return false
+	}
+	if operator == "!=" {
+		return (value == nil) != (values[0] == nil) || compareValues(typeInfo, value, values[0]) != 0
+	}
+	if value == nil || values[0] == nil {
+		return false
+	}
+	compare := compareValues(typeInfo, value, values[0])
+	switch operator {
+	case "<":
+		return compare < 0
+	case "<=":
+		return compare <= 0
+	case ">":
+		return compare > 0
+	case ">=":
+		return compare >= 0
+	}
+	return false
+}
+
+// compareValues compares two encoded values of a type
+func compareValues(typeInfo *typeStruct, a []byte, b []byte) int {
+	switch typeInfo.id {
+	case typeBigInt, typeCounter, typeInt, typeSmallInt, typeTinyInt, typeTimestamp, typeTime:
+		if len(a) == len(b) && len(a) > 0 {
+			a = append([]byte{a[0] ^ 0x80}, a[1:]...)
+			b = append([]byte{b[0] ^ 0x80}, b[1:]...)
+		}
+	case typeVarint:
+		return decodeBigInt(a).Cmp(decodeBigInt(b))
+	case typeFloat, typeDouble:
+		x, y := decodeFloat(a), decodeFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return bytes.Compare(a, b)
+}
+
+func decodeBigInt(data []byte) *big.Int {
+	number := new(big.Int).SetBytes(data)
+	if len(data) > 0 && data[0]&0x80 != 0 {
+		number.Sub(number, new(big.Int).Lsh(big.NewInt(1), uint(len(data)*8)))
+	}
+	return number
+}
+
+func decodeFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+func (executor *executorStruct) executeSelect(parsed *statementStruct, parameters *queryParametersStruct) (*resultStruct, error) {
+	table, err := executor.store.table(parsed.keyspace, parsed.name, executor.keyspace)
+	if err != nil {
+		return nil, err
+	}
+	columns, indexes, err := selectColumns(table, parsed.selectors)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, name := range parsed.groupBy {
+		column, ok := table.columnMap[name]
+		if !ok {
+			return nil, invalidError("Undefined column name " + name)
+		}
+		keys := append(append([]*columnStruct{}, table.partitionKey...), table.clusteringKey...)
+		if i >= len(keys) || keys[i] != column {
+			return nil, invalidError("Group by is currently only supported on the columns of the PRIMARY KEY, got " + name)
+		}
+	}
+
+	rows, err := executor.filterRows(table, parsed.relations)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups [][]*rowStruct
+	for _, row := range rows {
+		if len(groups) > 0 && len(parsed.groupBy) > 0 {
+			last := groups[len(groups)-1][0]
+			same := true
+			for _, name := range parsed.groupBy {
+				index := table.columnMap[name].index
+				same = same && bytes.Equal(last.values[index], row.values[index])
+			}
+			if same {
+				groups[len(groups)-1] = append(groups[len(groups)-1], row)
+				continue
+			}
+		}
+		groups = append(groups, []*rowStruct{row})
+	}
+
+	aggregate := false
+	for _, index := range indexes {
+		aggregate = aggregate || index < 0
+	}
+	if aggregate && len(parsed.groupBy) == 0 {
+		groups = [][]*rowStruct{rows}
+	}
+
+	result := &resultStruct{kind: resultRows, columns: columns}
+	for _, group := range groups {
+		if !aggregate {
+			for _, row := range group {
+				result.rows = append(result.rows, projectRow(row, indexes, 0))
+			}
+			continue
+		}
+		var first *rowStruct
+		if len(group) > 0 {
+			first = group[0]
+		}
+		result.rows = append(result.rows, projectRow(first, indexes, int64(len(group))))
+	}
+
+	if parsed.limit != nil {
+		data, unset, err := executor.evaluate(parsed.limit, &typeStruct{id: typeInt})
+		if err != nil {
+			return nil, err
+		}
+		if data == nil || unset || len(data) != 4 || int32(binary.BigEndian.Uint32(data)) <= 0 {
+			return nil, invalidError("LIMIT must be strictly positive")
+		}
+		limit := int(int32(binary.BigEndian.Uint32(data)))
+		if len(result.rows) > limit {
+			result.rows = result.rows[:limit]
+		}
+	}
+
+	if parameters != nil && parameters.pageSize > 0 {
+		offset := 0
+		if len(parameters.pagingState) == 4 {
+			offset = int(binary.BigEndian.Uint32(parameters.pagingState))
+		}
+		if offset > len(result.rows) {
+			offset = len(result.rows)
+		}
+		end := offset + parameters.pageSize
+		if end < len(result.rows) {
+			result.pagingState = encodeInt32(int32(end))
+		} else {
+			end = len(result.rows)
+		}
+		result.rows = result.rows[offset:end]
+	}
+
+	return result, nil
+}
+
+// projectRow returns the selected values of a row, count is used for count selectors
+func projectRow(row *rowStruct, indexes []int, count int64) [][]byte {
+	values := make([][]byte, len(indexes))
+	for i, index := range indexes {
+		switch {
+		case index < 0:
+			values[i] = encodeInt64(count)
+		case row != nil:
+			values[i] = row.values[index]
+		}
+	}
+	return values
+}
+
+// filterRows returns the sorted rows that match all relations
+func (executor *executorStruct) filterRows(table *tableStruct, relations []*relationStruct) ([]*rowStruct, error) {
+	type filterStruct struct {
+		column   *columnStruct
+		operator string
+		values   [][]byte
+	}
+	filters := make([]filterStruct, 0, len(relations))
+	for _, relation := range relations {
+		column, ok := table.columnMap[relation.column]
+		if !ok {
+			return nil, invalidError("Undefined column name " + relation.column)
+		}
+		values, err := executor.relationValues(relation, column)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if value == nil {
+				return nil, invalidError("Invalid null value in condition for column " + column.name)
+			}
+		}
+		filters = append(filters, filterStruct{column: column, operator: relation.operator, values: values})
+	}
+
+	var rows []*rowStruct
+	for _, row := range table.sortedRows() {
+		match := true
+		for _, filter := range filters {
+			if !matches(filter.column.typeInfo, filter.operator, row.values[filter.column.index], filter.values) {
+				match = false
+				break
+			}
+		}
+		if match {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
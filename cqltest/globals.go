@@ -0,0 +1,496 @@
+package cqltest
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+type (
+	// Server is an in-process fake Cassandra server that speaks the native protocol (v3 and v4).
+	// It is backed by an in-memory table store and supports scripted responses and error injection.
+	Server struct {
+		listener    net.Listener
+		mutex       sync.Mutex
+		waitGroup   sync.WaitGroup
+		connections map[*serverConnStruct]struct{}
+		closed      bool
+		username    string
+		password    string
+		authClass   string
+		store       *storeStruct
+		prepared    map[string]*preparedStruct
+		scripts     []*scriptStruct
+		injections  []*injectionStruct
+		statements  []string
+	}
+
+	// Response is a scripted response returned by the Server instead of executing a statement
+	Response struct {
+		// Columns are the result columns, when nil the result is void
+		Columns []Column
+		// Rows are the result rows, one Go value per column
+		Rows [][]interface{}
+		// Err is returned instead of a result when not nil
+		Err *Error
+		// Delay is how long to wait before responding
+		Delay time.Duration
+	}
+
+	// Column is a result column of a scripted response
+	Column struct {
+		// Name is the column name
+		Name string
+		// Type is the CQL type of the column, like text or map<text, int>
+		Type string
+	}
+
+	// Error is a native protocol error returned by the Server
+	Error struct {
+		// Code is the native protocol error code, one of the ErrorCode constants
+		Code int
+		// Message is the error message
+		Message  string
+		keyspace string
+		table    string
+	}
+
+	scriptStruct struct {
+		statement string
+		response  *Response
+	}
+
+	injectionStruct struct {
+		substring string
+		count     int
+		err       *Error
+	}
+
+	serverConnStruct struct {
+		server      *Server
+		conn        net.Conn
+		writeMutex  sync.Mutex
+		version     byte
+		compression string
+		keyspace    string
+		ready       bool
+		events      map[string]bool
+	}
+
+	frameStruct struct {
+		version byte
+		flags   byte
+		stream  int16
+		opcode  byte
+		body    []byte
+	}
+
+	readerStruct struct {
+		data []byte
+		err  error
+	}
+
+	writerStruct struct {
+		data []byte
+	}
+
+	queryParametersStruct struct {
+		consistency uint16
+		values      []boundValueStruct
+		names       []string
+		skipMeta    bool
+		pageSize    int
+		pagingState []byte
+		timestamp   int64
+	}
+
+	boundValueStruct struct {
+		data  []byte
+		unset bool
+	}
+
+	typeStruct struct {
+		id       uint16
+		name     string
+		keyspace string
+		elems    []*typeStruct
+		fields   []string
+	}
+
+	tokenStruct struct {
+		kind int
+		text string
+	}
+
+	parserStruct struct {
+		tokens   []tokenStruct
+		position int
+		markers  []*markerStruct
+		keyspace string
+		table    string
+	}
+
+	markerStruct struct {
+		name     string
+		kind     int
+		column   string
+		keyspace string
+		table    string
+		typeInfo *typeStruct
+	}
+
+	termStruct struct {
+		marker   int
+		value    interface{}
+		function string
+	}
+
+	selectorStruct struct {
+		column string
+		alias  string
+		count  bool
+	}
+
+	relationStruct struct {
+		column   string
+		operator string
+		terms    []*termStruct
+		list     *termStruct
+	}
+
+	assignmentStruct struct {
+		column   string
+		operator string
+		term     *termStruct
+		key      *termStruct
+	}
+
+	definitionStruct struct {
+		name       string
+		typeText   string
+		primaryKey bool
+	}
+
+	statementStruct struct {
+		kind          int
+		keyspace      string
+		name          string
+		ifExists      bool
+		ifNotExists   bool
+		selectors     []selectorStruct
+		relations     []*relationStruct
+		conditions    []*relationStruct
+		columns       []string
+		values        []*termStruct
+		assignments   []*assignmentStruct
+		limit         *termStruct
+		groupBy       []string
+		replication   interface{}
+		durableWrites bool
+		definitions   []*definitionStruct
+		partitionKey  []string
+		clusteringKey []string
+		statements    []*statementStruct
+	}
+
+	preparedStruct struct {
+		id        []byte
+		statement string
+		keyspace  string
+		parsed    *statementStruct
+		markers   []*markerStruct
+		pkIndexes []int
+		columns   []*resultColumnStruct
+		script    *Response
+	}
+
+	resultColumnStruct struct {
+		keyspace string
+		table    string
+		name     string
+		typeInfo *typeStruct
+	}
+
+	resultStruct struct {
+		kind         int
+		keyspace     string
+		columns      []*resultColumnStruct
+		rows         [][][]byte
+		pagingState  []byte
+		changeType   string
+		changeTarget string
+		changeName   string
+	}
+
+	executorStruct struct {
+		store    *storeStruct
+		values   []boundValueStruct
+		keyspace string
+		prepared []*preparedStruct
+	}
+
+	mutationStruct struct {
+		apply   func() error
+		table   *tableStruct
+		cas     bool
+		applied bool
+		columns []*columnStruct
+		row     *rowStruct
+	}
+
+	storeStruct struct {
+		mutex         sync.Mutex
+		keyspaces     map[string]*keyspaceStruct
+		hostID        [16]byte
+		schemaVersion [16]byte
+		address       string
+	}
+
+	keyspaceStruct struct {
+		name          string
+		system        bool
+		replication   map[string]string
+		durableWrites bool
+		tables        map[string]*tableStruct
+		types         map[string]*typeStruct
+	}
+
+	tableStruct struct {
+		keyspace      string
+		name          string
+		columns       []*columnStruct
+		columnMap     map[string]*columnStruct
+		partitionKey  []*columnStruct
+		clusteringKey []*columnStruct
+		rows          map[string]*rowStruct
+	}
+
+	columnStruct struct {
+		name     string
+		typeInfo *typeStruct
+		index    int
+	}
+
+	rowStruct struct {
+		key    string
+		values [][]byte
+	}
+
+	numberLiteral     string
+	identifierLiteral string
+	uuidLiteral       [16]byte
+	listLiteral       []interface{}
+	setLiteral        []interface{}
+	tupleLiteral      []interface{}
+	mapLiteral        struct {
+		keys   []interface{}
+		values []interface{}
+	}
+)
+
+// native protocol error codes
+const (
+	ErrorCodeServer         = 0x0000
+	ErrorCodeProtocol       = 0x000A
+	ErrorCodeBadCredentials = 0x0100
+	ErrorCodeUnavailable    = 0x1000
+	ErrorCodeOverloaded     = 0x1001
+	ErrorCodeBootstrapping  = 0x1002
+	ErrorCodeTruncate       = 0x1003
+	ErrorCodeWriteTimeout   = 0x1100
+	ErrorCodeReadTimeout    = 0x1200
+	ErrorCodeSyntax         = 0x2000
+	ErrorCodeUnauthorized   = 0x2100
+	ErrorCodeInvalid        = 0x2200
+	ErrorCodeConfig         = 0x2300
+	ErrorCodeAlreadyExists  = 0x2400
+	ErrorCodeUnprepared     = 0x2500
+)
+
+const (
+	opError         = 0x00
+	opStartup       = 0x01
+	opReady         = 0x02
+	opAuthenticate  = 0x03
+	opOptions       = 0x05
+	opSupported     = 0x06
+	opQuery         = 0x07
+	opResult        = 0x08
+	opPrepare       = 0x09
+	opExecute       = 0x0A
+	opRegister      = 0x0B
+	opEvent         = 0x0C
+	opBatch         = 0x0D
+	opAuthChallenge = 0x0E
+	opAuthResponse  = 0x0F
+	opAuthSuccess   = 0x10
+
+	flagCompression = 0x01
+	flagTracing     = 0x02
+
+	queryFlagValues      = 0x01
+	queryFlagSkipMeta    = 0x02
+	queryFlagPageSize    = 0x04
+	queryFlagPagingState = 0x08
+	queryFlagSerial      = 0x10
+	queryFlagTimestamp   = 0x20
+	queryFlagNames       = 0x40
+
+	resultVoid         = 0x0001
+	resultRows         = 0x0002
+	resultSetKeyspace  = 0x0003
+	resultPrepared     = 0x0004
+	resultSchemaChange = 0x0005
+
+	metaGlobalTableSpec = 0x0001
+	metaHasMorePages    = 0x0002
+	metaNoMetadata      = 0x0004
+
+	maxFrameLength = 256 * 1024 * 1024
+)
+
+const (
+	typeCustom    = 0x0000
+	typeASCII     = 0x0001
+	typeBigInt    = 0x0002
+	typeBlob      = 0x0003
+	typeBoolean   = 0x0004
+	typeCounter   = 0x0005
+	typeDecimal   = 0x0006
+	typeDouble    = 0x0007
+	typeFloat     = 0x0008
+	typeInt       = 0x0009
+	typeTimestamp = 0x000B
+	typeUUID      = 0x000C
+	typeVarchar   = 0x000D
+	typeVarint    = 0x000E
+	typeTimeUUID  = 0x000F
+	typeInet      = 0x0010
+	typeDate      = 0x0011
+	typeTime      = 0x0012
+	typeSmallInt  = 0x0013
+	typeTinyInt   = 0x0014
+	typeList      = 0x0020
+	typeMap       = 0x0021
+	typeSet       = 0x0022
+	typeUDT       = 0x0030
+	typeTuple     = 0x0031
+)
+
+const (
+	tokenEOF = iota
+	tokenIdentifier
+	tokenQuotedIdentifier
+	tokenString
+	tokenNumber
+	tokenUUID
+	tokenBlob
+	tokenMarker
+	tokenNamedMarker
+	tokenSymbol
+)
+
+const (
+	markerValue = iota
+	markerInList
+	markerMapKey
+	markerMapValue
+	markerLimit
+	markerTTL
+	markerTimestamp
+)
+
+const (
+	statementSelect = iota
+	statementInsert
+	statementUpdate
+	statementDelete
+	statementBatch
+	statementUse
+	statementCreateKeyspace
+	statementDropKeyspace
+	statementCreateTable
+	statementDropTable
+	statementTruncate
+	statementCreateType
+	statementDropType
+)
+
+var (
+	// ErrServerClosed is returned when the Server has been closed
+	ErrServerClosed = fmt.Errorf("server closed")
+
+	// CQLVersion is the CQL version reported by the Server
+	CQLVersion = "3.4.4"
+	// ReleaseVersion is the Cassandra release version reported by the Server
+	ReleaseVersion = "3.11.6"
+	// ClusterName is the cluster name reported by the Server
+	ClusterName = "Test Cluster"
+	// DataCenter is the data center reported by the Server
+	DataCenter = "datacenter1"
+	// Rack is the rack reported by the Server
+	Rack = "rack1"
+
+	systemKeyspaces = []string{"system", "system_schema", "system_auth", "system_distributed", "system_traces"}
+	nativeTypes     = map[string]uint16{
+		"ascii":     typeASCII,
+		"bigint":    typeBigInt,
+		"blob":      typeBlob,
+		"boolean":   typeBoolean,
+		"counter":   typeCounter,
+		"decimal":   typeDecimal,
+		"double":    typeDouble,
+		"float":     typeFloat,
+		"int":       typeInt,
+		"timestamp": typeTimestamp,
+		"uuid":      typeUUID,
+		"text":      typeVarchar,
+		"varchar":   typeVarchar,
+		"varint":    typeVarint,
+		"timeuuid":  typeTimeUUID,
+		"inet":      typeInet,
+		"date":      typeDate,
+		"time":      typeTime,
+		"smallint":  typeSmallInt,
+		"tinyint":   typeTinyInt,
+	}
+	typeNames = map[uint16]string{
+		typeASCII:     "ascii",
+		typeBigInt:    "bigint",
+		typeBlob:      "blob",
+		typeBoolean:   "boolean",
+		typeCounter:   "counter",
+		typeDecimal:   "decimal",
+		typeDouble:    "double",
+		typeFloat:     "float",
+		typeInt:       "int",
+		typeTimestamp: "timestamp",
+		typeUUID:      "uuid",
+		typeVarchar:   "text",
+		typeVarint:    "varint",
+		typeTimeUUID:  "timeuuid",
+		typeInet:      "inet",
+		typeDate:      "date",
+		typeTime:      "time",
+		typeSmallInt:  "smallint",
+		typeTinyInt:   "tinyint",
+	}
+	errorCodes = map[int]bool{
+		ErrorCodeServer:         true,
+		ErrorCodeProtocol:       true,
+		ErrorCodeBadCredentials: true,
+		ErrorCodeUnavailable:    true,
+		ErrorCodeOverloaded:     true,
+		ErrorCodeBootstrapping:  true,
+		ErrorCodeTruncate:       true,
+		ErrorCodeWriteTimeout:   true,
+		ErrorCodeReadTimeout:    true,
+		ErrorCodeSyntax:         true,
+		ErrorCodeUnauthorized:   true,
+		ErrorCodeInvalid:        true,
+		ErrorCodeConfig:         true,
+		ErrorCodeAlreadyExists:  true,
+		ErrorCodeUnprepared:     true,
+	}
+)
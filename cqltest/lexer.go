@@ -0,0 +1,181 @@
+package cqltest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenize splits a CQL statement into tokens, identifiers are lower cased unless quoted
+func tokenize(statement string) ([]tokenStruct, error) {
+	var tokens []tokenStruct
+	i := 0
+	for i < len(statement) {
+		char := statement[i]
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			i++
+
+		case strings.HasPrefix(statement[i:], "--") || strings.HasPrefix(statement[i:], "//"):
+			end := strings.IndexByte(statement[i:], '\n')
+			if end < 0 {
+				i = len(statement)
+			} else {
+				i += end + 1
+			}
+
+		case strings.HasPrefix(statement[i:], "/*"):
+			end := strings.Index(statement[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+
+		case char == '\'' || char == '"':
+			text, end, err := tokenizeQuoted(statement, i, char)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokenString
+			if char == '"' {
+				kind = tokenQuotedIdentifier
+			}
+			tokens = append(tokens, tokenStruct{kind: kind, text: text})
+			i = end
+
+		case strings.HasPrefix(statement[i:], "$$"):
+			end := strings.Index(statement[i+2:], "$$")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, tokenStruct{kind: tokenString, text: statement[i+2 : i+2+end]})
+			i += end + 4
+
+		case isUUIDAt(statement, i):
+			tokens = append(tokens, tokenStruct{kind: tokenUUID, text: strings.ToLower(statement[i : i+36])})
+			i += 36
+
+		case (char == '0') && i+1 < len(statement) && (statement[i+1] == 'x' || statement[i+1] == 'X'):
+			end := i + 2
+			for end < len(statement) && isHexDigit(statement[end]) {
+				end++
+			}
+			tokens = append(tokens, tokenStruct{kind: tokenBlob, text: strings.ToLower(statement[i+2 : end])})
+			i = end
+
+		case isDigit(char) || (char == '.' && i+1 < len(statement) && isDigit(statement[i+1])):
+			end := i
+			for end < len(statement) && (isDigit(statement[end]) || statement[end] == '.') {
+				end++
+			}
+			if end < len(statement) && (statement[end] == 'e' || statement[end] == 'E') {
+				end++
+				if end < len(statement) && (statement[end] == '+' || statement[end] == '-') {
+					end++
+				}
+				for end < len(statement) && isDigit(statement[end]) {
+					end++
+				}
+			}
+			tokens = append(tokens, tokenStruct{kind: tokenNumber, text: statement[i:end]})
+			i = end
+
+		case isIdentifierStart(char):
+			end := i + 1
+			for end < len(statement) && isIdentifierPart(statement[end]) {
+				end++
+			}
+			tokens = append(tokens, tokenStruct{kind: tokenIdentifier, text: strings.ToLower(statement[i:end])})
+			i = end
+
+		case char == '?':
+			tokens = append(tokens, tokenStruct{kind: tokenMarker, text: "?"})
+			i++
+
+		case char == ':' && i+1 < len(statement) && (isIdentifierStart(statement[i+1]) || statement[i+1] == '"'):
+			if statement[i+1] == '"' {
+				text, end, err := tokenizeQuoted(statement, i+1, '"')
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, tokenStruct{kind: tokenNamedMarker, text: text})
+				i = end
+				continue
+			}
+			end := i + 2
+			for end < len(statement) && isIdentifierPart(statement[end]) {
+				end++
+			}
+			tokens = append(tokens, tokenStruct{kind: tokenNamedMarker, text: strings.ToLower(statement[i+1 : end])})
+			i = end
+
+		case i+1 < len(statement) && (statement[i:i+2] == "<=" || statement[i:i+2] == ">=" || statement[i:i+2] == "!="):
+			tokens = append(tokens, tokenStruct{kind: tokenSymbol, text: statement[i : i+2]})
+			i += 2
+
+		case strings.IndexByte("(),;.=<>*[]{}+-:", char) >= 0:
+			tokens = append(tokens, tokenStruct{kind: tokenSymbol, text: string(char)})
+			i++
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q", char)
+		}
+	}
+
+	return append(tokens, tokenStruct{kind: tokenEOF}), nil
+}
+
+// tokenizeQuoted returns the unescaped text of a quoted string or identifier and the index after it
+func tokenizeQuoted(statement string, start int, quote byte) (string, int, error) {
+	var builder strings.Builder
+	i := start + 1
+	for i < len(statement) {
+		if statement[i] == quote {
+			if i+1 < len(statement) && statement[i+1] == quote {
+				builder.WriteByte(quote)
+				i += 2
+				continue
+			}
+			return builder.String(), i + 1, nil
+		}
+		builder.WriteByte(statement[i])
+		i++
+	}
+	return "", 0, fmt.Errorf("unterminated quote")
+}
+
+// isUUIDAt returns true if a UUID literal starts at index i
+func isUUIDAt(statement string, i int) bool {
+	if len(statement)-i < 36 {
+		return false
+	}
+	for j := 0; j < 36; j++ {
+		char := statement[i+j]
+		switch j {
+		case 8, 13, 18, 23:
+			if char != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(char) {
+				return false
+			}
+		}
+	}
+	return len(statement)-i == 36 || !isIdentifierPart(statement[i+36])
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+func isHexDigit(char byte) bool {
+	return isDigit(char) || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
+}
+
+func isIdentifierStart(char byte) bool {
+	return char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+func isIdentifierPart(char byte) bool {
+	return isIdentifierStart(char) || isDigit(char)
+}
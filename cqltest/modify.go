@@ -0,0 +1,581 @@
+package cqltest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+)
+
+// executeModifications runs insert, update, and delete statements as one atomic batch
+func (executor *executorStruct) executeModifications(statements []*statementStruct, valueSets [][]boundValueStruct) (*resultStruct, error) {
+	keyspace := executor.keyspace
+	mutations := make([]*mutationStruct, 0, len(statements))
+	conditional := false
+	applied := true
+	for i, statement := range statements {
+		executor.values = valueSets[i]
+		executor.keyspace = keyspace
+		if executor.prepared != nil && executor.prepared[i].keyspace != "" {
+			executor.keyspace = executor.prepared[i].keyspace
+		}
+
+		var mutation *mutationStruct
+		var err error
+		switch statement.kind {
+		case statementInsert:
+			mutation, err = executor.planInsert(statement)
+		case statementUpdate:
+			mutation, err = executor.planUpdate(statement)
+		case statementDelete:
+			mutation, err = executor.planDelete(statement)
+		default:
+			err = invalidError("Invalid statement in batch: only UPDATE, INSERT and DELETE statements are allowed.")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if mutation.cas {
+			conditional = true
+			applied = applied && mutation.applied
+		}
+		mutations = append(mutations, mutation)
+	}
+
+	if !applied {
+		if len(mutations) == 1 {
+			return casResult(mutations[0]), nil
+		}
+		return casResult(&mutationStruct{table: mutations[0].table}), nil
+	}
+
+	for _, mutation := range mutations {
+		err := mutation.apply()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if conditional {
+		return casResult(&mutationStruct{table: mutations[0].table, applied: true}), nil
+	}
+	return &resultStruct{kind: resultVoid}, nil
+}
+
+// casResult returns the rows result of a lightweight transaction
+func casResult(mutation *mutationStruct) *resultStruct {
+	table := mutation.table
+	result := &resultStruct{
+		kind: resultRows,
+		columns: []*resultColumnStruct{
+			{keyspace: table.keyspace, table: table.name, name: "[applied]", typeInfo: &typeStruct{id: typeBoolean}},
+		},
+	}
+	values := [][]byte{{0}}
+	if mutation.applied {
+		values[0][0] = 1
+	} else {
+		for _, column := range mutation.columns {
+			result.columns = append(result.columns, &resultColumnStruct{keyspace: table.keyspace, table: table.name, name: column.name, typeInfo: column.typeInfo})
+			var value []byte
+			if mutation.row != nil {
+				value = mutation.row.values[column.index]
+			}
+			values = append(values, value)
+		}
+	}
+	result.rows = [][][]byte{values}
+	return result
+}
+
+// modifiableTable returns the table of a statement if it is not a system table
+func (executor *executorStruct) modifiableTable(statement *statementStruct) (*tableStruct, error) {
+	table, err := executor.store.table(statement.keyspace, statement.name, executor.keyspace)
+	if err != nil {
+		return nil, err
+	}
+	if executor.store.keyspaces[table.keyspace].system {
+		return nil, unauthorizedError("system keyspace is not user-modifiable.")
+	}
+	return table, nil
+}
+
+func (executor *executorStruct) planInsert(statement *statementStruct) (*mutationStruct, error) {
+	table, err := executor.modifiableTable(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, len(table.columns))
+	set := make([]bool, len(table.columns))
+	for i, name := range statement.columns {
+		column, ok := table.columnMap[name]
+		if !ok {
+			return nil, invalidError("Undefined column name " + name)
+		}
+		if set[column.index] {
+			return nil, invalidError("Multiple definitions found for column " + name)
+		}
+		data, unset, err := executor.evaluate(statement.values[i], column.typeInfo)
+		if err != nil {
+			return nil, err
+		}
+		if unset {
+			if table.isPrimaryKey(column) {
+				return nil, invalidError("Invalid unset value for column " + name)
+			}
+			continue
+		}
+		values[column.index] = data
+		set[column.index] = true
+	}
+
+	for _, column := range table.partitionKey {
+		if !set[column.index] {
+			return nil, invalidError("Some partition key parts are missing: " + column.name)
+		}
+		if values[column.index] == nil {
+			return nil, invalidError("Invalid null value for partition key part " + column.name)
+		}
+	}
+	for _, column := range table.clusteringKey {
+		if !set[column.index] {
+			return nil, invalidError("Some clustering keys are missing: " + column.name)
+		}
+		if values[column.index] == nil {
+			return nil, invalidError("Invalid null value for clustering key part " + column.name)
+		}
+	}
+
+	mutation := &mutationStruct{
+		table: table,
+		apply: func() error {
+			table.upsert(values, set)
+			return nil
+		},
+	}
+	if statement.ifNotExists {
+		mutation.cas = true
+		mutation.row = table.rows[table.rowKey(values)]
+		mutation.applied = mutation.row == nil
+		mutation.columns = table.columns
+	}
+	return mutation, nil
+}
+
+// primaryKeys returns the primary key values selected by = and IN relations, each indexed by column
+func (executor *executorStruct) primaryKeys(table *tableStruct, relations []*relationStruct) ([][][]byte, error) {
+	keyValues := make(map[int][][]byte)
+	for _, relation := range relations {
+		column, ok := table.columnMap[relation.column]
+		if !ok {
+			return nil, invalidError("Undefined column name " + relation.column)
+		}
+		if !table.isPrimaryKey(column) {
+			return nil, invalidError("Non PRIMARY KEY columns found in where clause: " + column.name)
+		}
+		if relation.operator != "=" && relation.operator != "in" {
+			return nil, invalidError("Invalid operator " + relation.operator + " for PRIMARY KEY part " + column.name)
+		}
+		values, err := executor.relationValues(relation, column)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if value == nil {
+				return nil, invalidError("Invalid null value in condition for column " + column.name)
+			}
+		}
+		keyValues[column.index] = values
+	}
+
+	keys := [][][]byte{make([][]byte, len(table.columns))}
+	for _, column := range append(append([]*columnStruct{}, table.partitionKey...), table.clusteringKey...) {
+		values, ok := keyValues[column.index]
+		if !ok {
+			if table.isPartitionKey(column) {
+				return nil, invalidError("Some partition key parts are missing: " + column.name)
+			}
+			return nil, invalidError("Some clustering keys are missing: " + column.name)
+		}
+		expanded := make([][][]byte, 0, len(keys)*len(values))
+		for _, key := range keys {
+			for _, value := range values {
+				next := append([][]byte{}, key...)
+				next[column.index] = value
+				expanded = append(expanded, next)
+			}
+		}
+		keys = expanded
+	}
+	return keys, nil
+}
+
+// planConditions checks IF EXISTS and IF conditions against the row of a single primary key
+func (executor *executorStruct) planConditions(mutation *mutationStruct, statement *statementStruct, keys [][][]byte) error {
+	if !statement.ifExists && len(statement.conditions) == 0 {
+		return nil
+	}
+	if len(keys) != 1 {
+		return invalidError("IN on the clustering key columns is not supported with conditional updates")
+	}
+
+	table := mutation.table
+	mutation.cas = true
+	mutation.row = table.rows[table.rowKey(keys[0])]
+	if statement.ifExists {
+		mutation.applied = mutation.row != nil
+		return nil
+	}
+
+	mutation.applied = true
+	for _, condition := range statement.conditions {
+		column, ok := table.columnMap[condition.column]
+		if !ok {
+			return invalidError("Undefined column name " + condition.column)
+		}
+		if table.isPrimaryKey(column) {
+			return invalidError("PRIMARY KEY column '" + column.name + "' cannot have IF conditions")
+		}
+		values, err := executor.relationValues(condition, column)
+		if err != nil {
+			return err
+		}
+		var value []byte
+		if mutation.row != nil {
+			value = mutation.row.values[column.index]
+		}
+		if !matches(column.typeInfo, condition.operator, value, values) {
+			mutation.applied = false
+		}
+
+		found := false
+		for _, existing := range mutation.columns {
+			found = found || existing == column
+		}
+		if !found {
+			mutation.columns = append(mutation.columns, column)
+		}
+	}
+	return nil
+}
+
+func (executor *executorStruct) planUpdate(statement *statementStruct) (*mutationStruct, error) {
+	table, err := executor.modifiableTable(statement)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := executor.primaryKeys(table, statement.relations)
+	if err != nil {
+		return nil, err
+	}
+
+	type updateStruct struct {
+		column *columnStruct
+		assign *assignmentStruct
+		key    []byte
+		data   []byte
+	}
+	updates := make([]updateStruct, 0, len(statement.assignments))
+	for _, assignment := range statement.assignments {
+		column, ok := table.columnMap[assignment.column]
+		if !ok {
+			return nil, invalidError("Undefined column name " + assignment.column)
+		}
+		if table.isPrimaryKey(column) {
+			return nil, invalidError("PRIMARY KEY part " + column.name + " found in SET part")
+		}
+
+		update := updateStruct{column: column, assign: assignment}
+		var unset bool
+		if assignment.key != nil {
+			keyType, valueType := &typeStruct{id: typeInt}, column.typeInfo.elems[0]
+			switch column.typeInfo.id {
+			case typeMap:
+				keyType, valueType = column.typeInfo.elems[0], column.typeInfo.elems[1]
+			case typeList:
+			default:
+				return nil, invalidError("Invalid operation (" + column.name + "[?] = ?) for non list/map column " + column.name)
+			}
+			update.key, unset, err = executor.evaluate(assignment.key, keyType)
+			if err != nil {
+				return nil, err
+			}
+			if update.key == nil || unset {
+				return nil, invalidError("Invalid null value for map key or list index of " + column.name)
+			}
+			update.data, unset, err = executor.evaluate(assignment.term, valueType)
+		} else {
+			valueType := column.typeInfo
+			if column.typeInfo.id == typeCounter {
+				valueType = &typeStruct{id: typeBigInt}
+			}
+			if assignment.operator == "-" && column.typeInfo.id == typeMap {
+				valueType = &typeStruct{id: typeSet, elems: column.typeInfo.elems[:1]}
+			}
+			update.data, unset, err = executor.evaluate(assignment.term, valueType)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if unset {
+			continue
+		}
+		updates = append(updates, update)
+	}
+
+	mutation := &mutationStruct{table: table}
+	err = executor.planConditions(mutation, statement, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	mutation.apply = func() error {
+		for _, key := range keys {
+			values := append([][]byte{}, key...)
+			set := make([]bool, len(table.columns))
+			for _, column := range table.columns {
+				set[column.index] = table.isPrimaryKey(column)
+			}
+			row := table.rows[table.rowKey(key)]
+			for _, update := range updates {
+				var old []byte
+				if row != nil {
+					old = row.values[update.column.index]
+				}
+				if set[update.column.index] {
+					old = values[update.column.index]
+				}
+				data, err := applyAssignment(update.column, update.assign, old, update.key, update.data)
+				if err != nil {
+					return err
+				}
+				values[update.column.index] = data
+				set[update.column.index] = true
+			}
+			table.upsert(values, set)
+		}
+		return nil
+	}
+	return mutation, nil
+}
+
+// applyAssignment returns the new value of a column from its old value and an assignment
+func applyAssignment(column *columnStruct, assignment *assignmentStruct, old []byte, key []byte, data []byte) ([]byte, error) {
+	typeInfo := column.typeInfo
+
+	if key != nil {
+		elems, err := decodeCollection(old, typeInfo.id == typeMap)
+		if err != nil {
+			return nil, err
+		}
+		if typeInfo.id == typeList {
+			index := int(int32(binary.BigEndian.Uint32(append(make([]byte, 4-len(key)), key...))))
+			if index < 0 || index >= len(elems) {
+				return nil, invalidError("List index " + strconv.Itoa(index) + " out of bound, list has size " + strconv.Itoa(len(elems)))
+			}
+			if data == nil {
+				elems = append(elems[:index], elems[index+1:]...)
+			} else {
+				elems[index] = data
+			}
+			return collectionOrNull(encodeCollection(elems), len(elems)), nil
+		}
+		pairs := make([][2][]byte, 0, len(elems)/2+1)
+		for i := 0; i+1 < len(elems); i += 2 {
+			if !bytes.Equal(elems[i], key) {
+				pairs = append(pairs, [2][]byte{elems[i], elems[i+1]})
+			}
+		}
+		if data != nil {
+			pairs = append(pairs, [2][]byte{key, data})
+		}
+		return collectionOrNull(encodeMap(pairs), len(pairs)), nil
+	}
+
+	if assignment.operator == "=" {
+		if typeInfo.id == typeCounter {
+			return nil, invalidError("Cannot set the value of counter column " + column.name + " (counters can only be incremented/decremented, not set)")
+		}
+		if typeInfo.id >= typeList && typeInfo.id <= typeSet && data != nil {
+			elems, err := decodeCollection(data, typeInfo.id == typeMap)
+			if err != nil {
+				return nil, err
+			}
+			return collectionOrNull(data, len(elems)), nil
+		}
+		return data, nil
+	}
+
+	if data == nil {
+		return nil, invalidError("Invalid null value for counter increment/decrement or collection operation on " + column.name)
+	}
+
+	switch typeInfo.id {
+	case typeCounter:
+		var total int64
+		if len(old) == 8 {
+			total = int64(binary.BigEndian.Uint64(old))
+		}
+		delta := int64(binary.BigEndian.Uint64(data))
+		if assignment.operator == "-" {
+			delta = -delta
+		}
+		return encodeInt64(total + delta), nil
+
+	case typeList, typeSet:
+		oldElems, err := decodeCollection(old, false)
+		if err != nil {
+			return nil, err
+		}
+		newElems, err := decodeCollection(data, false)
+		if err != nil {
+			return nil, err
+		}
+		var elems [][]byte
+		switch assignment.operator {
+		case "+":
+			elems = append(oldElems, newElems...)
+		case "prepend":
+			elems = append(newElems, oldElems...)
+		case "-":
+			for _, elem := range oldElems {
+				remove := false
+				for _, other := range newElems {
+					remove = remove || bytes.Equal(elem, other)
+				}
+				if !remove {
+					elems = append(elems, elem)
+				}
+			}
+		}
+		if typeInfo.id == typeSet {
+			elems = sortUnique(elems)
+		}
+		return collectionOrNull(encodeCollection(elems), len(elems)), nil
+
+	case typeMap:
+		oldElems, err := decodeCollection(old, true)
+		if err != nil {
+			return nil, err
+		}
+		pairs := make([][2][]byte, 0, len(oldElems)/2)
+		for i := 0; i+1 < len(oldElems); i += 2 {
+			pairs = append(pairs, [2][]byte{oldElems[i], oldElems[i+1]})
+		}
+		if assignment.operator == "-" {
+			removeKeys, err := decodeCollection(data, false)
+			if err != nil {
+				return nil, err
+			}
+			kept := pairs[:0]
+			for _, pair := range pairs {
+				remove := false
+				for _, removeKey := range removeKeys {
+					remove = remove || bytes.Equal(pair[0], removeKey)
+				}
+				if !remove {
+					kept = append(kept, pair)
+				}
+			}
+			return collectionOrNull(encodeMap(kept), len(kept)), nil
+		}
+		newElems, err := decodeCollection(data, true)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(newElems); i += 2 {
+			pairs = append(pairs, [2][]byte{newElems[i], newElems[i+1]})
+		}
+		merged := encodeMap(pairs)
+		count := int(int32(binary.BigEndian.Uint32(merged)))
+		return collectionOrNull(merged, count), nil
+	}
+
+	return nil, invalidError("Invalid operation (" + column.name + " = " + column.name + " " + assignment.operator + " ?) for non list/set/map/counter column " + column.name)
+}
+
+// collectionOrNull returns null for empty collections like Cassandra
+func collectionOrNull(data []byte, count int) []byte {
+	if count == 0 {
+		return nil
+	}
+	return data
+}
+
+func (executor *executorStruct) planDelete(statement *statementStruct) (*mutationStruct, error) {
+	table, err := executor.modifiableTable(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	partition := make(map[*columnStruct]bool)
+	for _, relation := range statement.relations {
+		column, ok := table.columnMap[relation.column]
+		if !ok {
+			return nil, invalidError("Undefined column name " + relation.column)
+		}
+		if !table.isPrimaryKey(column) {
+			return nil, invalidError("Non PRIMARY KEY columns found in where clause: " + column.name)
+		}
+		if table.isPartitionKey(column) {
+			if relation.operator != "=" && relation.operator != "in" {
+				return nil, invalidError("Only EQ and IN relation are supported on the partition key (unless you use the token() function)")
+			}
+			partition[column] = true
+		}
+	}
+	for _, column := range table.partitionKey {
+		if !partition[column] {
+			return nil, invalidError("Some partition key parts are missing: " + column.name)
+		}
+	}
+
+	var columns []*columnStruct
+	for _, name := range statement.columns {
+		column, ok := table.columnMap[name]
+		if !ok {
+			return nil, invalidError("Undefined column name " + name)
+		}
+		if table.isPrimaryKey(column) {
+			return nil, invalidError("Invalid identifier " + name + " for deletion (should not be a PRIMARY KEY part)")
+		}
+		columns = append(columns, column)
+	}
+
+	mutation := &mutationStruct{table: table}
+	if statement.ifExists || len(statement.conditions) > 0 {
+		keys, err := executor.primaryKeys(table, statement.relations)
+		if err != nil {
+			return nil, err
+		}
+		err = executor.planConditions(mutation, statement, keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = executor.filterRows(table, statement.relations)
+	if err != nil {
+		return nil, err
+	}
+
+	values := executor.values
+	mutation.apply = func() error {
+		executor.values = values
+		rows, err := executor.filterRows(table, statement.relations)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if len(columns) == 0 {
+				delete(table.rows, row.key)
+				continue
+			}
+			for _, column := range columns {
+				row.values[column.index] = nil
+			}
+		}
+		return nil
+	}
+	return mutation, nil
+}
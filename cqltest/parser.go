@@ -0,0 +1,1078 @@
+package cqltest
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// parseStatement parses a CQL statement and returns it with its bind markers
+func parseStatement(statement string) (*statementStruct, []*markerStruct, error) {
+	tokens, err := tokenize(statement)
+	if err != nil {
+		return nil, nil, syntaxError(err.Error())
+	}
+
+	parser := &parserStruct{tokens: tokens}
+	parsed, err := parser.parseOne()
+	if err != nil {
+		return nil, nil, err
+	}
+	parser.acceptSymbol(";")
+	if parser.peek().kind != tokenEOF {
+		return nil, nil, parser.unexpected()
+	}
+
+	return parsed, parser.markers, nil
+}
+
+func (parser *parserStruct) peek() tokenStruct {
+	return parser.peekAt(0)
+}
+
+func (parser *parserStruct) peekAt(offset int) tokenStruct {
+	if parser.position+offset >= len(parser.tokens) {
+		return tokenStruct{kind: tokenEOF}
+	}
+	return parser.tokens[parser.position+offset]
+}
+
+func (parser *parserStruct) next() tokenStruct {
+	token := parser.peek()
+	parser.position++
+	return token
+}
+
+func (parser *parserStruct) isKeyword(keyword string) bool {
+	token := parser.peek()
+	return token.kind == tokenIdentifier && token.text == keyword
+}
+
+func (parser *parserStruct) accept(keyword string) bool {
+	if parser.isKeyword(keyword) {
+		parser.position++
+		return true
+	}
+	return false
+}
+
+func (parser *parserStruct) expect(keywords ...string) error {
+	for _, keyword := range keywords {
+		if !parser.accept(keyword) {
+			return parser.unexpected()
+		}
+	}
+	return nil
+}
+
+func (parser *parserStruct) isSymbol(symbol string) bool {
+	token := parser.peek()
+	return token.kind == tokenSymbol && token.text == symbol
+}
+
+func (parser *parserStruct) acceptSymbol(symbol string) bool {
+	if parser.isSymbol(symbol) {
+		parser.position++
+		return true
+	}
+	return false
+}
+
+func (parser *parserStruct) expectSymbol(symbol string) error {
+	if !parser.acceptSymbol(symbol) {
+		return parser.unexpected()
+	}
+	return nil
+}
+
+// unexpected returns a syntax error for the current token
+func (parser *parserStruct) unexpected() error {
+	token := parser.peek()
+	if token.kind == tokenEOF {
+		return syntaxError("line 1:0 mismatched input '<EOF>'")
+	}
+	return syntaxError("line 1:0 no viable alternative at input '" + token.text + "'")
+}
+
+// parseIdentifier parses an unquoted or quoted identifier
+func (parser *parserStruct) parseIdentifier() (string, error) {
+	token := parser.peek()
+	if token.kind != tokenIdentifier && token.kind != tokenQuotedIdentifier {
+		return "", parser.unexpected()
+	}
+	parser.position++
+	return token.text, nil
+}
+
+// parseName parses an optionally keyspace qualified name
+func (parser *parserStruct) parseName() (string, string, error) {
+	name, err := parser.parseIdentifier()
+	if err != nil {
+		return "", "", err
+	}
+	if !parser.acceptSymbol(".") {
+		return "", name, nil
+	}
+	table, err := parser.parseIdentifier()
+	if err != nil {
+		return "", "", err
+	}
+	return name, table, nil
+}
+
+// parseTableName parses a table name and remembers it for bind markers
+func (parser *parserStruct) parseTableName(parsed *statementStruct) error {
+	var err error
+	parsed.keyspace, parsed.name, err = parser.parseName()
+	if err != nil {
+		return err
+	}
+	parser.keyspace = parsed.keyspace
+	parser.table = parsed.name
+	return nil
+}
+
+func (parser *parserStruct) parseOne() (*statementStruct, error) {
+	token := parser.peek()
+	if token.kind != tokenIdentifier {
+		return nil, parser.unexpected()
+	}
+	parser.position++
+
+	switch token.text {
+	case "select":
+		return parser.parseSelect()
+	case "insert":
+		return parser.parseInsert()
+	case "update":
+		return parser.parseUpdate()
+	case "delete":
+		return parser.parseDelete()
+	case "begin":
+		return parser.parseBatch()
+	case "use":
+		name, err := parser.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		return &statementStruct{kind: statementUse, keyspace: name}, nil
+	case "create":
+		return parser.parseCreate()
+	case "drop":
+		return parser.parseDrop()
+	case "truncate":
+		if !parser.accept("table") {
+			parser.accept("columnfamily")
+		}
+		parsed := &statementStruct{kind: statementTruncate}
+		return parsed, parser.parseTableName(parsed)
+	}
+
+	parser.position--
+	return nil, parser.unexpected()
+}
+
+func (parser *parserStruct) parseSelect() (*statementStruct, error) {
+	parsed := &statementStruct{kind: statementSelect}
+	parser.accept("distinct")
+
+	if !parser.acceptSymbol("*") {
+		for {
+			column, err := parser.parseIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			selector := selectorStruct{column: column}
+			if parser.acceptSymbol("(") {
+				if column != "count" {
+					return nil, invalidError("Unknown function '" + column + "'")
+				}
+				if !parser.acceptSymbol("*") {
+					parser.next()
+				}
+				err = parser.expectSymbol(")")
+				if err != nil {
+					return nil, err
+				}
+				selector = selectorStruct{column: "count", count: true}
+			}
+			if parser.accept("as") {
+				selector.alias, err = parser.parseIdentifier()
+				if err != nil {
+					return nil, err
+				}
+			}
+			parsed.selectors = append(parsed.selectors, selector)
+			if !parser.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	err := parser.expect("from")
+	if err != nil {
+		return nil, err
+	}
+	err = parser.parseTableName(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.accept("where") {
+		parsed.relations, err = parser.parseRelations()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if parser.accept("group") {
+		err = parser.expect("by")
+		if err != nil {
+			return nil, err
+		}
+		for {
+			column, err := parser.parseIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			parsed.groupBy = append(parsed.groupBy, column)
+			if !parser.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	if parser.accept("order") {
+		err = parser.expect("by")
+		if err != nil {
+			return nil, err
+		}
+		for {
+			_, err = parser.parseIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			if !parser.accept("asc") {
+				parser.accept("desc")
+			}
+			if !parser.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	if parser.accept("limit") {
+		parsed.limit, err = parser.parseTerm(markerLimit, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if parser.accept("allow") {
+		err = parser.expect("filtering")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parsed, nil
+}
+
+func (parser *parserStruct) parseInsert() (*statementStruct, error) {
+	parsed := &statementStruct{kind: statementInsert}
+	err := parser.expect("into")
+	if err != nil {
+		return nil, err
+	}
+	err = parser.parseTableName(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = parser.expectSymbol("(")
+	if err != nil {
+		return nil, err
+	}
+	for {
+		column, err := parser.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		parsed.columns = append(parsed.columns, column)
+		if !parser.acceptSymbol(",") {
+			break
+		}
+	}
+	err = parser.expectSymbol(")")
+	if err != nil {
+		return nil, err
+	}
+
+	err = parser.expect("values")
+	if err != nil {
+		return nil, err
+	}
+	err = parser.expectSymbol("(")
+	if err != nil {
+		return nil, err
+	}
+	for {
+		column := ""
+		if len(parsed.values) < len(parsed.columns) {
+			column = parsed.columns[len(parsed.values)]
+		}
+		term, err := parser.parseTerm(markerValue, column)
+		if err != nil {
+			return nil, err
+		}
+		parsed.values = append(parsed.values, term)
+		if !parser.acceptSymbol(",") {
+			break
+		}
+	}
+	err = parser.expectSymbol(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.values) != len(parsed.columns) {
+		return nil, invalidError("Unmatched column names/values")
+	}
+
+	for {
+		switch {
+		case parser.accept("if"):
+			err = parser.expect("not", "exists")
+			parsed.ifNotExists = true
+		case parser.isKeyword("using"):
+			err = parser.parseUsing()
+		default:
+			return parsed, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseUsing parses USING TTL and TIMESTAMP, which are accepted but not applied
+func (parser *parserStruct) parseUsing() error {
+	if !parser.accept("using") {
+		return nil
+	}
+	for {
+		var err error
+		switch {
+		case parser.accept("ttl"):
+			_, err = parser.parseTerm(markerTTL, "")
+		case parser.accept("timestamp"):
+			_, err = parser.parseTerm(markerTimestamp, "")
+		default:
+			err = parser.unexpected()
+		}
+		if err != nil {
+			return err
+		}
+		if !parser.accept("and") {
+			return nil
+		}
+	}
+}
+
+func (parser *parserStruct) parseUpdate() (*statementStruct, error) {
+	parsed := &statementStruct{kind: statementUpdate}
+	err := parser.parseTableName(parsed)
+	if err != nil {
+		return nil, err
+	}
+	err = parser.parseUsing()
+	if err != nil {
+		return nil, err
+	}
+
+	err = parser.expect("set")
+	if err != nil {
+		return nil, err
+	}
+	for {
+		assignment, err := parser.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		parsed.assignments = append(parsed.assignments, assignment)
+		if !parser.acceptSymbol(",") {
+			break
+		}
+	}
+
+	err = parser.expect("where")
+	if err != nil {
+		return nil, err
+	}
+	parsed.relations, err = parser.parseRelations()
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed, parser.parseIf(parsed)
+}
+
+// parseAssignment parses column = term, column = column + term, column = term + column, and column[key] = term
+func (parser *parserStruct) parseAssignment() (*assignmentStruct, error) {
+	column, err := parser.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	assignment := &assignmentStruct{column: column, operator: "="}
+
+	if parser.acceptSymbol("[") {
+		assignment.key, err = parser.parseTerm(markerMapKey, column)
+		if err != nil {
+			return nil, err
+		}
+		err = parser.expectSymbol("]")
+		if err != nil {
+			return nil, err
+		}
+		err = parser.expectSymbol("=")
+		if err != nil {
+			return nil, err
+		}
+		assignment.term, err = parser.parseTerm(markerMapValue, column)
+		return assignment, err
+	}
+
+	err = parser.expectSymbol("=")
+	if err != nil {
+		return nil, err
+	}
+
+	token := parser.peek()
+	operator := parser.peekAt(1)
+	if (token.kind == tokenIdentifier || token.kind == tokenQuotedIdentifier) && token.text == column &&
+		operator.kind == tokenSymbol && (operator.text == "+" || operator.text == "-") {
+		parser.position += 2
+		assignment.operator = operator.text
+		assignment.term, err = parser.parseTerm(markerValue, column)
+		return assignment, err
+	}
+
+	assignment.term, err = parser.parseTerm(markerValue, column)
+	if err != nil {
+		return nil, err
+	}
+	if parser.acceptSymbol("+") {
+		name, err := parser.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		if name != column {
+			return nil, invalidError("Only expressions of the form X = <value> + X are supported.")
+		}
+		assignment.operator = "prepend"
+	}
+
+	return assignment, nil
+}
+
+func (parser *parserStruct) parseDelete() (*statementStruct, error) {
+	parsed := &statementStruct{kind: statementDelete}
+
+	for !parser.isKeyword("from") {
+		column, err := parser.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		parsed.columns = append(parsed.columns, column)
+		if !parser.acceptSymbol(",") {
+			break
+		}
+	}
+
+	err := parser.expect("from")
+	if err != nil {
+		return nil, err
+	}
+	err = parser.parseTableName(parsed)
+	if err != nil {
+		return nil, err
+	}
+	err = parser.parseUsing()
+	if err != nil {
+		return nil, err
+	}
+
+	err = parser.expect("where")
+	if err != nil {
+		return nil, err
+	}
+	parsed.relations, err = parser.parseRelations()
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed, parser.parseIf(parsed)
+}
+
+// parseIf parses IF EXISTS or IF conditions of an update or delete
+func (parser *parserStruct) parseIf(parsed *statementStruct) error {
+	if !parser.accept("if") {
+		return nil
+	}
+	if parser.accept("exists") {
+		parsed.ifExists = true
+		return nil
+	}
+
+	var err error
+	parsed.conditions, err = parser.parseRelations()
+	return err
+}
+
+// parseRelations parses relations joined by AND
+func (parser *parserStruct) parseRelations() ([]*relationStruct, error) {
+	var relations []*relationStruct
+	for {
+		column, err := parser.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		relation := &relationStruct{column: column}
+
+		token := parser.next()
+		switch {
+		case token.kind == tokenIdentifier && token.text == "in":
+			relation.operator = "in"
+			if parser.acceptSymbol("(") {
+				for !parser.acceptSymbol(")") {
+					term, err := parser.parseTerm(markerValue, column)
+					if err != nil {
+						return nil, err
+					}
+					relation.terms = append(relation.terms, term)
+					if !parser.acceptSymbol(",") {
+						err = parser.expectSymbol(")")
+						if err != nil {
+							return nil, err
+						}
+						break
+					}
+				}
+			} else {
+				relation.list, err = parser.parseTerm(markerInList, column)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+		case token.kind == tokenSymbol && strings.Contains(" = != < > <= >= ", " "+token.text+" "):
+			relation.operator = token.text
+			term, err := parser.parseTerm(markerValue, column)
+			if err != nil {
+				return nil, err
+			}
+			relation.terms = []*termStruct{term}
+
+		default:
+			parser.position--
+			return nil, parser.unexpected()
+		}
+
+		relations = append(relations, relation)
+		if !parser.accept("and") {
+			return relations, nil
+		}
+	}
+}
+
+func (parser *parserStruct) parseBatch() (*statementStruct, error) {
+	parsed := &statementStruct{kind: statementBatch}
+	if !parser.accept("unlogged") && !parser.accept("counter") {
+		parser.accept("logged")
+	}
+	err := parser.expect("batch")
+	if err != nil {
+		return nil, err
+	}
+	err = parser.parseUsing()
+	if err != nil {
+		return nil, err
+	}
+
+	for !parser.accept("apply") {
+		token := parser.peek()
+		if token.kind != tokenIdentifier || (token.text != "insert" && token.text != "update" && token.text != "delete") {
+			return nil, parser.unexpected()
+		}
+		statement, err := parser.parseOne()
+		if err != nil {
+			return nil, err
+		}
+		parsed.statements = append(parsed.statements, statement)
+		parser.acceptSymbol(";")
+	}
+
+	return parsed, parser.expect("batch")
+}
+
+func (parser *parserStruct) parseCreate() (*statementStruct, error) {
+	parsed := &statementStruct{durableWrites: true}
+	switch {
+	case parser.accept("keyspace"):
+		parsed.kind = statementCreateKeyspace
+	case parser.accept("table"), parser.accept("columnfamily"):
+		parsed.kind = statementCreateTable
+	case parser.accept("type"):
+		parsed.kind = statementCreateType
+	default:
+		return nil, parser.unexpected()
+	}
+
+	if parser.accept("if") {
+		err := parser.expect("not", "exists")
+		if err != nil {
+			return nil, err
+		}
+		parsed.ifNotExists = true
+	}
+
+	if parsed.kind == statementCreateKeyspace {
+		name, err := parser.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		parsed.keyspace = name
+		return parsed, parser.parseKeyspaceOptions(parsed)
+	}
+
+	err := parser.parseTableName(parsed)
+	if err != nil {
+		return nil, err
+	}
+	err = parser.expectSymbol("(")
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if parsed.kind == statementCreateTable && parser.accept("primary") {
+			err = parser.parsePrimaryKey(parsed)
+		} else {
+			err = parser.parseDefinition(parsed)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !parser.acceptSymbol(",") {
+			break
+		}
+		if parser.isSymbol(")") {
+			break
+		}
+	}
+	err = parser.expectSymbol(")")
+	if err != nil {
+		return nil, err
+	}
+
+	// table options are accepted and ignored
+	for parser.peek().kind != tokenEOF && !parser.isSymbol(";") {
+		parser.next()
+	}
+
+	return parsed, nil
+}
+
+// parseKeyspaceOptions parses WITH replication = {...} AND durable_writes = bool
+func (parser *parserStruct) parseKeyspaceOptions(parsed *statementStruct) error {
+	err := parser.expect("with")
+	if err != nil {
+		return err
+	}
+	for {
+		option, err := parser.parseIdentifier()
+		if err != nil {
+			return err
+		}
+		err = parser.expectSymbol("=")
+		if err != nil {
+			return err
+		}
+		value, err := parser.parseLiteral()
+		if err != nil {
+			return err
+		}
+		switch option {
+		case "replication":
+			parsed.replication = value
+		case "durable_writes":
+			durableWrites, ok := value.(bool)
+			if !ok {
+				return syntaxError("Invalid value for property 'durable_writes'")
+			}
+			parsed.durableWrites = durableWrites
+		default:
+			return syntaxError("Unknown property '" + option + "'")
+		}
+		if !parser.accept("and") {
+			return nil
+		}
+	}
+}
+
+// parsePrimaryKey parses PRIMARY KEY ((a, b), c) after PRIMARY
+func (parser *parserStruct) parsePrimaryKey(parsed *statementStruct) error {
+	err := parser.expect("key")
+	if err != nil {
+		return err
+	}
+	err = parser.expectSymbol("(")
+	if err != nil {
+		return err
+	}
+
+	if parser.acceptSymbol("(") {
+		for {
+			column, err := parser.parseIdentifier()
+			if err != nil {
+				return err
+			}
+			parsed.partitionKey = append(parsed.partitionKey, column)
+			if !parser.acceptSymbol(",") {
+				break
+			}
+		}
+		err = parser.expectSymbol(")")
+		if err != nil {
+			return err
+		}
+	} else {
+		column, err := parser.parseIdentifier()
+		if err != nil {
+			return err
+		}
+		parsed.partitionKey = []string{column}
+	}
+
+	for parser.acceptSymbol(",") {
+		column, err := parser.parseIdentifier()
+		if err != nil {
+			return err
+		}
+		parsed.clusteringKey = append(parsed.clusteringKey, column)
+	}
+
+	return parser.expectSymbol(")")
+}
+
+// parseDefinition parses a column or field definition
+func (parser *parserStruct) parseDefinition(parsed *statementStruct) error {
+	name, err := parser.parseIdentifier()
+	if err != nil {
+		return err
+	}
+	typeText, err := parser.parseTypeText()
+	if err != nil {
+		return err
+	}
+	definition := &definitionStruct{name: name, typeText: typeText}
+	parser.accept("static")
+	if parsed.kind == statementCreateTable && parser.accept("primary") {
+		err = parser.expect("key")
+		if err != nil {
+			return err
+		}
+		definition.primaryKey = true
+	}
+	parsed.definitions = append(parsed.definitions, definition)
+	return nil
+}
+
+// parseTypeText parses a type and returns its normalized text
+func (parser *parserStruct) parseTypeText() (string, error) {
+	token := parser.peek()
+	var name string
+	switch token.kind {
+	case tokenIdentifier:
+		name = token.text
+	case tokenQuotedIdentifier:
+		name = `"` + token.text + `"`
+	case tokenString:
+		name = "'" + token.text + "'"
+	default:
+		return "", parser.unexpected()
+	}
+	parser.position++
+
+	if parser.acceptSymbol(".") {
+		table, err := parser.parseIdentifier()
+		if err != nil {
+			return "", err
+		}
+		name += "." + table
+	}
+
+	if !parser.acceptSymbol("<") {
+		return name, nil
+	}
+	var elems []string
+	for {
+		elem, err := parser.parseTypeText()
+		if err != nil {
+			return "", err
+		}
+		elems = append(elems, elem)
+		if !parser.acceptSymbol(",") {
+			break
+		}
+	}
+	err := parser.expectSymbol(">")
+	if err != nil {
+		return "", err
+	}
+
+	return name + "<" + strings.Join(elems, ", ") + ">", nil
+}
+
+func (parser *parserStruct) parseDrop() (*statementStruct, error) {
+	parsed := &statementStruct{}
+	switch {
+	case parser.accept("keyspace"):
+		parsed.kind = statementDropKeyspace
+	case parser.accept("table"), parser.accept("columnfamily"):
+		parsed.kind = statementDropTable
+	case parser.accept("type"):
+		parsed.kind = statementDropType
+	default:
+		return nil, parser.unexpected()
+	}
+
+	if parser.accept("if") {
+		err := parser.expect("exists")
+		if err != nil {
+			return nil, err
+		}
+		parsed.ifExists = true
+	}
+
+	if parsed.kind == statementDropKeyspace {
+		name, err := parser.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		parsed.keyspace = name
+		return parsed, nil
+	}
+
+	return parsed, parser.parseTableName(parsed)
+}
+
+// parseTerm parses a bind marker, function call, or literal
+func (parser *parserStruct) parseTerm(kind int, column string) (*termStruct, error) {
+	token := parser.peek()
+	switch token.kind {
+	case tokenMarker, tokenNamedMarker:
+		parser.position++
+		name := token.text
+		if token.kind == tokenMarker {
+			name = markerName(kind, column)
+		}
+		parser.markers = append(parser.markers, &markerStruct{
+			name:     name,
+			kind:     kind,
+			column:   column,
+			keyspace: parser.keyspace,
+			table:    parser.table,
+		})
+		return &termStruct{marker: len(parser.markers) - 1}, nil
+
+	case tokenIdentifier:
+		if parser.peekAt(1).kind == tokenSymbol && parser.peekAt(1).text == "(" {
+			parser.position += 2
+			function := token.text
+			for depth := 1; depth > 0; {
+				switch {
+				case parser.peek().kind == tokenEOF:
+					return nil, parser.unexpected()
+				case parser.acceptSymbol("("):
+					depth++
+				case parser.acceptSymbol(")"):
+					depth--
+				default:
+					parser.next()
+				}
+			}
+			switch function {
+			case "now", "uuid", "currenttimeuuid", "currenttimestamp", "currentdate", "totimestamp":
+			default:
+				return nil, invalidError("Unknown function '" + function + "'")
+			}
+			return &termStruct{marker: -1, function: function}, nil
+		}
+	}
+
+	value, err := parser.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	return &termStruct{marker: -1, value: value}, nil
+}
+
+// markerName returns the name Cassandra gives an anonymous bind marker
+func markerName(kind int, column string) string {
+	switch kind {
+	case markerInList:
+		return "in(" + column + ")"
+	case markerMapKey:
+		return "key(" + column + ")"
+	case markerMapValue:
+		return "value(" + column + ")"
+	case markerLimit:
+		return "[limit]"
+	case markerTTL:
+		return "[ttl]"
+	case markerTimestamp:
+		return "[timestamp]"
+	}
+	return column
+}
+
+// parseLiteral parses a constant or collection literal
+func (parser *parserStruct) parseLiteral() (interface{}, error) {
+	token := parser.next()
+	switch token.kind {
+	case tokenString:
+		return token.text, nil
+	case tokenNumber:
+		return numberLiteral(token.text), nil
+	case tokenUUID:
+		var uuid uuidLiteral
+		_, err := hex.Decode(uuid[:], []byte(strings.Replace(token.text, "-", "", -1)))
+		if err != nil {
+			return nil, syntaxError("invalid uuid " + token.text)
+		}
+		return uuid, nil
+	case tokenBlob:
+		blob, err := hex.DecodeString(token.text)
+		if err != nil {
+			return nil, syntaxError("invalid blob 0x" + token.text)
+		}
+		return blob, nil
+	case tokenIdentifier:
+		switch token.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		case "nan":
+			return numberLiteral("NaN"), nil
+		case "infinity":
+			return numberLiteral("Infinity"), nil
+		}
+	case tokenSymbol:
+		switch token.text {
+		case "-":
+			next := parser.next()
+			if next.kind == tokenNumber {
+				return numberLiteral("-" + next.text), nil
+			}
+			if next.kind == tokenIdentifier && next.text == "infinity" {
+				return numberLiteral("-Infinity"), nil
+			}
+		case "[":
+			list := listLiteral{}
+			for !parser.acceptSymbol("]") {
+				value, err := parser.parseLiteral()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+				if !parser.acceptSymbol(",") {
+					err = parser.expectSymbol("]")
+					if err != nil {
+						return nil, err
+					}
+					break
+				}
+			}
+			return list, nil
+		case "(":
+			tuple := tupleLiteral{}
+			for !parser.acceptSymbol(")") {
+				value, err := parser.parseLiteral()
+				if err != nil {
+					return nil, err
+				}
+				tuple = append(tuple, value)
+				if !parser.acceptSymbol(",") {
+					err = parser.expectSymbol(")")
+					if err != nil {
+						return nil, err
+					}
+					break
+				}
+			}
+			return tuple, nil
+		case "{":
+			return parser.parseBraceLiteral()
+		}
+	}
+
+	parser.position--
+	return nil, parser.unexpected()
+}
+
+// parseBraceLiteral parses a map, set, or user defined type literal after {
+func (parser *parserStruct) parseBraceLiteral() (interface{}, error) {
+	if parser.acceptSymbol("}") {
+		return mapLiteral{}, nil
+	}
+
+	var keys []interface{}
+	var values []interface{}
+	isMap := false
+	for {
+		var key interface{}
+		var err error
+		token := parser.peek()
+		next := parser.peekAt(1)
+		if (token.kind == tokenIdentifier || token.kind == tokenQuotedIdentifier) &&
+			next.kind == tokenSymbol && next.text == ":" {
+			parser.position++
+			key = identifierLiteral(token.text)
+		} else {
+			key, err = parser.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+		}
+		keys = append(keys, key)
+
+		if len(keys) == 1 {
+			isMap = parser.isSymbol(":")
+		}
+		if isMap {
+			err = parser.expectSymbol(":")
+			if err != nil {
+				return nil, err
+			}
+			value, err := parser.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+
+		if !parser.acceptSymbol(",") {
+			break
+		}
+	}
+	err := parser.expectSymbol("}")
+	if err != nil {
+		return nil, err
+	}
+
+	if isMap {
+		return mapLiteral{keys: keys, values: values}, nil
+	}
+	return setLiteral(keys), nil
+}
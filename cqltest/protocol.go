@@ -0,0 +1,344 @@
+package cqltest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// readFrame reads a native protocol frame
+func readFrame(reader io.Reader) (*frameStruct, error) {
+	header := make([]byte, 9)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[5:])
+	if length > maxFrameLength {
+		return nil, fmt.Errorf("frame length %v is too large", length)
+	}
+
+	frame := &frameStruct{
+		version: header[0],
+		flags:   header[1],
+		stream:  int16(binary.BigEndian.Uint16(header[2:])),
+		opcode:  header[4],
+		body:    make([]byte, length),
+	}
+	_, err = io.ReadFull(reader, frame.body)
+	if err != nil {
+		return nil, err
+	}
+
+	return frame, nil
+}
+
+// bytes returns the frame encoded with its header
+func (frame *frameStruct) bytes() []byte {
+	data := make([]byte, 9, 9+len(frame.body))
+	data[0] = frame.version
+	data[1] = frame.flags
+	binary.BigEndian.PutUint16(data[2:], uint16(frame.stream))
+	data[4] = frame.opcode
+	binary.BigEndian.PutUint32(data[5:], uint32(len(frame.body)))
+	return append(data, frame.body...)
+}
+
+// fail records the first read error
+func (reader *readerStruct) fail(length int) bool {
+	if reader.err != nil {
+		return true
+	}
+	if len(reader.data) < length {
+		reader.err = fmt.Errorf("not enough bytes in frame body")
+		reader.data = nil
+		return true
+	}
+	return false
+}
+
+func (reader *readerStruct) readByte() byte {
+	if reader.fail(1) {
+		return 0
+	}
+	value := reader.data[0]
+	reader.data = reader.data[1:]
+	return value
+}
+
+func (reader *readerStruct) readShort() uint16 {
+	if reader.fail(2) {
+		return 0
+	}
+	value := binary.BigEndian.Uint16(reader.data)
+	reader.data = reader.data[2:]
+	return value
+}
+
+func (reader *readerStruct) readInt() int32 {
+	if reader.fail(4) {
+		return 0
+	}
+	value := int32(binary.BigEndian.Uint32(reader.data))
+	reader.data = reader.data[4:]
+	return value
+}
+
+func (reader *readerStruct) readLong() int64 {
+	if reader.fail(8) {
+		return 0
+	}
+	value := int64(binary.BigEndian.Uint64(reader.data))
+	reader.data = reader.data[8:]
+	return value
+}
+
+func (reader *readerStruct) readRaw(length int) []byte {
+	if length < 0 {
+		reader.err = fmt.Errorf("negative length in frame body")
+		return nil
+	}
+	if reader.fail(length) {
+		return nil
+	}
+	value := reader.data[:length:length]
+	reader.data = reader.data[length:]
+	return value
+}
+
+func (reader *readerStruct) readString() string {
+	return string(reader.readRaw(int(reader.readShort())))
+}
+
+func (reader *readerStruct) readLongString() string {
+	return string(reader.readRaw(int(reader.readInt())))
+}
+
+func (reader *readerStruct) readShortBytes() []byte {
+	return reader.readRaw(int(reader.readShort()))
+}
+
+// readValue reads a [value], a length of -1 is null and -2 is unset
+func (reader *readerStruct) readValue() boundValueStruct {
+	length := reader.readInt()
+	switch {
+	case length == -1:
+		return boundValueStruct{}
+	case length == -2:
+		return boundValueStruct{unset: true}
+	case length < 0:
+		reader.err = fmt.Errorf("invalid value length %v", length)
+		return boundValueStruct{}
+	}
+	data := reader.readRaw(int(length))
+	if data == nil {
+		data = []byte{}
+	}
+	return boundValueStruct{data: data}
+}
+
+func (reader *readerStruct) readStringMap() map[string]string {
+	count := int(reader.readShort())
+	stringMap := make(map[string]string, count)
+	for i := 0; i < count && reader.err == nil; i++ {
+		key := reader.readString()
+		stringMap[key] = reader.readString()
+	}
+	return stringMap
+}
+
+func (reader *readerStruct) readStringList() []string {
+	count := int(reader.readShort())
+	list := make([]string, 0, count)
+	for i := 0; i < count && reader.err == nil; i++ {
+		list = append(list, reader.readString())
+	}
+	return list
+}
+
+// readQueryParameters reads the query parameters of a QUERY or EXECUTE message
+func (reader *readerStruct) readQueryParameters() *queryParametersStruct {
+	parameters := &queryParametersStruct{
+		consistency: reader.readShort(),
+	}
+	flags := reader.readByte()
+
+	if flags&queryFlagValues != 0 {
+		count := int(reader.readShort())
+		parameters.values = make([]boundValueStruct, 0, count)
+		for i := 0; i < count && reader.err == nil; i++ {
+			if flags&queryFlagNames != 0 {
+				parameters.names = append(parameters.names, reader.readString())
+			}
+			parameters.values = append(parameters.values, reader.readValue())
+		}
+	}
+	parameters.skipMeta = flags&queryFlagSkipMeta != 0
+	if flags&queryFlagPageSize != 0 {
+		parameters.pageSize = int(reader.readInt())
+	}
+	if flags&queryFlagPagingState != 0 {
+		value := reader.readValue()
+		parameters.pagingState = value.data
+	}
+	if flags&queryFlagSerial != 0 {
+		reader.readShort()
+	}
+	if flags&queryFlagTimestamp != 0 {
+		parameters.timestamp = reader.readLong()
+	}
+
+	return parameters
+}
+
+func (writer *writerStruct) writeByte(value byte) {
+	writer.data = append(writer.data, value)
+}
+
+func (writer *writerStruct) writeShort(value uint16) {
+	writer.data = append(writer.data, byte(value>>8), byte(value))
+}
+
+func (writer *writerStruct) writeInt(value int32) {
+	writer.data = append(writer.data, byte(value>>24), byte(value>>16), byte(value>>8), byte(value))
+}
+
+func (writer *writerStruct) writeString(value string) {
+	writer.writeShort(uint16(len(value)))
+	writer.data = append(writer.data, value...)
+}
+
+func (writer *writerStruct) writeShortBytes(value []byte) {
+	writer.writeShort(uint16(len(value)))
+	writer.data = append(writer.data, value...)
+}
+
+// writeBytes writes [bytes], nil is written as null
+func (writer *writerStruct) writeBytes(value []byte) {
+	if value == nil {
+		writer.writeInt(-1)
+		return
+	}
+	writer.writeInt(int32(len(value)))
+	writer.data = append(writer.data, value...)
+}
+
+func (writer *writerStruct) writeStringList(list []string) {
+	writer.writeShort(uint16(len(list)))
+	for _, value := range list {
+		writer.writeString(value)
+	}
+}
+
+func (writer *writerStruct) writeStringMultimap(multimap map[string][]string) {
+	keys := make([]string, 0, len(multimap))
+	for key := range multimap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writer.writeShort(uint16(len(keys)))
+	for _, key := range keys {
+		writer.writeString(key)
+		writer.writeStringList(multimap[key])
+	}
+}
+
+// writeType writes a type [option]
+func (writer *writerStruct) writeType(typeInfo *typeStruct) {
+	writer.writeShort(typeInfo.id)
+	switch typeInfo.id {
+	case typeCustom:
+		writer.writeString(typeInfo.name)
+	case typeList, typeSet:
+		writer.writeType(typeInfo.elems[0])
+	case typeMap:
+		writer.writeType(typeInfo.elems[0])
+		writer.writeType(typeInfo.elems[1])
+	case typeUDT:
+		writer.writeString(typeInfo.keyspace)
+		writer.writeString(typeInfo.name)
+		writer.writeShort(uint16(len(typeInfo.fields)))
+		for i := 0; i < len(typeInfo.fields); i++ {
+			writer.writeString(typeInfo.fields[i])
+			writer.writeType(typeInfo.elems[i])
+		}
+	case typeTuple:
+		writer.writeShort(uint16(len(typeInfo.elems)))
+		for _, elem := range typeInfo.elems {
+			writer.writeType(elem)
+		}
+	}
+}
+
+// writeMetadata writes result or prepared metadata
+func (writer *writerStruct) writeMetadata(columns []*resultColumnStruct, pagingState []byte, noMetadata bool) {
+	var flags int32
+	if pagingState != nil {
+		flags |= metaHasMorePages
+	}
+	if noMetadata {
+		flags |= metaNoMetadata
+	}
+	global := len(columns) > 0 && !noMetadata
+	for _, column := range columns {
+		if column.keyspace != columns[0].keyspace || column.table != columns[0].table {
+			global = false
+			break
+		}
+	}
+	if global {
+		flags |= metaGlobalTableSpec
+	}
+
+	writer.writeInt(flags)
+	writer.writeInt(int32(len(columns)))
+	if pagingState != nil {
+		writer.writeBytes(pagingState)
+	}
+	if noMetadata {
+		return
+	}
+	if global {
+		writer.writeString(columns[0].keyspace)
+		writer.writeString(columns[0].table)
+	}
+	for _, column := range columns {
+		if !global {
+			writer.writeString(column.keyspace)
+			writer.writeString(column.table)
+		}
+		writer.writeString(column.name)
+		writer.writeType(column.typeInfo)
+	}
+}
+
+// writeError writes an ERROR body, including the extra fields gocql expects for the error code
+func (writer *writerStruct) writeError(err *Error, statementID []byte) {
+	writer.writeInt(int32(err.Code))
+	writer.writeString(err.Message)
+
+	switch err.Code {
+	case ErrorCodeUnavailable:
+		writer.writeShort(1)
+		writer.writeInt(1)
+		writer.writeInt(0)
+	case ErrorCodeWriteTimeout:
+		writer.writeShort(1)
+		writer.writeInt(0)
+		writer.writeInt(1)
+		writer.writeString("SIMPLE")
+	case ErrorCodeReadTimeout:
+		writer.writeShort(1)
+		writer.writeInt(0)
+		writer.writeInt(1)
+		writer.writeByte(0)
+	case ErrorCodeAlreadyExists:
+		writer.writeString(err.keyspace)
+		writer.writeString(err.table)
+	case ErrorCodeUnprepared:
+		writer.writeShortBytes(statementID)
+	}
+}
@@ -0,0 +1,164 @@
+package cqltest
+
+import (
+	"fmt"
+)
+
+// isSystemKeyspace returns true for the keyspaces Cassandra manages itself
+func isSystemKeyspace(name string) bool {
+	for _, system := range systemKeyspaces {
+		if name == system {
+			return true
+		}
+	}
+	return false
+}
+
+func (executor *executorStruct) executeKeyspace(parsed *statementStruct) (*resultStruct, error) {
+	store := executor.store
+	if isSystemKeyspace(parsed.keyspace) {
+		return nil, unauthorizedError(parsed.keyspace + " keyspace is not user-modifiable")
+	}
+	keyspace, exists := store.keyspaces[parsed.keyspace]
+
+	if parsed.kind == statementDropKeyspace {
+		if !exists {
+			if parsed.ifExists {
+				return &resultStruct{kind: resultVoid}, nil
+			}
+			return nil, newError(ErrorCodeConfig, "Cannot drop non existing keyspace '"+parsed.keyspace+"'.")
+		}
+		delete(store.keyspaces, parsed.keyspace)
+		keyspacesTable := store.keyspaces["system_schema"].tables["keyspaces"]
+		for key, row := range keyspacesTable.rows {
+			if string(row.values[0]) == parsed.keyspace {
+				delete(keyspacesTable.rows, key)
+			}
+		}
+		store.changeSchema()
+		return schemaChange("DROPPED", "KEYSPACE", keyspace.name, ""), nil
+	}
+
+	if exists {
+		if parsed.ifNotExists {
+			return &resultStruct{kind: resultVoid}, nil
+		}
+		err := newError(ErrorCodeAlreadyExists, "Cannot add existing keyspace \""+parsed.keyspace+"\"")
+		err.keyspace = parsed.keyspace
+		return nil, err
+	}
+
+	keys, values, ok := toMap(parsed.replication)
+	if !ok {
+		return nil, newError(ErrorCodeConfig, "Missing mandatory replication strategy class")
+	}
+	replication := make(map[string]string, len(keys))
+	for i := range keys {
+		replication[fmt.Sprint(keys[i])] = fmt.Sprint(values[i])
+	}
+	if replication["class"] == "" {
+		return nil, newError(ErrorCodeConfig, "Missing mandatory replication strategy class")
+	}
+
+	keyspace = &keyspaceStruct{
+		name:          parsed.keyspace,
+		replication:   replication,
+		durableWrites: parsed.durableWrites,
+		tables:        make(map[string]*tableStruct),
+		types:         make(map[string]*typeStruct),
+	}
+	store.keyspaces[keyspace.name] = keyspace
+	store.syncKeyspaceRow(keyspace)
+	store.changeSchema()
+	return schemaChange("CREATED", "KEYSPACE", keyspace.name, ""), nil
+}
+
+// executeSchema runs create and drop of tables and types
+func (executor *executorStruct) executeSchema(parsed *statementStruct) (*resultStruct, error) {
+	store := executor.store
+	keyspace, err := store.keyspace(parsed.keyspace, executor.keyspace)
+	if err != nil {
+		return nil, err
+	}
+	if keyspace.system {
+		return nil, unauthorizedError(keyspace.name + " keyspace is not user-modifiable.")
+	}
+
+	switch parsed.kind {
+	case statementCreateTable:
+		if _, ok := keyspace.tables[parsed.name]; ok {
+			if parsed.ifNotExists {
+				return &resultStruct{kind: resultVoid}, nil
+			}
+			err := newError(ErrorCodeAlreadyExists, "Object "+keyspace.name+"."+parsed.name+" already exists")
+			err.keyspace = keyspace.name
+			err.table = parsed.name
+			return nil, err
+		}
+		table, err := store.newTable(keyspace, parsed.name, parsed)
+		if err != nil {
+			return nil, err
+		}
+		keyspace.tables[table.name] = table
+		store.changeSchema()
+		return schemaChange("CREATED", "TABLE", keyspace.name, table.name), nil
+
+	case statementDropTable:
+		if _, ok := keyspace.tables[parsed.name]; !ok {
+			if parsed.ifExists {
+				return &resultStruct{kind: resultVoid}, nil
+			}
+			return nil, invalidError("unconfigured table " + parsed.name)
+		}
+		delete(keyspace.tables, parsed.name)
+		store.changeSchema()
+		return schemaChange("DROPPED", "TABLE", keyspace.name, parsed.name), nil
+
+	case statementCreateType:
+		if _, ok := keyspace.types[parsed.name]; ok {
+			if parsed.ifNotExists {
+				return &resultStruct{kind: resultVoid}, nil
+			}
+			err := newError(ErrorCodeAlreadyExists, "A user type of name "+keyspace.name+"."+parsed.name+" already exists")
+			err.keyspace = keyspace.name
+			err.table = parsed.name
+			return nil, err
+		}
+		udt := &typeStruct{id: typeUDT, keyspace: keyspace.name, name: parsed.name}
+		for _, definition := range parsed.definitions {
+			typeInfo, err := store.parseType(definition.typeText, keyspace.name)
+			if err != nil {
+				return nil, err
+			}
+			udt.fields = append(udt.fields, definition.name)
+			udt.elems = append(udt.elems, typeInfo)
+		}
+		keyspace.types[udt.name] = udt
+		store.changeSchema()
+		return schemaChange("CREATED", "TYPE", keyspace.name, udt.name), nil
+
+	case statementDropType:
+		if _, ok := keyspace.types[parsed.name]; !ok {
+			if parsed.ifExists {
+				return &resultStruct{kind: resultVoid}, nil
+			}
+			return nil, invalidError("No user type named " + keyspace.name + "." + parsed.name + " exists.")
+		}
+		delete(keyspace.types, parsed.name)
+		store.changeSchema()
+		return schemaChange("DROPPED", "TYPE", keyspace.name, parsed.name), nil
+	}
+
+	return nil, invalidError("statement is not supported")
+}
+
+// schemaChange returns a schema change result
+func schemaChange(changeType string, target string, keyspace string, name string) *resultStruct {
+	return &resultStruct{
+		kind:         resultSchemaChange,
+		changeType:   changeType,
+		changeTarget: target,
+		keyspace:     keyspace,
+		changeName:   name,
+	}
+}
@@ -0,0 +1,777 @@
+// Package cqltest is an in-process fake Cassandra server for tests.
+//
+// The Server speaks the CQL native protocol (v3 and v4) on a local TCP port and answers
+// STARTUP, OPTIONS, AUTH_RESPONSE, REGISTER, QUERY, PREPARE, EXECUTE, and BATCH messages
+// from an in-memory table store. It supports keyspaces, tables, user defined types,
+// select, insert, update, delete, lightweight transactions, batches, paging, and tracing.
+// Statements can also be scripted to return fixed responses, and errors can be injected.
+//
+// Example:
+//
+//	server, err := cqltest.NewServer()
+//	if err != nil {
+//		return err
+//	}
+//	defer server.Close()
+//	db, err := sql.Open("cql", server.Addr())
+package cqltest
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MichaelS11/go-cql-driver/internal/lz4"
+	"github.com/golang/snappy"
+)
+
+// NewServer starts a Server listening on a random port of 127.0.0.1
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return newServer(listener), nil
+}
+
+// NewTLSServer starts a Server listening with tls on a random port of 127.0.0.1
+func NewTLSServer(tlsConfig *tls.Config) (*Server, error) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newServer(listener), nil
+}
+
+func newServer(listener net.Listener) *Server {
+	server := &Server{
+		listener:    listener,
+		connections: make(map[*serverConnStruct]struct{}),
+		store:       newStore("127.0.0.1"),
+		prepared:    make(map[string]*preparedStruct),
+	}
+
+	server.waitGroup.Add(1)
+	go server.accept()
+
+	return server
+}
+
+// Addr returns the host:port the Server is listening on
+func (server *Server) Addr() string {
+	return server.listener.Addr().String()
+}
+
+// Host returns the host the Server is listening on
+func (server *Server) Host() string {
+	return server.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the Server is listening on
+func (server *Server) Port() int {
+	return server.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Close stops the Server and closes all connections
+func (server *Server) Close() error {
+	server.mutex.Lock()
+	if server.closed {
+		server.mutex.Unlock()
+		return ErrServerClosed
+	}
+	server.closed = true
+	err := server.listener.Close()
+	for conn := range server.connections {
+		conn.conn.Close()
+	}
+	server.mutex.Unlock()
+
+	server.waitGroup.Wait()
+	return err
+}
+
+// SetCredentials makes the Server require password authentication, an empty username disables it
+func (server *Server) SetCredentials(username string, password string) {
+	server.mutex.Lock()
+	server.username = username
+	server.password = password
+	server.mutex.Unlock()
+}
+
+// SetAuthenticator sets the authenticator class name sent to clients, empty is org.apache.cassandra.auth.PasswordAuthenticator
+func (server *Server) SetAuthenticator(className string) {
+	server.mutex.Lock()
+	server.authClass = className
+	server.mutex.Unlock()
+}
+
+// Exec runs a statement directly against the store, useful for setting up test data.
+// Tables must be qualified with their keyspace.
+func (server *Server) Exec(statement string) error {
+	prepared, err := server.store.prepare(statement, "")
+	if err != nil {
+		return err
+	}
+	if len(prepared.markers) > 0 {
+		return invalidError("bind markers are not supported by Exec")
+	}
+	result, err := server.store.execute(prepared, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	server.schemaEvent(result)
+	return nil
+}
+
+// Script makes statements equal to statement, ignoring case and extra white space, return response instead of executing.
+// Scripted statements that can not be parsed are prepared with blob bind markers.
+func (server *Server) Script(statement string, response Response) {
+	server.mutex.Lock()
+	server.scripts = append(server.scripts, &scriptStruct{statement: normalizeStatement(statement), response: &response})
+	server.mutex.Unlock()
+}
+
+// InjectError makes the next count statements that contain substring, ignoring case, fail with err.
+// A count less than 0 fails all matching statements until Reset. An empty substring matches all statements.
+func (server *Server) InjectError(substring string, count int, err *Error) {
+	injected := *err
+	if !errorCodes[injected.Code] {
+		injected.Code = ErrorCodeServer
+	}
+	server.mutex.Lock()
+	server.injections = append(server.injections, &injectionStruct{substring: strings.ToLower(substring), count: count, err: &injected})
+	server.mutex.Unlock()
+}
+
+// Reset removes all scripts and injected errors and clears the received statements
+func (server *Server) Reset() {
+	server.mutex.Lock()
+	server.scripts = nil
+	server.injections = nil
+	server.statements = nil
+	server.mutex.Unlock()
+}
+
+// Statements returns the statements received by QUERY, EXECUTE, and BATCH messages
+func (server *Server) Statements() []string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return append([]string{}, server.statements...)
+}
+
+// normalizeStatement lower cases a statement and collapses white space
+func normalizeStatement(statement string) string {
+	return strings.TrimSuffix(strings.Join(strings.Fields(strings.ToLower(statement)), " "), ";")
+}
+
+func (server *Server) accept() {
+	defer server.waitGroup.Done()
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		serverConn := &serverConnStruct{
+			server: server,
+			conn:   conn,
+			events: make(map[string]bool),
+		}
+		server.mutex.Lock()
+		if server.closed {
+			server.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		server.connections[serverConn] = struct{}{}
+		server.waitGroup.Add(1)
+		server.mutex.Unlock()
+
+		go serverConn.serve()
+	}
+}
+
+// serve reads and answers frames until the connection is closed
+func (conn *serverConnStruct) serve() {
+	server := conn.server
+	defer func() {
+		conn.conn.Close()
+		server.mutex.Lock()
+		delete(server.connections, conn)
+		server.mutex.Unlock()
+		server.waitGroup.Done()
+	}()
+
+	for {
+		frame, err := readFrame(conn.conn)
+		if err != nil {
+			return
+		}
+
+		version := frame.version & 0x7f
+		if frame.version&0x80 != 0 || version < 3 || version > 4 {
+			if version < 1 || version > 5 {
+				version = 4
+			}
+			conn.writeError(version, frame.stream, newError(ErrorCodeProtocol,
+				fmt.Sprintf("Invalid or unsupported protocol version (%v); the lowest supported version is 3 and the greatest is 4", frame.version&0x7f)), nil)
+			continue
+		}
+		if conn.version == 0 {
+			conn.version = version
+		}
+
+		if frame.flags&flagCompression != 0 {
+			switch conn.compression {
+			case "snappy":
+				frame.body, err = snappy.Decode(nil, frame.body)
+			case "lz4":
+				if len(frame.body) < 4 {
+					err = lz4.ErrCorrupt
+					break
+				}
+				frame.body, err = lz4.Decode(frame.body[4:], int(binary.BigEndian.Uint32(frame.body)))
+			default:
+				conn.writeError(version, frame.stream, newError(ErrorCodeProtocol, "Compressed frame without compression"), nil)
+				continue
+			}
+			if err != nil {
+				conn.writeError(version, frame.stream, newError(ErrorCodeProtocol, "Invalid compressed frame"), nil)
+				continue
+			}
+		}
+
+		conn.handle(frame)
+	}
+}
+
+// write sends a response frame
+func (conn *serverConnStruct) write(version byte, flags byte, stream int16, opcode byte, body []byte) {
+	frame := &frameStruct{version: 0x80 | version, flags: flags, stream: stream, opcode: opcode, body: body}
+	conn.writeMutex.Lock()
+	conn.conn.Write(frame.bytes())
+	conn.writeMutex.Unlock()
+}
+
+// writeError sends an ERROR frame
+func (conn *serverConnStruct) writeError(version byte, stream int16, err *Error, statementID []byte) {
+	writer := &writerStruct{}
+	writer.writeError(err, statementID)
+	conn.write(version, 0, stream, opError, writer.data)
+}
+
+// handle answers one request frame
+func (conn *serverConnStruct) handle(frame *frameStruct) {
+	server := conn.server
+	reader := &readerStruct{data: frame.body}
+	version := frame.version & 0x7f
+
+	switch frame.opcode {
+	case opOptions:
+		writer := &writerStruct{}
+		writer.writeStringMultimap(map[string][]string{
+			"CQL_VERSION":       {CQLVersion},
+			"COMPRESSION":       {"snappy", "lz4"},
+			"PROTOCOL_VERSIONS": {"3/v3", "4/v4"},
+		})
+		conn.write(version, 0, frame.stream, opSupported, writer.data)
+		return
+
+	case opStartup:
+		options := reader.readStringMap()
+		if reader.err != nil {
+			conn.writeError(version, frame.stream, newError(ErrorCodeProtocol, reader.err.Error()), nil)
+			return
+		}
+		compression := strings.ToLower(options["COMPRESSION"])
+		if compression != "" && compression != "snappy" && compression != "lz4" {
+			conn.writeError(version, frame.stream, newError(ErrorCodeProtocol, "Unknown compression algorithm: "+options["COMPRESSION"]), nil)
+			return
+		}
+		conn.compression = compression
+
+		server.mutex.Lock()
+		username, authClass := server.username, server.authClass
+		server.mutex.Unlock()
+		if username != "" {
+			if authClass == "" {
+				authClass = "org.apache.cassandra.auth.PasswordAuthenticator"
+			}
+			writer := &writerStruct{}
+			writer.writeString(authClass)
+			conn.write(version, 0, frame.stream, opAuthenticate, writer.data)
+			return
+		}
+		conn.ready = true
+		conn.write(version, 0, frame.stream, opReady, nil)
+		return
+
+	case opAuthResponse:
+		token := reader.readValue().data
+		parts := strings.Split(string(token), "\x00")
+		server.mutex.Lock()
+		username, password := server.username, server.password
+		server.mutex.Unlock()
+		if len(parts) != 3 || parts[1] != username || parts[2] != password {
+			name := ""
+			if len(parts) == 3 {
+				name = parts[1]
+			}
+			conn.writeError(version, frame.stream, newError(ErrorCodeBadCredentials, "Provided username "+name+" and/or password are incorrect"), nil)
+			return
+		}
+		conn.ready = true
+		writer := &writerStruct{}
+		writer.writeBytes(nil)
+		conn.write(version, 0, frame.stream, opAuthSuccess, writer.data)
+		return
+	}
+
+	if !conn.ready {
+		conn.writeError(version, frame.stream, newError(ErrorCodeProtocol, "Unexpected message, expecting STARTUP or AUTH_RESPONSE"), nil)
+		return
+	}
+
+	started := time.Now()
+	var result *resultStruct
+	var prepared *preparedStruct
+	var parameters *queryParametersStruct
+	var err *Error
+	var statementID []byte
+	command := "QUERY"
+	statement := ""
+
+	switch frame.opcode {
+	case opRegister:
+		for _, event := range reader.readStringList() {
+			conn.events[event] = true
+		}
+		conn.write(version, 0, frame.stream, opReady, nil)
+		return
+
+	case opQuery:
+		statement = reader.readLongString()
+		parameters = reader.readQueryParameters()
+		if reader.err != nil {
+			err = newError(ErrorCodeProtocol, reader.err.Error())
+			break
+		}
+		result, err = conn.query(statement, parameters)
+
+	case opPrepare:
+		command = "PREPARE"
+		statement = reader.readLongString()
+		if reader.err != nil {
+			err = newError(ErrorCodeProtocol, reader.err.Error())
+			break
+		}
+		prepared, err = conn.prepare(statement)
+
+	case opExecute:
+		command = "EXECUTE"
+		statementID = reader.readShortBytes()
+		parameters = reader.readQueryParameters()
+		if reader.err != nil {
+			err = newError(ErrorCodeProtocol, reader.err.Error())
+			break
+		}
+		server.mutex.Lock()
+		prepared = server.prepared[string(statementID)]
+		server.mutex.Unlock()
+		if prepared == nil {
+			err = newError(ErrorCodeUnprepared, fmt.Sprintf("Prepared query with ID %x not found", statementID))
+			break
+		}
+		statement = prepared.statement
+		result, err = conn.run(prepared, parameters, statementID)
+		prepared = nil
+
+	case opBatch:
+		command = "BATCH"
+		result, statement, statementID, err = conn.batch(reader)
+
+	default:
+		err = newError(ErrorCodeProtocol, fmt.Sprintf("Unknown opcode %v", frame.opcode))
+	}
+
+	var tracingID []byte
+	flags := byte(0)
+	if frame.flags&flagTracing != 0 {
+		tracingID = server.trace(conn, command, statement, started)
+		flags |= flagTracing
+	}
+
+	if err != nil {
+		writer := &writerStruct{data: tracingID}
+		writer.writeError(err, statementID)
+		conn.write(version, flags, frame.stream, opError, writer.data)
+		return
+	}
+
+	writer := &writerStruct{data: tracingID}
+	if prepared != nil {
+		conn.writePrepared(writer, prepared)
+	} else {
+		skipMeta := parameters != nil && parameters.skipMeta
+		writer.writeResult(result, skipMeta)
+	}
+	conn.write(version, flags, frame.stream, opResult, writer.data)
+
+	if result != nil {
+		server.schemaEvent(result)
+	}
+}
+
+// record saves a received statement and returns an injected error or scripted response for it
+func (server *Server) record(statement string) (*Error, *Response) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.statements = append(server.statements, statement)
+
+	lower := strings.ToLower(statement)
+	for i, injection := range server.injections {
+		if injection.count == 0 || !strings.Contains(lower, injection.substring) {
+			continue
+		}
+		if injection.count > 0 {
+			injection.count--
+		}
+		if injection.count == 0 {
+			server.injections = append(server.injections[:i:i], server.injections[i+1:]...)
+		}
+		err := *injection.err
+		return &err, nil
+	}
+
+	normalized := normalizeStatement(statement)
+	for _, script := range server.scripts {
+		if script.statement == normalized {
+			return nil, script.response
+		}
+	}
+	return nil, nil
+}
+
+// query answers a QUERY message
+func (conn *serverConnStruct) query(statement string, parameters *queryParametersStruct) (*resultStruct, *Error) {
+	prepared, err := conn.server.store.prepare(statement, conn.keyspace)
+	if err != nil {
+		injected, response := conn.server.record(statement)
+		if injected != nil {
+			return nil, injected
+		}
+		if response != nil {
+			return conn.scriptResult(response)
+		}
+		return nil, toError(err)
+	}
+	return conn.run(prepared, parameters, prepared.id)
+}
+
+// run executes a prepared statement unless an error is injected or the statement is scripted
+func (conn *serverConnStruct) run(prepared *preparedStruct, parameters *queryParametersStruct, statementID []byte) (*resultStruct, *Error) {
+	injected, response := conn.server.record(prepared.statement)
+	if injected != nil {
+		return nil, injected
+	}
+	if response != nil {
+		return conn.scriptResult(response)
+	}
+	if prepared.parsed == nil {
+		return nil, syntaxError("line 1:0 no viable alternative at input '" + prepared.statement + "'")
+	}
+
+	values, err := bindValues(prepared, parameters)
+	if err != nil {
+		return nil, toError(err)
+	}
+	result, err := conn.server.store.execute(prepared, values, parameters, conn)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return result, nil
+}
+
+// scriptResult returns the result of a scripted response
+func (conn *serverConnStruct) scriptResult(response *Response) (*resultStruct, *Error) {
+	if response.Delay > 0 {
+		time.Sleep(response.Delay)
+	}
+	if response.Err != nil {
+		err := *response.Err
+		if !errorCodes[err.Code] {
+			err.Code = ErrorCodeServer
+		}
+		return nil, &err
+	}
+	if response.Columns == nil {
+		return &resultStruct{kind: resultVoid}, nil
+	}
+
+	columns, err := conn.scriptColumns(response)
+	if err != nil {
+		return nil, toError(err)
+	}
+	result := &resultStruct{kind: resultRows, columns: columns}
+	for _, row := range response.Rows {
+		if len(row) != len(columns) {
+			return nil, newError(ErrorCodeServer, "scripted row has "+strconv.Itoa(len(row))+" values for "+strconv.Itoa(len(columns))+" columns")
+		}
+		values := make([][]byte, len(row))
+		for i, value := range row {
+			values[i], err = encodeValue(columns[i].typeInfo, value)
+			if err != nil {
+				return nil, toError(err)
+			}
+		}
+		result.rows = append(result.rows, values)
+	}
+	return result, nil
+}
+
+// scriptColumns returns the result columns of a scripted response
+func (conn *serverConnStruct) scriptColumns(response *Response) ([]*resultColumnStruct, error) {
+	store := conn.server.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	columns := make([]*resultColumnStruct, len(response.Columns))
+	for i, column := range response.Columns {
+		typeInfo, err := store.parseType(column.Type, conn.keyspace)
+		if err != nil {
+			return nil, err
+		}
+		columns[i] = &resultColumnStruct{keyspace: conn.keyspace, table: "script", name: column.Name, typeInfo: typeInfo}
+	}
+	return columns, nil
+}
+
+// prepare answers a PREPARE message
+func (conn *serverConnStruct) prepare(statement string) (*preparedStruct, *Error) {
+	server := conn.server
+	prepared, err := server.store.prepare(statement, conn.keyspace)
+	if err != nil {
+		server.mutex.Lock()
+		var response *Response
+		normalized := normalizeStatement(statement)
+		for _, script := range server.scripts {
+			if script.statement == normalized {
+				response = script.response
+			}
+		}
+		server.mutex.Unlock()
+		if response == nil {
+			return nil, toError(err)
+		}
+
+		// scripted statements that do not parse get blob bind markers
+		prepared.parsed = nil
+		prepared.markers = nil
+		tokens, _ := tokenize(statement)
+		for _, token := range tokens {
+			if token.kind == tokenMarker || token.kind == tokenNamedMarker {
+				prepared.markers = append(prepared.markers, &markerStruct{name: token.text, typeInfo: &typeStruct{id: typeBlob}})
+			}
+		}
+		if response.Columns != nil {
+			prepared.columns, err = conn.scriptColumns(response)
+			if err != nil {
+				return nil, toError(err)
+			}
+		}
+	}
+
+	server.mutex.Lock()
+	server.prepared[string(prepared.id)] = prepared
+	server.mutex.Unlock()
+	return prepared, nil
+}
+
+// writePrepared writes a prepared RESULT body
+func (conn *serverConnStruct) writePrepared(writer *writerStruct, prepared *preparedStruct) {
+	writer.writeInt(resultPrepared)
+	writer.writeShortBytes(prepared.id)
+
+	markers := make([]*resultColumnStruct, len(prepared.markers))
+	for i, marker := range prepared.markers {
+		keyspace := marker.keyspace
+		if keyspace == "" {
+			keyspace = prepared.keyspace
+		}
+		markers[i] = &resultColumnStruct{keyspace: keyspace, table: marker.table, name: marker.name, typeInfo: marker.typeInfo}
+	}
+
+	metadata := &writerStruct{}
+	metadata.writeMetadata(markers, nil, false)
+	if conn.version >= 4 {
+		// v4 adds the partition key bind marker indexes after the column count
+		writer.data = append(writer.data, metadata.data[:8]...)
+		writer.writeInt(int32(len(prepared.pkIndexes)))
+		for _, index := range prepared.pkIndexes {
+			writer.writeShort(uint16(index))
+		}
+		writer.data = append(writer.data, metadata.data[8:]...)
+	} else {
+		writer.data = append(writer.data, metadata.data...)
+	}
+
+	writer.writeMetadata(prepared.columns, nil, false)
+}
+
+// writeResult writes a RESULT body
+func (writer *writerStruct) writeResult(result *resultStruct, skipMeta bool) {
+	writer.writeInt(int32(result.kind))
+	switch result.kind {
+	case resultRows:
+		writer.writeMetadata(result.columns, result.pagingState, skipMeta && len(result.columns) > 0 && result.columns[0].name != "[applied]")
+		writer.writeInt(int32(len(result.rows)))
+		for _, row := range result.rows {
+			for _, value := range row {
+				writer.writeBytes(value)
+			}
+		}
+	case resultSetKeyspace:
+		writer.writeString(result.keyspace)
+	case resultSchemaChange:
+		writer.writeSchemaChange(result)
+	}
+}
+
+// writeSchemaChange writes the schema change of a result or event
+func (writer *writerStruct) writeSchemaChange(result *resultStruct) {
+	writer.writeString(result.changeType)
+	writer.writeString(result.changeTarget)
+	writer.writeString(result.keyspace)
+	if result.changeTarget != "KEYSPACE" {
+		writer.writeString(result.changeName)
+	}
+}
+
+// schemaEvent sends a SCHEMA_CHANGE event to registered connections
+func (server *Server) schemaEvent(result *resultStruct) {
+	if result.kind != resultSchemaChange {
+		return
+	}
+
+	writer := &writerStruct{}
+	writer.writeString("SCHEMA_CHANGE")
+	writer.writeSchemaChange(result)
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	for conn := range server.connections {
+		if conn.events["SCHEMA_CHANGE"] {
+			conn.write(conn.version, 0, -1, opEvent, writer.data)
+		}
+	}
+}
+
+// batch answers a BATCH message
+func (conn *serverConnStruct) batch(reader *readerStruct) (*resultStruct, string, []byte, *Error) {
+	server := conn.server
+	reader.readByte()
+	count := int(reader.readShort())
+
+	prepared := make([]*preparedStruct, 0, count)
+	valueSets := make([][]boundValueStruct, 0, count)
+	statements := make([]string, 0, count)
+	for i := 0; i < count && reader.err == nil; i++ {
+		var entry *preparedStruct
+		switch reader.readByte() {
+		case 0:
+			statement := reader.readLongString()
+			var err error
+			entry, err = server.store.prepare(statement, conn.keyspace)
+			if err != nil {
+				return nil, statement, nil, toError(err)
+			}
+		case 1:
+			id := reader.readShortBytes()
+			server.mutex.Lock()
+			entry = server.prepared[string(id)]
+			server.mutex.Unlock()
+			if entry == nil {
+				return nil, "", id, newError(ErrorCodeUnprepared, fmt.Sprintf("Prepared query with ID %x not found", id))
+			}
+		default:
+			return nil, "", nil, newError(ErrorCodeProtocol, "Invalid query kind in BATCH messages")
+		}
+
+		valueCount := int(reader.readShort())
+		values := make([]boundValueStruct, 0, valueCount)
+		for j := 0; j < valueCount && reader.err == nil; j++ {
+			values = append(values, reader.readValue())
+		}
+
+		injected, _ := server.record(entry.statement)
+		if injected != nil {
+			return nil, entry.statement, entry.id, injected
+		}
+		if entry.parsed == nil {
+			return nil, entry.statement, nil, invalidError("Invalid statement in batch: only UPDATE, INSERT and DELETE statements are allowed.")
+		}
+		values, err := bindValues(entry, &queryParametersStruct{values: values})
+		if err != nil {
+			return nil, entry.statement, nil, toError(err)
+		}
+
+		prepared = append(prepared, entry)
+		valueSets = append(valueSets, values)
+		statements = append(statements, entry.statement)
+	}
+	reader.readShort()
+	reader.readByte()
+	if reader.err != nil {
+		return nil, "", nil, newError(ErrorCodeProtocol, reader.err.Error())
+	}
+
+	result, err := server.store.executeBatch(prepared, valueSets, conn)
+	if err != nil {
+		return nil, strings.Join(statements, "; "), nil, toError(err)
+	}
+	return result, strings.Join(statements, "; "), nil, nil
+}
+
+// trace records a tracing session and its events in system_traces and returns the session id
+func (server *Server) trace(conn *serverConnStruct, command string, statement string, started time.Time) []byte {
+	store := server.store
+	sessionID := newTimeUUID()
+	elapsed := time.Since(started)
+	address := store.address
+	if tcpAddr, ok := conn.conn.RemoteAddr().(*net.TCPAddr); ok {
+		address = tcpAddr.IP.String()
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.putRow("system_traces", "sessions", map[string]interface{}{
+		"session_id":  sessionID,
+		"client":      address,
+		"command":     command,
+		"coordinator": store.address,
+		"duration":    int64(elapsed / time.Microsecond),
+		"parameters":  map[string]string{"query": statement},
+		"request":     "Execute CQL3 query",
+		"started_at":  started,
+	})
+
+	activities := []string{"Parsing " + statement, "Preparing statement", "Executing statement", "Request complete"}
+	for i, activity := range activities {
+		store.putRow("system_traces", "events", map[string]interface{}{
+			"session_id":     sessionID,
+			"event_id":       newTimeUUID(),
+			"activity":       activity,
+			"source":         store.address,
+			"source_elapsed": int64(elapsed/time.Microsecond) * int64(i) / int64(len(activities)-1),
+			"thread":         "Native-Transport-Requests-1",
+		})
+	}
+
+	return sessionID[:]
+}
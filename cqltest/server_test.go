@@ -0,0 +1,267 @@
+package cqltest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+func testNewSession(t *testing.T, server *Server, configure func(*gocql.ClusterConfig)) *gocql.Session {
+	clusterConfig := gocql.NewCluster(server.Host())
+	clusterConfig.Port = server.Port()
+	clusterConfig.Timeout = 10 * time.Second
+	clusterConfig.ConnectTimeout = 10 * time.Second
+	if configure != nil {
+		configure(clusterConfig)
+	}
+	session, err := clusterConfig.CreateSession()
+	if err != nil {
+		t.Fatalf("CreateSession error - received: %v - expected: %v ", err, nil)
+	}
+	return session
+}
+
+func testNewServer(t *testing.T) *Server {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	err = server.Exec("create keyspace cqltest with replication = {'class': 'SimpleStrategy', 'replication_factor': 1}")
+	if err != nil {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+	}
+	err = server.Exec("create table cqltest.data (id int, seq int, text_data text, list_data list<int>, primary key (id, seq))")
+	if err != nil {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+	}
+	return server
+}
+
+func TestServerInsertSelect(t *testing.T) {
+	server := testNewServer(t)
+	defer server.Close()
+	session := testNewSession(t, server, nil)
+	defer session.Close()
+
+	for i := 0; i < 5; i++ {
+		err := session.Query("insert into cqltest.data (id, seq, text_data, list_data) values (?, ?, ?, ?)", 1, i, "text", []int{i, i}).Exec()
+		if err != nil {
+			t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+		}
+	}
+	err := session.Query("update cqltest.data set text_data = ?, list_data = list_data + [9] where id = 1 and seq = 2", "two").Exec()
+	if err != nil {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+	}
+
+	var text string
+	var list []int
+	err = session.Query("select text_data, list_data from cqltest.data where id = ? and seq = ?", 1, 2).Scan(&text, &list)
+	if err != nil {
+		t.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+	}
+	if text != "two" {
+		t.Fatalf("text_data - received: %v - expected: %v ", text, "two")
+	}
+	if !reflect.DeepEqual(list, []int{2, 2, 9}) {
+		t.Fatalf("list_data - received: %v - expected: %v ", list, []int{2, 2, 9})
+	}
+
+	// paging
+	iter := session.Query("select seq from cqltest.data where id = 1").PageSize(2).Iter()
+	var seq int
+	var seqs []int
+	for iter.Scan(&seq) {
+		seqs = append(seqs, seq)
+	}
+	err = iter.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+	if !reflect.DeepEqual(seqs, []int{0, 1, 2, 3, 4}) {
+		t.Fatalf("seqs - received: %v - expected: %v ", seqs, []int{0, 1, 2, 3, 4})
+	}
+
+	// delete range
+	err = session.Query("delete from cqltest.data where id = 1 and seq >= 3").Exec()
+	if err != nil {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+	}
+	var count int
+	err = session.Query("select count(*) from cqltest.data where id = 1").Scan(&count)
+	if err != nil {
+		t.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+	}
+	if count != 3 {
+		t.Fatalf("count - received: %v - expected: %v ", count, 3)
+	}
+}
+
+func TestServerBatchAndCAS(t *testing.T) {
+	server := testNewServer(t)
+	defer server.Close()
+	session := testNewSession(t, server, func(clusterConfig *gocql.ClusterConfig) {
+		clusterConfig.ProtoVersion = 3
+		clusterConfig.Compressor = &gocql.SnappyCompressor{}
+	})
+	defer session.Close()
+
+	batch := session.NewBatch(gocql.LoggedBatch)
+	batch.Query("insert into cqltest.data (id, seq, text_data) values (?, ?, ?)", 2, 1, "one")
+	batch.Query("insert into cqltest.data (id, seq, text_data) values (?, ?, ?)", 2, 2, "two")
+	err := session.ExecuteBatch(batch)
+	if err != nil {
+		t.Fatalf("ExecuteBatch error - received: %v - expected: %v ", err, nil)
+	}
+
+	existing := make(map[string]interface{})
+	applied, err := session.Query("insert into cqltest.data (id, seq, text_data) values (?, ?, ?) if not exists", 2, 1, "other").MapScanCAS(existing)
+	if err != nil {
+		t.Fatalf("MapScanCAS error - received: %v - expected: %v ", err, nil)
+	}
+	if applied {
+		t.Fatalf("applied - received: %v - expected: %v ", applied, false)
+	}
+	if existing["text_data"] != "one" {
+		t.Fatalf("text_data - received: %v - expected: %v ", existing["text_data"], "one")
+	}
+
+	var text string
+	applied, err = session.Query("update cqltest.data set text_data = ? where id = ? and seq = ? if text_data = ?", "changed", 2, 2, "two").ScanCAS(&text)
+	if err != nil {
+		t.Fatalf("ScanCAS error - received: %v - expected: %v ", err, nil)
+	}
+	if !applied {
+		t.Fatalf("applied - received: %v - expected: %v ", applied, true)
+	}
+}
+
+func TestServerScriptAndInjectError(t *testing.T) {
+	server := testNewServer(t)
+	defer server.Close()
+	session := testNewSession(t, server, nil)
+	defer session.Close()
+
+	server.Script("SELECT name, value FROM   scripted.values WHERE key = ?", Response{
+		Columns: []Column{{Name: "name", Type: "text"}, {Name: "value", Type: "bigint"}},
+		Rows:    [][]interface{}{{"a", 1}, {"b", 2}},
+	})
+	iter := session.Query("select name, value from scripted.values where key = ?", "x").Iter()
+	var name string
+	var value int64
+	var names []string
+	for iter.Scan(&name, &value) {
+		names = append(names, name)
+	}
+	err := iter.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Fatalf("names - received: %v - expected: %v ", names, []string{"a", "b"})
+	}
+
+	server.InjectError("cqltest.data", 1, &Error{Code: ErrorCodeOverloaded, Message: "too busy"})
+	err = session.Query("insert into cqltest.data (id, seq) values (?, ?)", 3, 1).Exec()
+	if err == nil || err.Error() != "too busy" {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, "too busy")
+	}
+	err = session.Query("insert into cqltest.data (id, seq) values (?, ?)", 3, 1).Exec()
+	if err != nil {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+	}
+
+	statements := server.Statements()
+	if len(statements) == 0 || !strings.HasPrefix(statements[len(statements)-1], "insert into cqltest.data") {
+		t.Fatalf("Statements - received: %v - expected: %v ", statements, "insert into cqltest.data")
+	}
+
+	server.Reset()
+	if len(server.Statements()) != 0 {
+		t.Fatalf("Statements - received: %v - expected: %v ", server.Statements(), nil)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	server := testNewServer(t)
+	defer server.Close()
+	session := testNewSession(t, server, nil)
+	defer session.Close()
+
+	err := session.Query("select * from cqltest.missing").Exec()
+	expectedError := "unconfigured table missing"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, expectedError)
+	}
+
+	err = session.Query("selectt * from cqltest.data").Exec()
+	expectedError = "line 1:0 no viable alternative at input 'selectt'"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, expectedError)
+	}
+
+	err = session.Query("create table cqltest.data (id int primary key)").Exec()
+	expectedError = "Object cqltest.data already exists"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, expectedError)
+	}
+
+	err = session.Query("insert into system.local (key) values ('other')").Exec()
+	expectedError = "system keyspace is not user-modifiable."
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, expectedError)
+	}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	server := testNewServer(t)
+	defer server.Close()
+	server.SetCredentials("user", "secret")
+
+	clusterConfig := gocql.NewCluster(server.Host())
+	clusterConfig.Port = server.Port()
+	clusterConfig.Authenticator = gocql.PasswordAuthenticator{Username: "user", Password: "wrong"}
+	_, err := clusterConfig.CreateSession()
+	if err == nil {
+		t.Fatalf("CreateSession error - received: %v - expected: %v ", err, "error")
+	}
+
+	session := testNewSession(t, server, func(clusterConfig *gocql.ClusterConfig) {
+		clusterConfig.Authenticator = gocql.PasswordAuthenticator{Username: "user", Password: "secret"}
+	})
+	session.Close()
+}
+
+func TestServerTracing(t *testing.T) {
+	server := testNewServer(t)
+	defer server.Close()
+	session := testNewSession(t, server, nil)
+	defer session.Close()
+
+	builder := &strings.Builder{}
+	err := session.Query("select * from cqltest.data").Trace(gocql.NewTraceWriter(session, builder)).Exec()
+	if err != nil {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+	}
+	if !strings.Contains(builder.String(), "Parsing select * from cqltest.data") {
+		t.Fatalf("trace - received: %v - expected: %v ", builder.String(), "Parsing select * from cqltest.data")
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	err = server.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+	err = server.Close()
+	if err != ErrServerClosed {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, ErrServerClosed)
+	}
+}
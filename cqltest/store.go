@@ -0,0 +1,301 @@
+package cqltest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	timeUUIDMutex    sync.Mutex
+	timeUUIDLastTime int64
+)
+
+// newStore returns a store with the system keyspaces and the local host row
+func newStore(address string) *storeStruct {
+	store := &storeStruct{
+		keyspaces:     make(map[string]*keyspaceStruct),
+		hostID:        newUUID(),
+		schemaVersion: newUUID(),
+		address:       address,
+	}
+
+	for _, name := range systemKeyspaces {
+		store.keyspaces[name] = &keyspaceStruct{
+			name:          name,
+			system:        true,
+			replication:   map[string]string{"class": "org.apache.cassandra.locator.LocalStrategy"},
+			durableWrites: true,
+			tables:        make(map[string]*tableStruct),
+			types:         make(map[string]*typeStruct),
+		}
+	}
+
+	store.systemTable("system", "local", []string{
+		"key text", "bootstrapped text", "broadcast_address inet", "cluster_name text", "cql_version text",
+		"data_center text", "host_id uuid", "listen_address inet", "native_protocol_version text",
+		"partitioner text", "rack text", "release_version text", "rpc_address inet",
+		"schema_version uuid", "tokens set<text>",
+	}, "key", "")
+	store.systemTable("system", "peers", []string{
+		"peer inet", "data_center text", "host_id uuid", "preferred_ip inet", "rack text",
+		"release_version text", "rpc_address inet", "schema_version uuid", "tokens set<text>",
+	}, "peer", "")
+	store.systemTable("system_schema", "keyspaces", []string{
+		"keyspace_name text", "durable_writes boolean", "replication map<text, text>",
+	}, "keyspace_name", "")
+	store.systemTable("system_traces", "sessions", []string{
+		"session_id uuid", "client inet", "command text", "coordinator inet", "duration int",
+		"parameters map<text, text>", "request text", "started_at timestamp",
+	}, "session_id", "")
+	store.systemTable("system_traces", "events", []string{
+		"session_id uuid", "event_id timeuuid", "activity text", "source inet", "source_elapsed int", "thread text",
+	}, "session_id", "event_id")
+
+	store.putRow("system", "local", map[string]interface{}{
+		"key":                     "local",
+		"bootstrapped":            "COMPLETED",
+		"broadcast_address":       address,
+		"cluster_name":            ClusterName,
+		"cql_version":             CQLVersion,
+		"data_center":             DataCenter,
+		"host_id":                 store.hostID,
+		"listen_address":          address,
+		"native_protocol_version": "4",
+		"partitioner":             "org.apache.cassandra.dht.Murmur3Partitioner",
+		"rack":                    Rack,
+		"release_version":         ReleaseVersion,
+		"rpc_address":             address,
+		"schema_version":          store.schemaVersion,
+		"tokens":                  []string{"0"},
+	})
+	for _, name := range systemKeyspaces {
+		store.syncKeyspaceRow(store.keyspaces[name])
+	}
+
+	return store
+}
+
+// systemTable adds a system table from column definitions
+func (store *storeStruct) systemTable(keyspace string, name string, definitions []string, partitionKey string, clusteringKey string) {
+	parsed := &statementStruct{partitionKey: []string{partitionKey}}
+	if clusteringKey != "" {
+		parsed.clusteringKey = []string{clusteringKey}
+	}
+	for _, definition := range definitions {
+		fields := strings.SplitN(definition, " ", 2)
+		parsed.definitions = append(parsed.definitions, &definitionStruct{name: fields[0], typeText: fields[1]})
+	}
+	table, err := store.newTable(store.keyspaces[keyspace], name, parsed)
+	if err != nil {
+		panic(err)
+	}
+	store.keyspaces[keyspace].tables[name] = table
+}
+
+// putRow upserts a row of Go values, used for system tables
+func (store *storeStruct) putRow(keyspace string, name string, values map[string]interface{}) {
+	table := store.keyspaces[keyspace].tables[name]
+	row := make([][]byte, len(table.columns))
+	set := make([]bool, len(table.columns))
+	for columnName, value := range values {
+		column := table.columnMap[columnName]
+		data, err := encodeValue(column.typeInfo, value)
+		if err != nil {
+			panic(err)
+		}
+		row[column.index] = data
+		set[column.index] = true
+	}
+	table.upsert(row, set)
+}
+
+// syncKeyspaceRow updates the system_schema.keyspaces row of a keyspace
+func (store *storeStruct) syncKeyspaceRow(keyspace *keyspaceStruct) {
+	store.putRow("system_schema", "keyspaces", map[string]interface{}{
+		"keyspace_name":  keyspace.name,
+		"durable_writes": keyspace.durableWrites,
+		"replication":    keyspace.replication,
+	})
+}
+
+// changeSchema records a new schema version
+func (store *storeStruct) changeSchema() {
+	store.schemaVersion = newUUID()
+	store.putRow("system", "local", map[string]interface{}{
+		"key":            "local",
+		"schema_version": store.schemaVersion,
+	})
+}
+
+// newTable returns a table from a create table statement
+func (store *storeStruct) newTable(keyspace *keyspaceStruct, name string, parsed *statementStruct) (*tableStruct, error) {
+	table := &tableStruct{
+		keyspace:  keyspace.name,
+		name:      name,
+		columnMap: make(map[string]*columnStruct),
+		rows:      make(map[string]*rowStruct),
+	}
+
+	partitionKey := parsed.partitionKey
+	clusteringKey := parsed.clusteringKey
+	var columns []*columnStruct
+	for _, definition := range parsed.definitions {
+		if _, ok := table.columnMap[definition.name]; ok {
+			return nil, invalidError("Multiple definition of identifier " + definition.name)
+		}
+		typeInfo, err := store.parseType(definition.typeText, keyspace.name)
+		if err != nil {
+			return nil, err
+		}
+		column := &columnStruct{name: definition.name, typeInfo: typeInfo}
+		table.columnMap[definition.name] = column
+		columns = append(columns, column)
+		if definition.primaryKey {
+			if len(partitionKey) > 0 {
+				return nil, invalidError("Multiple PRIMARY KEYs specified (exactly one required)")
+			}
+			partitionKey = []string{definition.name}
+		}
+	}
+	if len(partitionKey) == 0 {
+		return nil, invalidError("No PRIMARY KEY specifed (exactly one required)")
+	}
+
+	for _, columnName := range append(append([]string{}, partitionKey...), clusteringKey...) {
+		column, ok := table.columnMap[columnName]
+		if !ok {
+			return nil, invalidError("Unknown definition " + columnName + " referenced in PRIMARY KEY")
+		}
+		if column.typeInfo.id == typeCounter {
+			return nil, invalidError("counter type is not supported for PRIMARY KEY part " + columnName)
+		}
+	}
+	for _, columnName := range partitionKey {
+		table.partitionKey = append(table.partitionKey, table.columnMap[columnName])
+	}
+	for _, columnName := range clusteringKey {
+		table.clusteringKey = append(table.clusteringKey, table.columnMap[columnName])
+	}
+
+	// columns are ordered like Cassandra, partition key, clustering key, then the rest by name
+	var regular []*columnStruct
+	for _, column := range columns {
+		if !table.isPrimaryKey(column) {
+			regular = append(regular, column)
+		}
+	}
+	sort.Slice(regular, func(i, j int) bool { return regular[i].name < regular[j].name })
+	table.columns = append(append(append([]*columnStruct{}, table.partitionKey...), table.clusteringKey...), regular...)
+	for i, column := range table.columns {
+		column.index = i
+	}
+
+	return table, nil
+}
+
+// isPrimaryKey returns true if the column is part of the primary key
+func (table *tableStruct) isPrimaryKey(column *columnStruct) bool {
+	for _, key := range table.partitionKey {
+		if key == column {
+			return true
+		}
+	}
+	for _, key := range table.clusteringKey {
+		if key == column {
+			return true
+		}
+	}
+	return false
+}
+
+// isPartitionKey returns true if the column is part of the partition key
+func (table *tableStruct) isPartitionKey(column *columnStruct) bool {
+	for _, key := range table.partitionKey {
+		if key == column {
+			return true
+		}
+	}
+	return false
+}
+
+// rowKey returns the storage key of a row from its primary key values
+func (table *tableStruct) rowKey(values [][]byte) string {
+	writer := &writerStruct{}
+	for _, column := range table.partitionKey {
+		writer.writeBytes(values[column.index])
+	}
+	for _, column := range table.clusteringKey {
+		writer.writeBytes(values[column.index])
+	}
+	return string(writer.data)
+}
+
+// upsert inserts or updates a row, when set is not nil only the columns it marks are written
+func (table *tableStruct) upsert(values [][]byte, set []bool) *rowStruct {
+	key := table.rowKey(values)
+	row, ok := table.rows[key]
+	if !ok {
+		row = &rowStruct{key: key, values: make([][]byte, len(table.columns))}
+		table.rows[key] = row
+	}
+	for i, value := range values {
+		if set == nil || set[i] {
+			row.values[i] = value
+		}
+	}
+	return row
+}
+
+// sortedRows returns the rows sorted by primary key
+func (table *tableStruct) sortedRows() []*rowStruct {
+	rows := make([]*rowStruct, 0, len(table.rows))
+	for _, row := range table.rows {
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		for _, column := range append(append([]*columnStruct{}, table.partitionKey...), table.clusteringKey...) {
+			compare := bytes.Compare(rows[i].values[column.index], rows[j].values[column.index])
+			if compare != 0 {
+				return compare < 0
+			}
+		}
+		return false
+	})
+	return rows
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() [16]byte {
+	var uuid [16]byte
+	_, err := rand.Read(uuid[:])
+	if err != nil {
+		panic(err)
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return uuid
+}
+
+// newTimeUUID returns a version 1 UUID for the current time
+func newTimeUUID() [16]byte {
+	// 100 nanosecond intervals since 1582-10-15
+	timeUUIDMutex.Lock()
+	now := time.Now().UnixNano()/100 + 0x01B21DD213814000
+	if now <= timeUUIDLastTime {
+		now = timeUUIDLastTime + 1
+	}
+	timeUUIDLastTime = now
+	timeUUIDMutex.Unlock()
+
+	uuid := newUUID()
+	binary.BigEndian.PutUint32(uuid[0:], uint32(now))
+	binary.BigEndian.PutUint16(uuid[4:], uint16(now>>32))
+	binary.BigEndian.PutUint16(uuid[6:], uint16(now>>48)&0x0fff|0x1000)
+	uuid[10] |= 0x01
+	return uuid
+}
@@ -0,0 +1,746 @@
+package cqltest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const durationClass = "org.apache.cassandra.db.marshal.DurationType"
+
+// parseType parses CQL type text, user defined types are looked up in keyspace
+func (store *storeStruct) parseType(text string, keyspace string) (*typeStruct, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, invalidError("Unknown type " + text)
+	}
+	parser := &parserStruct{tokens: tokens}
+	typeInfo, err := store.parseTypeTokens(parser, keyspace)
+	if err != nil {
+		return nil, err
+	}
+	if parser.peek().kind != tokenEOF {
+		return nil, invalidError("Unknown type " + text)
+	}
+	return typeInfo, nil
+}
+
+func (store *storeStruct) parseTypeTokens(parser *parserStruct, keyspace string) (*typeStruct, error) {
+	token := parser.next()
+	switch token.kind {
+	case tokenString:
+		return &typeStruct{id: typeCustom, name: token.text}, nil
+	case tokenIdentifier, tokenQuotedIdentifier:
+	default:
+		return nil, invalidError("Unknown type " + token.text)
+	}
+
+	name := token.text
+	if token.kind == tokenIdentifier {
+		if id, ok := nativeTypes[name]; ok {
+			return &typeStruct{id: id}, nil
+		}
+		if name == "duration" {
+			return &typeStruct{id: typeCustom, name: durationClass}, nil
+		}
+	}
+
+	var elems []*typeStruct
+	if token.kind == tokenIdentifier && parser.acceptSymbol("<") {
+		for {
+			elem, err := store.parseTypeTokens(parser, keyspace)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+			if !parser.acceptSymbol(",") {
+				break
+			}
+		}
+		if !parser.acceptSymbol(">") {
+			return nil, invalidError("Unknown type " + name)
+		}
+
+		switch {
+		case name == "frozen" && len(elems) == 1:
+			return elems[0], nil
+		case (name == "list" || name == "set") && len(elems) == 1:
+			id := uint16(typeList)
+			if name == "set" {
+				id = typeSet
+			}
+			return &typeStruct{id: id, elems: elems}, nil
+		case name == "map" && len(elems) == 2:
+			return &typeStruct{id: typeMap, elems: elems}, nil
+		case name == "tuple" && len(elems) > 0:
+			return &typeStruct{id: typeTuple, elems: elems}, nil
+		}
+		return nil, invalidError("Unknown type " + name)
+	}
+
+	if parser.acceptSymbol(".") {
+		keyspace = name
+		var err error
+		name, err = parser.parseIdentifier()
+		if err != nil {
+			return nil, invalidError("Unknown type " + keyspace + ".")
+		}
+	}
+	keyspaceStore := store.keyspaces[keyspace]
+	if keyspaceStore != nil {
+		if udt, ok := keyspaceStore.types[name]; ok {
+			return udt, nil
+		}
+	}
+	return nil, invalidError("Unknown type " + keyspace + "." + name)
+}
+
+// String returns the CQL text of the type
+func (typeInfo *typeStruct) String() string {
+	switch typeInfo.id {
+	case typeCustom:
+		if typeInfo.name == durationClass {
+			return "duration"
+		}
+		return "'" + typeInfo.name + "'"
+	case typeList, typeSet, typeMap, typeTuple:
+		name := map[uint16]string{typeList: "list", typeSet: "set", typeMap: "map", typeTuple: "tuple"}[typeInfo.id]
+		elems := make([]string, len(typeInfo.elems))
+		for i, elem := range typeInfo.elems {
+			elems[i] = elem.String()
+			if elem.id >= typeList {
+				elems[i] = "frozen<" + elems[i] + ">"
+			}
+		}
+		return name + "<" + strings.Join(elems, ", ") + ">"
+	case typeUDT:
+		return typeInfo.name
+	}
+	return typeNames[typeInfo.id]
+}
+
+// encodeValue encodes a literal or Go value as the native protocol bytes of a type, nil is null
+func encodeValue(typeInfo *typeStruct, value interface{}) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() == reflect.Ptr {
+		if reflectValue.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(typeInfo, reflectValue.Elem().Interface())
+	}
+
+	switch typeInfo.id {
+	case typeASCII, typeVarchar:
+		switch data := value.(type) {
+		case string:
+			return []byte(data), nil
+		case []byte:
+			return data, nil
+		}
+
+	case typeBlob:
+		switch data := value.(type) {
+		case []byte:
+			return data, nil
+		case string:
+			return []byte(data), nil
+		}
+
+	case typeBoolean:
+		if data, ok := value.(bool); ok {
+			if data {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		}
+
+	case typeBigInt, typeCounter, typeInt, typeSmallInt, typeTinyInt:
+		number, ok := toInt64(value)
+		if !ok {
+			break
+		}
+		size := map[uint16]int{typeBigInt: 8, typeCounter: 8, typeInt: 4, typeSmallInt: 2, typeTinyInt: 1}[typeInfo.id]
+		if size < 8 && (number < -(1<<(uint(size)*8-1)) || number >= 1<<(uint(size)*8-1)) {
+			return nil, invalidError(fmt.Sprintf("Invalid %v value %v", typeInfo, value))
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(number))
+		return data[8-size:], nil
+
+	case typeVarint:
+		number, ok := toBigInt(value)
+		if ok {
+			return encodeBigInt(number), nil
+		}
+
+	case typeFloat, typeDouble:
+		number, ok := toFloat64(value)
+		if !ok {
+			break
+		}
+		if typeInfo.id == typeFloat {
+			data := make([]byte, 4)
+			binary.BigEndian.PutUint32(data, math.Float32bits(float32(number)))
+			return data, nil
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, math.Float64bits(number))
+		return data, nil
+
+	case typeDecimal:
+		var text string
+		switch data := value.(type) {
+		case numberLiteral:
+			text = string(data)
+		case string:
+			text = data
+		default:
+			number, ok := toFloat64(value)
+			if !ok {
+				return nil, invalidError(fmt.Sprintf("Invalid decimal value %v", value))
+			}
+			text = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		return encodeDecimal(text)
+
+	case typeTimestamp:
+		switch data := value.(type) {
+		case time.Time:
+			return encodeInt64(data.UnixNano() / int64(time.Millisecond)), nil
+		case string:
+			parsed, err := parseTimestamp(data)
+			if err != nil {
+				return nil, err
+			}
+			return encodeInt64(parsed.UnixNano() / int64(time.Millisecond)), nil
+		}
+		if number, ok := toInt64(value); ok {
+			return encodeInt64(number), nil
+		}
+
+	case typeDate:
+		var days int64
+		switch data := value.(type) {
+		case time.Time:
+			days = data.Unix() / 86400
+			if data.Unix() < 0 && data.Unix()%86400 != 0 {
+				days--
+			}
+		case string:
+			parsed, err := time.Parse("2006-01-02", data)
+			if err != nil {
+				return nil, invalidError("Unable to coerce '" + data + "' to a formatted date (long)")
+			}
+			days = parsed.Unix() / 86400
+		default:
+			number, ok := toInt64(value)
+			if !ok {
+				return nil, invalidError(fmt.Sprintf("Invalid date value %v", value))
+			}
+			return encodeInt32(int32(uint32(number))), nil
+		}
+		return encodeInt32(int32(uint32(days + (1 << 31)))), nil
+
+	case typeTime:
+		switch data := value.(type) {
+		case time.Duration:
+			return encodeInt64(int64(data)), nil
+		case string:
+			parsed, err := time.Parse("15:04:05.999999999", data)
+			if err != nil {
+				return nil, invalidError("Unable to coerce '" + data + "' to a formatted time (long)")
+			}
+			clock := time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute +
+				time.Duration(parsed.Second())*time.Second + time.Duration(parsed.Nanosecond())
+			return encodeInt64(int64(clock)), nil
+		}
+		if number, ok := toInt64(value); ok {
+			return encodeInt64(number), nil
+		}
+
+	case typeUUID, typeTimeUUID:
+		switch data := value.(type) {
+		case uuidLiteral:
+			return append([]byte{}, data[:]...), nil
+		case [16]byte:
+			return append([]byte{}, data[:]...), nil
+		case []byte:
+			if len(data) == 16 {
+				return data, nil
+			}
+		case string:
+			uuid, err := hex.DecodeString(strings.Replace(data, "-", "", -1))
+			if err == nil && len(uuid) == 16 {
+				return uuid, nil
+			}
+		default:
+			if reflectValue.Kind() == reflect.Array && reflectValue.Len() == 16 && reflectValue.Type().Elem().Kind() == reflect.Uint8 {
+				uuid := make([]byte, 16)
+				reflect.Copy(reflect.ValueOf(uuid), reflectValue)
+				return uuid, nil
+			}
+		}
+
+	case typeInet:
+		var ip net.IP
+		switch data := value.(type) {
+		case net.IP:
+			ip = data
+		case string:
+			ip = net.ParseIP(data)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return []byte(ip4), nil
+		}
+		if ip != nil {
+			return []byte(ip), nil
+		}
+
+	case typeList, typeSet:
+		elems, ok := toSlice(value)
+		if !ok {
+			break
+		}
+		encoded := make([][]byte, 0, len(elems))
+		for _, elem := range elems {
+			data, err := encodeValue(typeInfo.elems[0], elem)
+			if err != nil {
+				return nil, err
+			}
+			if data == nil {
+				return nil, invalidError("null is not supported inside collections")
+			}
+			encoded = append(encoded, data)
+		}
+		if typeInfo.id == typeSet {
+			encoded = sortUnique(encoded)
+		}
+		return encodeCollection(encoded), nil
+
+	case typeMap:
+		keys, values, ok := toMap(value)
+		if !ok {
+			break
+		}
+		pairs := make([][2][]byte, 0, len(keys))
+		for i := range keys {
+			key, err := encodeValue(typeInfo.elems[0], keys[i])
+			if err != nil {
+				return nil, err
+			}
+			data, err := encodeValue(typeInfo.elems[1], values[i])
+			if err != nil {
+				return nil, err
+			}
+			if key == nil || data == nil {
+				return nil, invalidError("null is not supported inside collections")
+			}
+			pairs = append(pairs, [2][]byte{key, data})
+		}
+		return encodeMap(pairs), nil
+
+	case typeTuple:
+		elems, ok := toSlice(value)
+		if !ok || len(elems) > len(typeInfo.elems) {
+			break
+		}
+		writer := &writerStruct{data: []byte{}}
+		for i, elemType := range typeInfo.elems {
+			var data []byte
+			if i < len(elems) {
+				var err error
+				data, err = encodeValue(elemType, elems[i])
+				if err != nil {
+					return nil, err
+				}
+			}
+			writer.writeBytes(data)
+		}
+		return writer.data, nil
+
+	case typeUDT:
+		keys, values, ok := toMap(value)
+		if !ok {
+			break
+		}
+		fields := make(map[string]interface{}, len(keys))
+		for i, key := range keys {
+			name := fmt.Sprint(key)
+			found := false
+			for _, field := range typeInfo.fields {
+				found = found || field == name
+			}
+			if !found {
+				return nil, invalidError("Unknown field '" + name + "' in value of user defined type " + typeInfo.name)
+			}
+			fields[name] = values[i]
+		}
+		writer := &writerStruct{data: []byte{}}
+		for i, field := range typeInfo.fields {
+			data, err := encodeValue(typeInfo.elems[i], fields[field])
+			if err != nil {
+				return nil, err
+			}
+			writer.writeBytes(data)
+		}
+		return writer.data, nil
+
+	case typeCustom:
+		switch data := value.(type) {
+		case []byte:
+			return data, nil
+		case time.Duration:
+			if typeInfo.name == durationClass {
+				return encodeDuration(0, 0, int64(data)), nil
+			}
+		case string:
+			if typeInfo.name == durationClass {
+				return parseDuration(data)
+			}
+		}
+	}
+
+	return nil, invalidError(fmt.Sprintf("Invalid %v value %v for type %v", reflect.TypeOf(value), value, typeInfo))
+}
+
+func encodeInt32(value int32) []byte {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(value))
+	return data
+}
+
+func encodeInt64(value int64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(value))
+	return data
+}
+
+// encodeBigInt encodes a varint as big-endian two's complement
+func encodeBigInt(number *big.Int) []byte {
+	if number.Sign() >= 0 {
+		data := number.Bytes()
+		if len(data) == 0 || data[0]&0x80 != 0 {
+			data = append([]byte{0}, data...)
+		}
+		return data
+	}
+
+	length := (number.BitLen() + 8) / 8
+	twos := new(big.Int).Add(number, new(big.Int).Lsh(big.NewInt(1), uint(length*8)))
+	data := twos.Bytes()
+	for len(data) < length {
+		data = append([]byte{0xff}, data...)
+	}
+	if data[0]&0x80 == 0 {
+		data = append([]byte{0xff}, data...)
+	}
+	return data
+}
+
+// encodeDecimal encodes decimal text as a scale and unscaled varint
+func encodeDecimal(text string) ([]byte, error) {
+	mantissa := text
+	exponent := 0
+	if index := strings.IndexAny(text, "eE"); index >= 0 {
+		var err error
+		exponent, err = strconv.Atoi(text[index+1:])
+		if err != nil {
+			return nil, invalidError("Invalid decimal value " + text)
+		}
+		mantissa = text[:index]
+	}
+	scale := 0
+	if index := strings.IndexByte(mantissa, '.'); index >= 0 {
+		scale = len(mantissa) - index - 1
+		mantissa = mantissa[:index] + mantissa[index+1:]
+	}
+	scale -= exponent
+
+	unscaled, ok := new(big.Int).SetString(mantissa, 10)
+	if !ok {
+		return nil, invalidError("Invalid decimal value " + text)
+	}
+	return append(encodeInt32(int32(scale)), encodeBigInt(unscaled)...), nil
+}
+
+// encodeDuration encodes a duration as zig-zag variable length months, days, and nanoseconds
+func encodeDuration(months int64, days int64, nanoseconds int64) []byte {
+	data := make([]byte, 0, 3*binary.MaxVarintLen64)
+	buffer := make([]byte, binary.MaxVarintLen64)
+	for _, value := range []int64{months, days, nanoseconds} {
+		zigzag := uint64(value<<1) ^ uint64(value>>63)
+		data = append(data, encodeVint(zigzag, buffer)...)
+	}
+	return data
+}
+
+// encodeVint encodes an unsigned variable length integer the way Cassandra does
+func encodeVint(value uint64, buffer []byte) []byte {
+	size := 1
+	for size < 9 && value >= 1<<(uint(7*size)) {
+		size++
+	}
+	if size == 9 {
+		buffer[0] = 0xff
+		binary.BigEndian.PutUint64(buffer[1:], value)
+		return buffer[:9]
+	}
+	for i := size - 1; i >= 0; i-- {
+		buffer[i] = byte(value)
+		value >>= 8
+	}
+	buffer[0] |= byte(0xff << uint(9-size))
+	return buffer[:size]
+}
+
+// parseDuration parses duration text like 1h30m or 2mo3d
+func parseDuration(text string) ([]byte, error) {
+	units := []struct {
+		name   string
+		months int64
+		days   int64
+		nanos  int64
+	}{
+		{"mo", 1, 0, 0}, {"ms", 0, 0, int64(time.Millisecond)}, {"us", 0, 0, int64(time.Microsecond)},
+		{"µs", 0, 0, int64(time.Microsecond)}, {"ns", 0, 0, 1}, {"y", 12, 0, 0}, {"w", 0, 7, 0},
+		{"d", 0, 1, 0}, {"h", 0, 0, int64(time.Hour)}, {"m", 0, 0, int64(time.Minute)}, {"s", 0, 0, int64(time.Second)},
+	}
+
+	negative := strings.HasPrefix(text, "-")
+	rest := strings.ToLower(strings.TrimPrefix(text, "-"))
+	if rest == "" {
+		return nil, invalidError("Unable to convert '" + text + "' to a duration")
+	}
+	var months, days, nanos int64
+	for rest != "" {
+		end := 0
+		for end < len(rest) && isDigit(rest[end]) {
+			end++
+		}
+		number, err := strconv.ParseInt(rest[:end], 10, 64)
+		if err != nil {
+			return nil, invalidError("Unable to convert '" + text + "' to a duration")
+		}
+		rest = rest[end:]
+		found := false
+		for _, unit := range units {
+			if strings.HasPrefix(rest, unit.name) {
+				months += number * unit.months
+				days += number * unit.days
+				nanos += number * unit.nanos
+				rest = rest[len(unit.name):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, invalidError("Unable to convert '" + text + "' to a duration")
+		}
+	}
+	if negative {
+		months, days, nanos = -months, -days, -nanos
+	}
+	return encodeDuration(months, days, nanos), nil
+}
+
+// parseTimestamp parses timestamp text the way Cassandra accepts it
+func parseTimestamp(text string) (time.Time, error) {
+	formats := []string{
+		"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02T15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05.999999999Z0700", "2006-01-02T15:04:05.999999999Z0700",
+		"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04Z0700", "2006-01-02 15:04", "2006-01-02Z0700", "2006-01-02",
+	}
+	for _, format := range formats {
+		parsed, err := time.Parse(format, text)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, invalidError("Unable to coerce '" + text + "' to a formatted date (long)")
+}
+
+// encodeCollection encodes list or set elements
+func encodeCollection(elems [][]byte) []byte {
+	writer := &writerStruct{data: []byte{}}
+	writer.writeInt(int32(len(elems)))
+	for _, elem := range elems {
+		writer.writeBytes(elem)
+	}
+	return writer.data
+}
+
+// encodeMap encodes map pairs sorted by key, later duplicate keys replace earlier ones
+func encodeMap(pairs [][2][]byte) []byte {
+	unique := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		unique[string(pair[0])] = pair[1]
+	}
+	keys := make([][]byte, 0, len(unique))
+	for key := range unique {
+		keys = append(keys, []byte(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+	writer := &writerStruct{data: []byte{}}
+	writer.writeInt(int32(len(keys)))
+	for _, key := range keys {
+		writer.writeBytes(key)
+		writer.writeBytes(unique[string(key)])
+	}
+	return writer.data
+}
+
+// decodeCollection decodes list or set elements, or map keys and values alternating
+func decodeCollection(data []byte, isMap bool) ([][]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+	reader := &readerStruct{data: data}
+	count := int(reader.readInt())
+	if isMap {
+		count *= 2
+	}
+	elems := make([][]byte, 0, count)
+	for i := 0; i < count && reader.err == nil; i++ {
+		elems = append(elems, reader.readValue().data)
+	}
+	if reader.err != nil {
+		return nil, invalidError("Invalid collection value")
+	}
+	return elems, nil
+}
+
+// sortUnique sorts values and removes duplicates
+func sortUnique(values [][]byte) [][]byte {
+	sort.Slice(values, func(i, j int) bool { return bytes.Compare(values[i], values[j]) < 0 })
+	unique := values[:0]
+	for i, value := range values {
+		if i == 0 || !bytes.Equal(value, values[i-1]) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch data := value.(type) {
+	case numberLiteral:
+		number, err := strconv.ParseInt(string(data), 10, 64)
+		return number, err == nil
+	case string:
+		number, err := strconv.ParseInt(data, 10, 64)
+		return number, err == nil
+	case time.Duration:
+		return int64(data), true
+	}
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflectValue.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if reflectValue.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(reflectValue.Uint()), true
+	}
+	return 0, false
+}
+
+func toBigInt(value interface{}) (*big.Int, bool) {
+	switch data := value.(type) {
+	case *big.Int:
+		return data, true
+	case big.Int:
+		return &data, true
+	case numberLiteral:
+		return new(big.Int).SetString(string(data), 10)
+	case string:
+		return new(big.Int).SetString(data, 10)
+	}
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(reflectValue.Uint()), true
+	}
+	number, ok := toInt64(value)
+	if !ok {
+		return nil, false
+	}
+	return big.NewInt(number), true
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch data := value.(type) {
+	case numberLiteral:
+		number, err := strconv.ParseFloat(string(data), 64)
+		return number, err == nil
+	case float32:
+		return float64(data), true
+	case float64:
+		return data, true
+	}
+	number, ok := toInt64(value)
+	return float64(number), ok
+}
+
+// toSlice returns the elements of a list, set, tuple, or Go slice value
+func toSlice(value interface{}) ([]interface{}, bool) {
+	switch data := value.(type) {
+	case listLiteral:
+		return data, true
+	case setLiteral:
+		return data, true
+	case tupleLiteral:
+		return data, true
+	case mapLiteral:
+		return nil, len(data.keys) == 0
+	case string, []byte:
+		return nil, false
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Array {
+		return nil, false
+	}
+	elems := make([]interface{}, reflectValue.Len())
+	for i := range elems {
+		elems[i] = reflectValue.Index(i).Interface()
+	}
+	return elems, true
+}
+
+// toMap returns the keys and values of a map literal or Go map value
+func toMap(value interface{}) ([]interface{}, []interface{}, bool) {
+	if data, ok := value.(mapLiteral); ok {
+		return data.keys, data.values, true
+	}
+	if data, ok := value.(setLiteral); ok && len(data) == 0 {
+		return nil, nil, true
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Map {
+		return nil, nil, false
+	}
+	keys := make([]interface{}, 0, reflectValue.Len())
+	values := make([]interface{}, 0, reflectValue.Len())
+	iter := reflectValue.MapRange()
+	for iter.Next() {
+		keys = append(keys, iter.Key().Interface())
+		values = append(values, iter.Value().Interface())
+	}
+	return keys, values, true
+}
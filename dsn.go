@@ -0,0 +1,690 @@
+package cql
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// NewConfig returns a new Config with the defaults set
+func NewConfig() *Config {
+	clusterConfig := NewClusterConfig()
+	return &Config{
+		Hosts:                 clusterConfig.Hosts,
+		Consistency:           clusterConfig.Consistency,
+		Timeout:               clusterConfig.Timeout,
+		ConnectTimeout:        clusterConfig.ConnectTimeout,
+		NumConns:              clusterConfig.NumConns,
+		WriteCoalesceWaitTime: clusterConfig.WriteCoalesceWaitTime,
+		ProtoVersion:          clusterConfig.ProtoVersion,
+		CQLVersion:            clusterConfig.CQLVersion,
+		Port:                  clusterConfig.Port,
+		MaxPreparedStmts:      clusterConfig.MaxPreparedStmts,
+		Retries:               -1,
+		RetryMin:              -1,
+		RetryMax:              -1,
+		ReconnectRetries:      -1,
+		ReconnectInterval:     -1,
+		ReconnectMaxInterval:  -1,
+	}
+}
+
+// ParseConfig parses a config string, hosts?key=value&key=value or a cql:// URL, into a Config.
+// When ExpandValues is enabled the ${env:NAME} and ${file:path} values are resolved.
+// Only the syntax of the values is checked, use Validate to check the values.
+// A negative timeout or connectTimeout and a numConns less than 1 keep the default.
+func ParseConfig(configString string) (*Config, error) {
+	config := NewConfig()
+	var values string
+
+	if strings.HasPrefix(configString, "cql://") {
+		hosts, passwordAuthenticator, keyspace, urlValues, err := splitURL(configString)
+		if err != nil {
+			return nil, err
+		}
+		if hosts != nil {
+			config.Hosts = hosts
+		}
+		if passwordAuthenticator != nil {
+			config.Username = passwordAuthenticator.Username
+			config.Password = passwordAuthenticator.Password
+			config.emptyPasswordAuthenticator = config.Username == "" && config.Password == ""
+		}
+		config.Keyspace = keyspace
+		values = urlValues
+	} else {
+		configStringSplit := strings.SplitN(configString, "?", 2)
+		if len(configStringSplit[0]) > 1 {
+			hostsSplit := strings.Split(configStringSplit[0], ",")
+			config.Hosts = make([]string, len(hostsSplit))
+			for i := 0; i < len(hostsSplit); i++ {
+				config.Hosts[i] = strings.TrimSpace(hostsSplit[i])
+			}
+		}
+		if len(configStringSplit) > 1 {
+			values = configStringSplit[1]
+		}
+	}
+
+	tlsKey := false
+	if len(values) > 1 {
+		dataSplit := strings.Split(values, "&")
+		for i := 0; i < len(dataSplit); i++ {
+			settingSplit := strings.SplitN(dataSplit[i], "=", 2)
+			if len(settingSplit) != 2 {
+				return nil, fmt.Errorf("missing =")
+			}
+			key := strings.TrimSpace(settingSplit[0])
+			value, err := expandValue(key, settingSplit[1])
+			if err != nil {
+				return nil, err
+			}
+			err = config.setValue(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if key == "tls" {
+				tlsKey = true
+			}
+		}
+	}
+
+	// the other tls settings enable tls unless tls is set to false
+	if config.hasTLSSettings() {
+		if tlsKey && !config.TLS {
+			return nil, fmt.Errorf("tls settings require tls=true")
+		}
+		config.TLS = true
+	}
+
+	return config, nil
+}
+
+// hasTLSSettings returns true if any tls setting other than tls is set
+func (config *Config) hasTLSSettings() bool {
+	return config.TLSServerName != "" || config.TLSMinVersion != "" || config.TLSInsecureSkipVerify || config.TLSConfig != ""
+}
+
+// setValue sets the field of a config string key
+func (config *Config) setValue(key string, value string) error {
+	var err error
+	switch key {
+	case "consistency":
+		consistency, ok := DbConsistencyLevels[value]
+		if !ok {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.Consistency = consistency
+	case "keyspace":
+		if value == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.Keyspace = value
+	case "timeout":
+		var data time.Duration
+		data, err = time.ParseDuration(value)
+		if data >= 0 {
+			config.Timeout = data
+		}
+	case "connectTimeout":
+		var data time.Duration
+		data, err = time.ParseDuration(value)
+		if data >= 0 {
+			config.ConnectTimeout = data
+		}
+	case "defaultQueryTimeout":
+		config.DefaultQueryTimeout, err = time.ParseDuration(value)
+	case "numConns":
+		var data int
+		data, err = strconv.Atoi(value)
+		if data > 0 {
+			config.NumConns = data
+		}
+	case "maxPreparedStmts":
+		config.MaxPreparedStmts, err = strconv.Atoi(value)
+	case "ignorePeerAddr":
+		config.IgnorePeerAddr, err = strconv.ParseBool(value)
+	case "disableInitialHostLookup":
+		config.DisableInitialHostLookup, err = strconv.ParseBool(value)
+	case "writeCoalesceWaitTime":
+		config.WriteCoalesceWaitTime, err = time.ParseDuration(value)
+	case "protoVersion":
+		config.ProtoVersion, err = strconv.Atoi(value)
+	case "cqlVersion":
+		config.CQLVersion = value
+	case "port":
+		config.Port, err = strconv.Atoi(value)
+	case "compression":
+		if value == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.Compression = value
+	case "hostSelection":
+		if value == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.HostSelection = value
+	case "localDC":
+		config.LocalDC, err = url.QueryUnescape(value)
+		if config.LocalDC == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case "shuffleReplicas":
+		config.ShuffleReplicas, err = strconv.ParseBool(value)
+	case "retryPolicy":
+		if value == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.RetryPolicy = value
+	case "retries":
+		config.Retries, err = strconv.Atoi(value)
+		if config.Retries < 0 {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case "retryMin":
+		config.RetryMin, err = time.ParseDuration(value)
+		if config.RetryMin < 0 {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case "retryMax":
+		config.RetryMax, err = time.ParseDuration(value)
+		if config.RetryMax < 0 {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case "retryConsistencies":
+		config.RetryConsistencies = nil
+		for _, name := range strings.Split(value, ",") {
+			consistency, ok := DbConsistencyLevels[strings.TrimSpace(name)]
+			if !ok {
+				return fmt.Errorf("failed for: %v = %v", key, value)
+			}
+			config.RetryConsistencies = append(config.RetryConsistencies, consistency)
+		}
+	case "reconnectPolicy":
+		if value == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.ReconnectPolicy = value
+	case "reconnectRetries":
+		config.ReconnectRetries, err = strconv.Atoi(value)
+		if config.ReconnectRetries < 0 {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case "reconnectInterval":
+		config.ReconnectInterval, err = time.ParseDuration(value)
+		if config.ReconnectInterval < 0 {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case "reconnectMaxInterval":
+		config.ReconnectMaxInterval, err = time.ParseDuration(value)
+		if config.ReconnectMaxInterval < 0 {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case "username":
+		config.Username, err = url.QueryUnescape(value)
+		config.emptyPasswordAuthenticator = config.emptyPasswordAuthenticator || value == ""
+	case "password":
+		config.Password, err = url.QueryUnescape(value)
+		if err != nil {
+			// do not echo secret values
+			return fmt.Errorf("failed for: %v", key)
+		}
+		config.emptyPasswordAuthenticator = config.emptyPasswordAuthenticator || value == ""
+	case "enableHostVerification":
+		config.EnableHostVerification, err = strconv.ParseBool(value)
+		config.emptySslOptions = config.emptySslOptions || (err == nil && !config.EnableHostVerification)
+	case "certPath":
+		config.CertPath, err = url.QueryUnescape(value)
+		config.emptySslOptions = config.emptySslOptions || value == ""
+	case "keyPath":
+		config.KeyPath, err = url.QueryUnescape(value)
+		if err != nil {
+			// do not echo secret values
+			return fmt.Errorf("failed for: %v", key)
+		}
+		config.emptySslOptions = config.emptySslOptions || value == ""
+	case "caPath":
+		config.CaPath, err = url.QueryUnescape(value)
+		config.emptySslOptions = config.emptySslOptions || value == ""
+	case "tls":
+		config.TLS, err = strconv.ParseBool(value)
+	case "tlsServerName":
+		config.TLSServerName, err = url.QueryUnescape(value)
+		if config.TLSServerName == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case "tlsMinVersion":
+		if value == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.TLSMinVersion = value
+	case "tlsInsecureSkipVerify":
+		config.TLSInsecureSkipVerify, err = strconv.ParseBool(value)
+	case "tlsConfig":
+		if value == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.TLSConfig = value
+	case "auth":
+		if value == "" {
+			return fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		config.Auth = value
+	case "trace":
+		config.Trace, err = strconv.ParseBool(value)
+	default:
+		if !strings.HasPrefix(key, "auth.") || len(key) < 6 {
+			return fmt.Errorf("invalid key: %v", key)
+		}
+		data, err := url.QueryUnescape(value)
+		if err != nil {
+			// auth params are often secret
+			return fmt.Errorf("failed for: %v", key)
+		}
+		if config.AuthParams == nil {
+			config.AuthParams = make(map[string]string)
+		}
+		config.AuthParams[key[5:]] = data
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed for: %v = %v", key, value)
+	}
+	return nil
+}
+
+// Validate checks the config values and returns ConfigErrors with all the problems found or nil
+func (config *Config) Validate() error {
+	return config.validate(true)
+}
+
+// validate checks the config values, the strict checks are only skipped by ConfigStringToClusterConfig
+// which keeps accepting the config strings it accepted before the Config API
+func (config *Config) validate(strict bool) error {
+	var configErrors ConfigErrors
+	failed := func(key string, value interface{}) {
+		configErrors = append(configErrors, fmt.Errorf("failed for: %v = %v", key, value))
+	}
+
+	if len(config.Hosts) < 1 {
+		configErrors = append(configErrors, fmt.Errorf("hosts is empty"))
+	}
+	if strict {
+		for _, host := range config.Hosts {
+			if host == "" || strings.ContainsAny(host, ",?&") {
+				failed("host", host)
+			}
+		}
+	}
+	if strings.ContainsAny(config.Keyspace, "&") {
+		failed("keyspace", config.Keyspace)
+	}
+	if _, ok := DbConsistency[config.Consistency]; !ok {
+		failed("consistency", config.Consistency)
+	}
+	if config.Timeout < 0 {
+		failed("timeout", config.Timeout)
+	}
+	if config.ConnectTimeout < 0 {
+		failed("connectTimeout", config.ConnectTimeout)
+	}
+	if config.DefaultQueryTimeout < 0 {
+		failed("defaultQueryTimeout", config.DefaultQueryTimeout)
+	}
+	if config.NumConns < 1 {
+		failed("numConns", config.NumConns)
+	}
+	if strict && config.WriteCoalesceWaitTime < 0 {
+		failed("writeCoalesceWaitTime", config.WriteCoalesceWaitTime)
+	}
+	if config.ProtoVersion < 0 || config.ProtoVersion > 4 {
+		failed("protoVersion", config.ProtoVersion)
+	}
+	if !cqlVersionRegexp.MatchString(config.CQLVersion) {
+		failed("cqlVersion", config.CQLVersion)
+	}
+	if config.Port < 1 || config.Port > 65535 {
+		failed("port", config.Port)
+	}
+	if config.MaxPreparedStmts < 1 {
+		failed("maxPreparedStmts", config.MaxPreparedStmts)
+	}
+	switch config.Compression {
+	case "", "none", "snappy", "lz4":
+	default:
+		failed("compression", config.Compression)
+	}
+
+	switch config.HostSelection {
+	case "", "roundRobin", "dcAwareRoundRobin", "tokenAware":
+		if config.HostSelection != "" || config.LocalDC != "" || config.ShuffleReplicas {
+			_, err := newHostSelectionPolicy(config.HostSelection, config.LocalDC, config.ShuffleReplicas)
+			if err != nil {
+				configErrors = append(configErrors, err)
+			}
+		}
+	default:
+		failed("hostSelection", config.HostSelection)
+	}
+
+	for _, consistency := range config.RetryConsistencies {
+		if _, ok := DbConsistency[consistency]; !ok {
+			failed("retryConsistencies", consistency)
+		}
+	}
+	switch config.RetryPolicy {
+	case "", "simple", "exponential", "downgradingConsistency":
+		if config.RetryPolicy != "" || config.Retries >= 0 || config.RetryMin >= 0 || config.RetryMax >= 0 || config.RetryConsistencies != nil {
+			_, err := newRetryPolicy(config.RetryPolicy, config.Retries, config.RetryMin, config.RetryMax, config.RetryConsistencies)
+			if err != nil {
+				configErrors = append(configErrors, err)
+			}
+		}
+	default:
+		failed("retryPolicy", config.RetryPolicy)
+	}
+
+	switch config.ReconnectPolicy {
+	case "", "constant", "exponential":
+		if config.ReconnectPolicy != "" || config.ReconnectRetries >= 0 || config.ReconnectInterval >= 0 || config.ReconnectMaxInterval >= 0 {
+			_, err := newReconnectionPolicy(config.ReconnectPolicy, config.ReconnectRetries, config.ReconnectInterval, config.ReconnectMaxInterval)
+			if err != nil {
+				configErrors = append(configErrors, err)
+			}
+		}
+	default:
+		failed("reconnectPolicy", config.ReconnectPolicy)
+	}
+
+	if strict && config.CertPath != "" && config.KeyPath == "" {
+		configErrors = append(configErrors, fmt.Errorf("certPath requires keyPath"))
+	}
+	if strict && config.KeyPath != "" && config.CertPath == "" {
+		configErrors = append(configErrors, fmt.Errorf("keyPath requires certPath"))
+	}
+
+	if !config.TLS && config.hasTLSSettings() {
+		configErrors = append(configErrors, fmt.Errorf("tls settings require tls=true"))
+	}
+	if _, ok := TLSVersionNames[config.TLSMinVersion]; !ok && config.TLSMinVersion != "" {
+		failed("tlsMinVersion", config.TLSMinVersion)
+	}
+	if config.TLS || config.TLSConfig != "" {
+		_, err := newTLSConfig(config.TLSConfig, config.TLSServerName, TLSVersionNames[config.TLSMinVersion], config.TLSInsecureSkipVerify)
+		if err != nil {
+			configErrors = append(configErrors, err)
+		}
+	}
+	if config.TLSInsecureSkipVerify && config.EnableHostVerification {
+		// gocql sets InsecureSkipVerify to the inverse of EnableHostVerification
+		configErrors = append(configErrors, fmt.Errorf("tlsInsecureSkipVerify conflicts with enableHostVerification"))
+	}
+
+	if config.Auth != "" {
+		authenticatorRegistry.mutex.RLock()
+		_, ok := authenticatorRegistry.factories[config.Auth]
+		authenticatorRegistry.mutex.RUnlock()
+		if !ok {
+			failed("auth", config.Auth)
+		}
+		if config.Username != "" || config.Password != "" || config.emptyPasswordAuthenticator {
+			configErrors = append(configErrors, fmt.Errorf("auth can not be used with username or password"))
+		} else if ok {
+			_, err := newAuthenticator(config.Auth, config.AuthParams)
+			if err != nil {
+				configErrors = append(configErrors, err)
+			}
+		}
+	} else if len(config.AuthParams) > 0 {
+		configErrors = append(configErrors, fmt.Errorf("auth settings require auth"))
+	}
+
+	if len(configErrors) > 0 {
+		return configErrors
+	}
+	return nil
+}
+
+// FormatDSN returns the config string of the config, only values that are not the default are included.
+// The password and auth params are not masked, use RedactedConfigString of the ClusterConfig for logging.
+func (config *Config) FormatDSN() string {
+	configDefault := NewConfig()
+	stringConfig := strings.Join(config.Hosts, ",") + "?"
+
+	if config.Consistency != configDefault.Consistency {
+		stringConfig += "consistency=" + DbConsistency[config.Consistency] + "&"
+	}
+	if config.Timeout != configDefault.Timeout {
+		stringConfig += "timeout=" + config.Timeout.String() + "&"
+	}
+	if config.ConnectTimeout != configDefault.ConnectTimeout {
+		stringConfig += "connectTimeout=" + config.ConnectTimeout.String() + "&"
+	}
+	if config.DefaultQueryTimeout != configDefault.DefaultQueryTimeout {
+		stringConfig += "defaultQueryTimeout=" + config.DefaultQueryTimeout.String() + "&"
+	}
+	if config.Keyspace != "" {
+		stringConfig += "keyspace=" + config.Keyspace + "&"
+	}
+	if config.NumConns != configDefault.NumConns {
+		stringConfig += "numConns=" + strconv.FormatInt(int64(config.NumConns), 10) + "&"
+	}
+	if config.IgnorePeerAddr {
+		stringConfig += "ignorePeerAddr=true&"
+	}
+	if config.DisableInitialHostLookup {
+		stringConfig += "disableInitialHostLookup=true&"
+	}
+	if config.WriteCoalesceWaitTime != configDefault.WriteCoalesceWaitTime {
+		stringConfig += "writeCoalesceWaitTime=" + config.WriteCoalesceWaitTime.String() + "&"
+	}
+	if config.ProtoVersion != configDefault.ProtoVersion {
+		stringConfig += "protoVersion=" + strconv.FormatInt(int64(config.ProtoVersion), 10) + "&"
+	}
+	if config.CQLVersion != configDefault.CQLVersion {
+		stringConfig += "cqlVersion=" + config.CQLVersion + "&"
+	}
+	if config.Port != configDefault.Port {
+		stringConfig += "port=" + strconv.FormatInt(int64(config.Port), 10) + "&"
+	}
+	if config.MaxPreparedStmts != configDefault.MaxPreparedStmts {
+		stringConfig += "maxPreparedStmts=" + strconv.FormatInt(int64(config.MaxPreparedStmts), 10) + "&"
+	}
+	if config.Compression != "" {
+		stringConfig += "compression=" + config.Compression + "&"
+	}
+
+	if config.HostSelection != "" {
+		stringConfig += "hostSelection=" + config.HostSelection + "&"
+	}
+	if config.LocalDC != "" {
+		stringConfig += "localDC=" + url.QueryEscape(config.LocalDC) + "&"
+	}
+	if config.ShuffleReplicas {
+		stringConfig += "shuffleReplicas=true&"
+	}
+
+	if config.RetryPolicy != "" {
+		stringConfig += "retryPolicy=" + config.RetryPolicy + "&"
+	}
+	if config.Retries >= 0 {
+		stringConfig += "retries=" + strconv.FormatInt(int64(config.Retries), 10) + "&"
+	}
+	if config.RetryMin >= 0 {
+		stringConfig += "retryMin=" + config.RetryMin.String() + "&"
+	}
+	if config.RetryMax >= 0 {
+		stringConfig += "retryMax=" + config.RetryMax.String() + "&"
+	}
+	if config.RetryConsistencies != nil {
+		consistencies := make([]string, len(config.RetryConsistencies))
+		for i, consistency := range config.RetryConsistencies {
+			consistencies[i] = DbConsistency[consistency]
+		}
+		stringConfig += "retryConsistencies=" + strings.Join(consistencies, ",") + "&"
+	}
+
+	if config.ReconnectPolicy != "" {
+		stringConfig += "reconnectPolicy=" + config.ReconnectPolicy + "&"
+	}
+	if config.ReconnectRetries >= 0 {
+		stringConfig += "reconnectRetries=" + strconv.FormatInt(int64(config.ReconnectRetries), 10) + "&"
+	}
+	if config.ReconnectInterval >= 0 {
+		stringConfig += "reconnectInterval=" + config.ReconnectInterval.String() + "&"
+	}
+	if config.ReconnectMaxInterval >= 0 {
+		stringConfig += "reconnectMaxInterval=" + config.ReconnectMaxInterval.String() + "&"
+	}
+
+	if config.Username != "" {
+		stringConfig += "username=" + url.QueryEscape(config.Username) + "&"
+	}
+	if config.Password != "" {
+		stringConfig += "password=" + url.QueryEscape(config.Password) + "&"
+	}
+
+	if config.EnableHostVerification {
+		stringConfig += "enableHostVerification=true&"
+	}
+	if config.KeyPath != "" {
+		stringConfig += "keyPath=" + url.QueryEscape(config.KeyPath) + "&"
+	}
+	if config.CertPath != "" {
+		stringConfig += "certPath=" + url.QueryEscape(config.CertPath) + "&"
+	}
+	if config.CaPath != "" {
+		stringConfig += "caPath=" + url.QueryEscape(config.CaPath) + "&"
+	}
+	if config.TLS {
+		stringConfig += "tls=true&"
+	}
+	if config.TLSServerName != "" {
+		stringConfig += "tlsServerName=" + url.QueryEscape(config.TLSServerName) + "&"
+	}
+	if config.TLSMinVersion != "" {
+		stringConfig += "tlsMinVersion=" + config.TLSMinVersion + "&"
+	}
+	if config.TLSInsecureSkipVerify {
+		stringConfig += "tlsInsecureSkipVerify=true&"
+	}
+	if config.TLSConfig != "" {
+		stringConfig += "tlsConfig=" + config.TLSConfig + "&"
+	}
+	if config.Auth != "" {
+		stringConfig += "auth=" + config.Auth + "&"
+	}
+	if len(config.AuthParams) > 0 {
+		stringConfig += authParamsToConfig(config.AuthParams) + "&"
+	}
+	if config.Trace {
+		stringConfig += "trace=true&"
+	}
+
+	return stringConfig[:len(stringConfig)-1]
+}
+
+// ClusterConfig validates the config and returns a gocql ClusterConfig for it.
+// DefaultQueryTimeout and Trace are connection settings, they are not part of the ClusterConfig.
+func (config *Config) ClusterConfig() (*gocql.ClusterConfig, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config.newClusterConfig()
+}
+
+// configStringClusterConfig returns a gocql ClusterConfig with the ConfigStringToClusterConfig checks,
+// used by the driver Open and OpenConnector so they accept the same config strings
+func (config *Config) configStringClusterConfig() (*gocql.ClusterConfig, error) {
+	err := config.validate(false)
+	if err != nil {
+		return nil, err
+	}
+	return config.newClusterConfig()
+}
+
+// newClusterConfig returns a gocql ClusterConfig for a validated config
+func (config *Config) newClusterConfig() (*gocql.ClusterConfig, error) {
+	clusterConfig := NewClusterConfig(config.Hosts...)
+	clusterConfig.Keyspace = config.Keyspace
+	clusterConfig.Consistency = config.Consistency
+	clusterConfig.Timeout = config.Timeout
+	clusterConfig.ConnectTimeout = config.ConnectTimeout
+	clusterConfig.NumConns = config.NumConns
+	clusterConfig.IgnorePeerAddr = config.IgnorePeerAddr
+	clusterConfig.DisableInitialHostLookup = config.DisableInitialHostLookup
+	clusterConfig.WriteCoalesceWaitTime = config.WriteCoalesceWaitTime
+	clusterConfig.ProtoVersion = config.ProtoVersion
+	clusterConfig.CQLVersion = config.CQLVersion
+	clusterConfig.Port = config.Port
+	clusterConfig.MaxPreparedStmts = config.MaxPreparedStmts
+
+	switch config.Compression {
+	case "snappy":
+		clusterConfig.Compressor = gocql.SnappyCompressor{}
+	case "lz4":
+		clusterConfig.Compressor = LZ4Compressor{}
+	}
+
+	var err error
+	if config.HostSelection != "" || config.LocalDC != "" || config.ShuffleReplicas {
+		clusterConfig.PoolConfig.HostSelectionPolicy, err = newHostSelectionPolicy(config.HostSelection, config.LocalDC, config.ShuffleReplicas)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.RetryPolicy != "" || config.Retries >= 0 || config.RetryMin >= 0 || config.RetryMax >= 0 || config.RetryConsistencies != nil {
+		clusterConfig.RetryPolicy, err = newRetryPolicy(config.RetryPolicy, config.Retries, config.RetryMin, config.RetryMax, config.RetryConsistencies)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.ReconnectPolicy != "" || config.ReconnectRetries >= 0 || config.ReconnectInterval >= 0 || config.ReconnectMaxInterval >= 0 {
+		clusterConfig.ReconnectionPolicy, err = newReconnectionPolicy(config.ReconnectPolicy, config.ReconnectRetries, config.ReconnectInterval, config.ReconnectMaxInterval)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Auth != "" {
+		clusterConfig.Authenticator, err = newAuthenticator(config.Auth, config.AuthParams)
+		if err != nil {
+			return nil, err
+		}
+	} else if config.Username != "" || config.Password != "" || config.emptyPasswordAuthenticator {
+		clusterConfig.Authenticator = gocql.PasswordAuthenticator{Username: config.Username, Password: config.Password}
+	}
+
+	if config.TLS || config.EnableHostVerification || config.CertPath != "" || config.KeyPath != "" || config.CaPath != "" || config.emptySslOptions {
+		clusterConfig.SslOpts = &gocql.SslOptions{
+			EnableHostVerification: config.EnableHostVerification,
+			CertPath:               config.CertPath,
+			KeyPath:                config.KeyPath,
+			CaPath:                 config.CaPath,
+		}
+	}
+	if config.TLS {
+		clusterConfig.SslOpts.Config, err = newTLSConfig(config.TLSConfig, config.TLSServerName, TLSVersionNames[config.TLSMinVersion], config.TLSInsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		// gocql sets InsecureSkipVerify to the inverse of EnableHostVerification
+		clusterConfig.SslOpts.EnableHostVerification = !clusterConfig.SslOpts.InsecureSkipVerify
+	}
+
+	return clusterConfig, nil
+}
+
+// Error returns the problems separated by a semicolon
+func (configErrors ConfigErrors) Error() string {
+	messages := make([]string, len(configErrors))
+	for i, err := range configErrors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,74 @@
+package cql
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// expandValue returns the value of a ${env:NAME} or ${file:path} config string value when ExpandValues is enabled.
+// The value is returned escaped for the key.
+func expandValue(key string, value string) (string, error) {
+	if !ExpandValues || !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return value, nil
+	}
+
+	reference := value[2 : len(value)-1]
+	var data string
+	switch {
+	case strings.HasPrefix(reference, "env:"):
+		var ok bool
+		data, ok = os.LookupEnv(strings.TrimPrefix(reference, "env:"))
+		if !ok {
+			return "", fmt.Errorf("failed for: %v = %v", key, value)
+		}
+	case strings.HasPrefix(reference, "file:"):
+		bytes, err := ioutil.ReadFile(strings.TrimPrefix(reference, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("failed for: %v = %v", key, value)
+		}
+		// secret files usually end with a new line
+		data = strings.TrimRight(string(bytes), "\r\n")
+	default:
+		return value, nil
+	}
+
+	return escapeValue(key, data), nil
+}
+
+// escapeValue returns the value query escaped if the key has query escaped values
+func escapeValue(key string, value string) string {
+	if _, ok := queryEscapedKeys[key]; ok || strings.HasPrefix(key, "auth.") {
+		return url.QueryEscape(value)
+	}
+	return value
+}
+
+// configKeyToEnv returns the CQL_ environment variable name of a config string key, numConns is CQL_NUM_CONNS
+func configKeyToEnv(key string) string {
+	name := "CQL_"
+	runes := []rune(key)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && !unicode.IsUpper(runes[i-1]) {
+			name += "_"
+		}
+		name += string(unicode.ToUpper(r))
+	}
+	return name
+}
+
+// envConfigString returns the config string built from the CQL_ environment variables
+func envConfigString() string {
+	configString := os.Getenv("CQL_HOSTS") + "?"
+	for _, key := range configKeys {
+		value, ok := os.LookupEnv(configKeyToEnv(key))
+		if !ok {
+			continue
+		}
+		configString += key + "=" + escapeValue(key, value) + "&"
+	}
+	return configString[:len(configString)-1]
+}
@@ -0,0 +1,93 @@
+package cql
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+func TestConfigKeyToEnv(t *testing.T) {
+	tests := map[string]string{
+		"keyspace":                 "CQL_KEYSPACE",
+		"numConns":                 "CQL_NUM_CONNS",
+		"localDC":                  "CQL_LOCAL_DC",
+		"disableInitialHostLookup": "CQL_DISABLE_INITIAL_HOST_LOOKUP",
+		"tlsServerName":            "CQL_TLS_SERVER_NAME",
+	}
+	for key, expected := range tests {
+		name := configKeyToEnv(key)
+		if name != expected {
+			t.Errorf("configKeyToEnv - received: %v - expected: %v - info: %v", name, expected, key)
+		}
+	}
+}
+
+func TestExpandValues(t *testing.T) {
+	directory, err := ioutil.TempDir("", "cql")
+	if err != nil {
+		t.Fatalf("TempDir error - received: %v - expected: %v ", err, nil)
+	}
+	defer os.RemoveAll(directory)
+	secretFile := filepath.Join(directory, "secret")
+	err = ioutil.WriteFile(secretFile, []byte("file%se&cret+\n"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile error - received: %v - expected: %v ", err, nil)
+	}
+	os.Setenv("CQL_TEST_PASSWORD", "env%se&cret+")
+	defer os.Unsetenv("CQL_TEST_PASSWORD")
+	os.Setenv("CQL_TEST_CONSISTENCY", "one")
+	defer os.Unsetenv("CQL_TEST_CONSISTENCY")
+
+	ExpandValues = true
+	defer func() { ExpandValues = false }()
+
+	tests := []TestStringToConfigStruct{
+		{info: "env", configString: "?consistency=${env:CQL_TEST_CONSISTENCY}&password=${env:CQL_TEST_PASSWORD}", clusterConfig: cfgWith(func(cfg *gocql.ClusterConfig) {
+			cfg.Consistency = gocql.One
+			cfg.Authenticator = gocql.PasswordAuthenticator{Password: "env%se&cret+"}
+		})},
+		{info: "file", configString: "?username=alice&password=${file:" + secretFile + "}", clusterConfig: cfgWithAuth(gocql.PasswordAuthenticator{Username: "alice", Password: "file%se&cret+"})},
+		{info: "not a reference", configString: "?password=${other:foo}", clusterConfig: cfgWithAuth(gocql.PasswordAuthenticator{Password: "${other:foo}"})},
+		{info: "missing env", configString: "?password=${env:CQL_TEST_MISSING}", err: fmt.Errorf("failed for: password = ${env:CQL_TEST_MISSING}")},
+		{info: "missing file", configString: "?password=${file:" + secretFile + "missing}", err: fmt.Errorf("failed for: password = ${file:" + secretFile + "missing}")},
+	}
+
+	for _, test := range tests {
+		clusterConfig, err := ConfigStringToClusterConfig(test.configString)
+		if err == nil || test.err == nil {
+			if err != test.err {
+				t.Errorf("error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+				continue
+			}
+		} else if err.Error() != test.err.Error() {
+			t.Errorf("error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+			continue
+		}
+		if !reflect.DeepEqual(clusterConfig, test.clusterConfig) {
+			t.Errorf("clusterConfig - received: %#v - expected: %#v - info: %v", clusterConfig, test.clusterConfig, test.info)
+		}
+	}
+
+	config, err := ParseConfig("?password=${file:" + secretFile + "}")
+	if err != nil {
+		t.Fatalf("ParseConfig error - received: %v - expected: %v ", err, nil)
+	}
+	if config.Password != "file%se&cret+" {
+		t.Fatalf("Password - received: %v - expected: %v ", config.Password, "file%se&cret+")
+	}
+
+	ExpandValues = false
+	clusterConfig, err := ConfigStringToClusterConfig("?username=${env:CQL_TEST_PASSWORD}")
+	if err != nil {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, nil)
+	}
+	expected := gocql.PasswordAuthenticator{Username: "${env:CQL_TEST_PASSWORD}"}
+	if clusterConfig.Authenticator != expected {
+		t.Fatalf("Authenticator - received: %v - expected: %v ", clusterConfig.Authenticator, expected)
+	}
+}
@@ -1,11 +1,17 @@
 package cql
 
 import (
+	"container/list"
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"sync"
+	"time"
 
 	"github.com/gocql/gocql"
 )
@@ -24,14 +30,120 @@ type (
 		// ClusterConfig is used for changing config options
 		// https://godoc.org/github.com/gocql/gocql#ClusterConfig
 		ClusterConfig *gocql.ClusterConfig
+		// DefaultQueryTimeout is the timeout for queries whose context has no deadline, 0 is no timeout.
+		// Set by the defaultQueryTimeout config string key.
+		DefaultQueryTimeout time.Duration
+		// Trace enables tracing of every query, the traces are logged with Logger
+		// unless the query context has a collector set by WithTrace. Set by the trace config string key.
+		Trace bool
 	}
 
 	cqlConnStruct struct {
-		logger        *log.Logger
-		clusterConfig *gocql.ClusterConfig
+		logger              *log.Logger
+		clusterConfig       *gocql.ClusterConfig
+		defaultQueryTimeout time.Duration
+		trace               bool
+		// context is owned by the connection and canceled on Close, it is used when database/sql does not pass one
 		context       context.Context
+		cancel        context.CancelFunc
+		sharedSession *sharedSessionStruct
 		session       *gocql.Session
 		pingQuery     *gocql.Query
+		tx            *cqlTxStruct
+		// useConfig is a copy of clusterConfig with the keyspace of a use statement, nil when the keyspace was not changed
+		useConfig *gocql.ClusterConfig
+	}
+
+	// sharedSessionStruct is a gocql session shared by all connections with the same config string, key is the sessionKey
+	sharedSessionStruct struct {
+		key      string
+		refs     int
+		ready    chan struct{}
+		session  *gocql.Session
+		err      error
+		prepared *preparedCacheStruct
+	}
+
+	// preparedCacheStruct is a least recently used cache of prepared statement metadata
+	preparedCacheStruct struct {
+		mutex   sync.Mutex
+		maxSize int
+		maxAge  time.Duration
+		list    *list.List
+		entries map[string]*list.Element
+	}
+
+	// preparedEntryStruct is a prepared cache entry
+	preparedEntryStruct struct {
+		statement string
+		queryInfo *gocql.QueryInfo
+		added     time.Time
+	}
+
+	// preparedPolicyStruct wraps the session host selection policy to clear the prepared cache on keyspace schema change events,
+	// gocql only passes keyspace events to the policy
+	preparedPolicyStruct struct {
+		gocql.HostSelectionPolicy
+		prepared *preparedCacheStruct
+	}
+
+	// AuthenticatorFactory returns a gocql Authenticator for the auth config string parameters.
+	// The params keys are the auth.name keys without the auth. prefix.
+	AuthenticatorFactory func(params map[string]string) (gocql.Authenticator, error)
+
+	authenticatorRegistryStruct struct {
+		mutex     sync.RWMutex
+		factories map[string]AuthenticatorFactory
+	}
+
+	// registeredAuthenticatorStruct is an Authenticator created by a registered AuthenticatorFactory,
+	// it keeps the name and params for the config string
+	registeredAuthenticatorStruct struct {
+		gocql.Authenticator
+		name   string
+		params map[string]string
+	}
+
+	// plainAuthenticatorStruct is a SASL PLAIN Authenticator that only answers allowed server authenticators
+	plainAuthenticatorStruct struct {
+		allowed     []string
+		credentials func() (username string, password string, err error)
+	}
+
+	// hostSelectionPolicyStruct is a gocql host selection policy built from config values,
+	// the values are kept so the policy can be converted back to a config string
+	hostSelectionPolicyStruct struct {
+		gocql.HostSelectionPolicy
+		hostSelection   string
+		localDC         string
+		shuffleReplicas bool
+	}
+
+	// hostTLSDialerStruct dials with tls, verifying every host against the host name or address it is dialed with
+	hostTLSDialerStruct struct {
+		dialer    gocql.Dialer
+		tlsConfig *tls.Config
+	}
+
+	// tlsSettingsStruct are the tls config string settings a tls config was built from
+	tlsSettingsStruct struct {
+		name               string
+		serverName         string
+		minVersion         uint16
+		insecureSkipVerify bool
+	}
+
+	tlsConfigRegistryStruct struct {
+		mutex   sync.RWMutex
+		configs map[string]*tls.Config
+		// built are the tls configs built from tls settings, settings is the reverse so config strings can be recreated
+		built    map[tlsSettingsStruct]*tls.Config
+		settings map[*tls.Config]tlsSettingsStruct
+	}
+
+	sessionRegistryStruct struct {
+		mutex    sync.Mutex
+		sessions map[string]*sharedSessionStruct
 	}
 
 	// CqlStmt is the sql driver statement
@@ -40,17 +152,238 @@ type (
 		// https://godoc.org/github.com/gocql/gocql#Query
 		// This will only work if Go sql every gives access to the driver
 		CqlQuery *gocql.Query
+		// QueryInfo is the prepared statement metadata, the bind markers and result columns.
+		// It is nil when the statement type can not be prepared.
+		QueryInfo   *gocql.QueryInfo
+		session     *gocql.Session
+		conn        *cqlConnStruct
+		numInput    int
+		conditional bool
+	}
+
+	// cqlTxStruct is a transaction that buffers statements and sends them as a batch on commit
+	cqlTxStruct struct {
+		conn    *cqlConnStruct
+		session *gocql.Session
+		batch   *gocql.Batch
+		binders []*namedBinderStruct
+	}
+
+	// namedBinderStruct binds named values to the bind markers of a prepared statement
+	namedBinderStruct struct {
+		namedValues []driver.NamedValue
+		err         error
+	}
+
+	queryOptionsKey struct{}
+
+	// queryOptionsStruct holds the per query options set with the With context functions
+	queryOptionsStruct struct {
+		consistency       *gocql.Consistency
+		serialConsistency *gocql.SerialConsistency
+		pageSize          *int
+		idempotent        *bool
+		timestamp         *int64
+		batchType         *gocql.BatchType
+		casResult         *CASResult
+		pageState         *[]byte
+		pageResult        *PageResult
+		traceCollector    *TraceCollector
+	}
+
+	// CASResult holds the result of a lightweight transaction, an insert, update, or delete with an if clause.
+	// Use WithCASResult to have it filled in when the statement is executed.
+	CASResult struct {
+		// Applied is true if the lightweight transaction was applied
+		Applied bool
+		// Existing holds the existing row values when the lightweight transaction was not applied
+		Existing map[string]interface{}
+	}
+
+	// TraceCollector is called with the trace of a query traced with WithTrace or the trace config string key
+	TraceCollector func(trace *Trace)
+
+	// Trace is a query tracing session read from system_traces
+	Trace struct {
+		// SessionID is the tracing session id
+		SessionID gocql.UUID
+		// Coordinator is the address of the coordinator node
+		Coordinator string
+		// Client is the address of the client
+		Client string
+		// Command is the type of request, for example QUERY
+		Command string
+		// Request is the request description
+		Request string
+		// Parameters are the request parameters, for example the query
+		Parameters map[string]string
+		// StartedAt is when the request started
+		StartedAt time.Time
+		// Duration is how long the request took on the coordinator
+		Duration time.Duration
+		// Events are the tracing events in order
+		Events []TraceEvent
+	}
+
+	// TraceEvent is an event of a tracing session
+	TraceEvent struct {
+		// EventID is the time based id of the event
+		EventID gocql.UUID
+		// Time is the time of the event
+		Time time.Time
+		// Activity is the description of the event
+		Activity string
+		// Source is the address of the node of the event
+		Source string
+		// SourceElapsed is the time since the request started on the source node
+		SourceElapsed time.Duration
+		// Thread is the name of the thread of the event
+		Thread string
+	}
+
+	// tracerStruct is a gocql Tracer that reads the trace from system_traces and passes it to the collector
+	tracerStruct struct {
+		ctx       context.Context
+		session   *gocql.Session
+		logger    *log.Logger
+		collector TraceCollector
+	}
+
+	// PageResult holds the paging state of a query, use WithPageResult to have it filled in when the rows are closed
+	PageResult struct {
+		// PageState is the paging state of the next page, it is empty when there are no more pages
+		PageState []byte
 	}
 
 	cqlResultStruct struct {
+		conditional  bool
+		rowsAffected int64
 	}
 
 	cqlRowsStruct struct {
-		iter    *gocql.Iter
-		columns []string
+		iter       *gocql.Iter
+		columns    []string
+		columnInfo []gocql.ColumnInfo
+		cancel     context.CancelFunc
+		pageResult *PageResult
 	}
 
 	converter struct{}
+
+	// List is a CQL list value that can be used as a query value and as a scan destination
+	List []interface{}
+
+	// Set is a CQL set value that can be used as a query value and as a scan destination
+	Set []interface{}
+
+	// Map is a CQL map value that can be used as a query value and as a scan destination
+	Map map[interface{}]interface{}
+
+	// Tuple is a CQL tuple value that can be used as a query value and as a scan destination
+	Tuple []interface{}
+
+	// UDT is a CQL user defined type value, field name to value,
+	// that can be used as a query value and as a scan destination
+	UDT map[string]interface{}
+
+	// Config is a typed config string, every config string key has a field.
+	// Use NewConfig or ParseConfig to get a Config with the defaults set.
+	Config struct {
+		// Hosts are the cluster hosts, host or host:port
+		Hosts []string
+		// Keyspace is the initial keyspace, keyspace
+		Keyspace string
+		// Consistency is the default consistency, consistency
+		Consistency gocql.Consistency
+		// Timeout is the query timeout, timeout
+		Timeout time.Duration
+		// ConnectTimeout is the initial connection timeout, connectTimeout
+		ConnectTimeout time.Duration
+		// DefaultQueryTimeout is the timeout for queries whose context has no deadline, defaultQueryTimeout
+		DefaultQueryTimeout time.Duration
+		// Trace enables tracing of every query, trace
+		Trace bool
+		// NumConns is the number of connections per host, numConns
+		NumConns int
+		// IgnorePeerAddr is ignorePeerAddr
+		IgnorePeerAddr bool
+		// DisableInitialHostLookup is disableInitialHostLookup
+		DisableInitialHostLookup bool
+		// WriteCoalesceWaitTime is writeCoalesceWaitTime
+		WriteCoalesceWaitTime time.Duration
+		// ProtoVersion is the native protocol version, 0 is discover, protoVersion
+		ProtoVersion int
+		// CQLVersion is cqlVersion
+		CQLVersion string
+		// Port is the default port, port
+		Port int
+		// Compression is snappy, lz4, none, or empty for none, compression
+		Compression string
+		// HostSelection is roundRobin, dcAwareRoundRobin, tokenAware, or empty, hostSelection
+		HostSelection string
+		// LocalDC is localDC
+		LocalDC string
+		// ShuffleReplicas is shuffleReplicas
+		ShuffleReplicas bool
+		// RetryPolicy is simple, exponential, downgradingConsistency, or empty, retryPolicy
+		RetryPolicy string
+		// Retries is retries, less than 0 uses the policy default
+		Retries int
+		// RetryMin is retryMin, less than 0 uses the policy default
+		RetryMin time.Duration
+		// RetryMax is retryMax, less than 0 uses the policy default
+		RetryMax time.Duration
+		// RetryConsistencies is retryConsistencies
+		RetryConsistencies []gocql.Consistency
+		// ReconnectPolicy is constant, exponential, or empty, reconnectPolicy
+		ReconnectPolicy string
+		// ReconnectRetries is reconnectRetries, less than 0 uses the policy default
+		ReconnectRetries int
+		// ReconnectInterval is reconnectInterval, less than 0 uses the policy default
+		ReconnectInterval time.Duration
+		// ReconnectMaxInterval is reconnectMaxInterval, less than 0 uses the policy default
+		ReconnectMaxInterval time.Duration
+		// Username is username
+		Username string
+		// Password is password
+		Password string
+		// EnableHostVerification is enableHostVerification
+		EnableHostVerification bool
+		// CertPath is certPath
+		CertPath string
+		// KeyPath is keyPath
+		KeyPath string
+		// CaPath is caPath
+		CaPath string
+		// TLS is tls, the other tls settings require it, a config string with tls settings sets it
+		TLS bool
+		// TLSServerName is tlsServerName, the server host name is verified against it unless TLSInsecureSkipVerify.
+		// Without it every host is verified against the host name or address it is dialed with.
+		TLSServerName string
+		// TLSMinVersion is 1.0, 1.1, 1.2, 1.3, or empty, tlsMinVersion
+		TLSMinVersion string
+		// TLSInsecureSkipVerify is tlsInsecureSkipVerify
+		TLSInsecureSkipVerify bool
+		// TLSConfig is the RegisterTLSConfig name, tlsConfig
+		TLSConfig string
+		// Auth is the RegisterAuthenticator name, auth
+		Auth string
+		// AuthParams are the auth.name params without the auth. prefix
+		AuthParams map[string]string
+		// MaxPreparedStmts is the size of the prepared statement caches, maxPreparedStmts
+		MaxPreparedStmts int
+
+		// emptyPasswordAuthenticator is set by an empty username or password, a PasswordAuthenticator is still used
+		emptyPasswordAuthenticator bool
+		// emptySslOptions is set by an empty or false ssl option, SslOptions are still used
+		emptySslOptions bool
+	}
+
+	// ConfigErrors is returned by Config Validate with all the problems found
+	ConfigErrors []error
+
+	// LZ4Compressor is a gocql Compressor for the native protocol lz4 frame compression
+	LZ4Compressor struct{}
 )
 
 var (
@@ -60,17 +393,128 @@ var (
 	ErrNotImplementedYet = fmt.Errorf("not implemented yet")
 	// ErrQueryIsNil is returned when a query is nil
 	ErrQueryIsNil = fmt.Errorf("query is nil")
-	// ErrNamedValuesNotSupported is returned when values are named. Named values are not supported.
+	// ErrNamedValuesNotSupported is returned when values are named but the statement can not be prepared.
+	// Only select, insert, update, delete, and batch statements support named values.
 	ErrNamedValuesNotSupported = fmt.Errorf("named values not supported")
+	// ErrNamedValueUnknown is returned when a named value does not match any statement bind marker
+	ErrNamedValueUnknown = fmt.Errorf("named value does not match a bind marker")
+	// ErrNamedValueMissing is returned when a statement bind marker does not have a value
+	ErrNamedValueMissing = fmt.Errorf("bind marker is missing a value")
+	// ErrPageStateInvalid is returned by DecodePageState when the page token is not an encoded paging state
+	ErrPageStateInvalid = fmt.Errorf("page state invalid")
+	// ErrTxStatementNotSupported is returned when a select or other non insert, update, or delete statement is used in a transaction
+	ErrTxStatementNotSupported = fmt.Errorf("transaction only supports insert, update, and delete statements")
+	// ErrUseKeyspaceMissing is returned when a use statement does not have a keyspace
+	ErrUseKeyspaceMissing = fmt.Errorf("use statement keyspace is missing")
+	// ErrUseNotSupported is returned for a use statement when the cluster config has settings the config string does not hold
+	ErrUseNotSupported = fmt.Errorf("use statement requires a cluster config without custom policies, authenticators, or tls configs")
 	// ErrOrdinalOutOfRange is returned when values ordinal is out of range
 	ErrOrdinalOutOfRange = fmt.Errorf("ordinal out of range")
+	// ErrTLSConfigNameInvalid is returned when registering a tls config with an empty or reserved name
+	ErrTLSConfigNameInvalid = fmt.Errorf("tls config name is empty or reserved")
+	// ErrTLSConfigIsNil is returned when registering a nil tls config
+	ErrTLSConfigIsNil = fmt.Errorf("tls config is nil")
+	// ErrAuthenticatorNameInvalid is returned when registering an authenticator with an empty name or a name with &, =, or ?
+	ErrAuthenticatorNameInvalid = fmt.Errorf("authenticator name is empty or invalid")
+	// ErrAuthenticatorFactoryIsNil is returned when registering a nil authenticator factory
+	ErrAuthenticatorFactoryIsNil = fmt.Errorf("authenticator factory is nil")
+	// ErrLZ4BodyTooShort is returned when a lz4 compressed frame body is missing the uncompressed length
+	ErrLZ4BodyTooShort = fmt.Errorf("lz4 body too short")
+
+	// errPrepared stops the query after the statement is prepared
+	errPrepared = fmt.Errorf("prepared")
 
 	// CqlDriver is the sql driver
 	CqlDriver = &CqlDriverStruct{
 		Logger: log.New(os.Stderr, "cql ", log.Ldate|log.Ltime|log.LUTC|log.Lshortfile),
 	}
+
+	// ExpandValues enables ${env:NAME} and ${file:path} config string values.
+	// It is off by default since the config string can then read any environment variable or file.
+	ExpandValues = false
+
+	// PreparedMaxAge is how long prepared statement metadata is cached before the statement is prepared on the cluster again.
+	// Table changes made by other clients are not sent to the driver, so a prepare can miss them for up to PreparedMaxAge.
+	// 0 or less caches the metadata until it is removed.
+	PreparedMaxAge = time.Minute
+
+	cqlVersionRegexp = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+	// configKeys are the config string keys, used for the CQL_ environment variables
+	configKeys = []string{
+		"consistency", "keyspace", "timeout", "connectTimeout", "defaultQueryTimeout", "numConns", "ignorePeerAddr", "disableInitialHostLookup", "writeCoalesceWaitTime",
+		"protoVersion", "cqlVersion", "port", "compression", "hostSelection", "localDC", "shuffleReplicas",
+		"retryPolicy", "retries", "retryMin", "retryMax", "retryConsistencies",
+		"reconnectPolicy", "reconnectRetries", "reconnectInterval", "reconnectMaxInterval",
+		"username", "password", "enableHostVerification", "certPath", "keyPath", "caPath",
+		"tls", "tlsServerName", "tlsMinVersion", "tlsInsecureSkipVerify", "tlsConfig", "auth", "maxPreparedStmts", "trace",
+	}
+
+	// queryEscapedKeys are the config string keys with query escaped values
+	queryEscapedKeys = map[string]struct{}{
+		"localDC": {}, "username": {}, "password": {}, "certPath": {}, "keyPath": {}, "caPath": {}, "tlsServerName": {},
+	}
+
+	tlsConfigRegistry = &tlsConfigRegistryStruct{
+		configs:  make(map[string]*tls.Config),
+		built:    make(map[tlsSettingsStruct]*tls.Config),
+		settings: make(map[*tls.Config]tlsSettingsStruct),
+	}
+
+	authenticatorRegistry = &authenticatorRegistryStruct{
+		factories: map[string]AuthenticatorFactory{
+			"password": passwordAuthenticatorFactory,
+		},
+	}
+
+	// DefaultAllowedAuthenticators are the server authenticator class names the built-in authenticators answer
+	// when the auth.allowed parameter is not set
+	DefaultAllowedAuthenticators = []string{
+		"org.apache.cassandra.auth.PasswordAuthenticator",
+		"com.instaclustr.cassandra.auth.SharedSecretAuthenticator",
+		"com.datastax.bdp.cassandra.auth.DseAuthenticator",
+		"io.aiven.cassandra.auth.AivenAuthenticator",
+		"com.ericsson.bss.cassandra.ecaudit.auth.AuditPasswordAuthenticator",
+		"com.amazon.helenus.auth.HelenusAuthenticator",
+		"com.ericsson.bss.cassandra.ecaudit.auth.AuditAuthenticator",
+		"com.scylladb.auth.TransitionalAuthenticator",
+		"com.scylladb.auth.SaslauthdAuthenticator",
+	}
+
+	sessionRegistry = &sessionRegistryStruct{
+		sessions: make(map[string]*sharedSessionStruct),
+	}
 )
 
+// Transaction isolation levels that can be used in sql.TxOptions to select the transaction batch type
+const (
+	// LevelLoggedBatch sends the transaction as a logged batch, same as sql.LevelDefault
+	LevelLoggedBatch = sql.IsolationLevel(100 + iota)
+	// LevelUnloggedBatch sends the transaction as an unlogged batch
+	LevelUnloggedBatch
+	// LevelCounterBatch sends the transaction as a counter batch
+	LevelCounterBatch
+)
+
+// redactedValue replaces secret values in RedactedConfigString
+const redactedValue = "xxxxx"
+
+// TLSVersionNames maps string to tls versions for the tlsMinVersion config string key
+var TLSVersionNames = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSVersions maps tls versions to string
+var TLSVersions = map[uint16]string{
+	tls.VersionTLS10: "1.0",
+	tls.VersionTLS11: "1.1",
+	tls.VersionTLS12: "1.2",
+	tls.VersionTLS13: "1.3",
+}
+
 // DbConsistencyLevels maps string to gocql consistency levels
 var DbConsistencyLevels = map[string]gocql.Consistency{
 	"any":         gocql.Any,
@@ -4,5 +4,6 @@ go 1.15
 
 require (
 	github.com/gocql/gocql v0.0.0-20200815110948-5378c8f664e9
-	github.com/golang/snappy v0.0.1 // indirect
+	github.com/golang/snappy v0.0.1
+	gopkg.in/inf.v0 v0.9.1
 )
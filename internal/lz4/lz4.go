@@ -0,0 +1,195 @@
+// Package lz4 implements the LZ4 block format used by the CQL native protocol lz4 compression
+package lz4
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	minMatch       = 4
+	lastLiterals   = 5
+	matchLimit     = 12
+	hashLog        = 16
+	maxOffset      = 65535
+	maxInputLength = 0x7E000000
+	// maxRatio is the most data a block byte can decode to, a length byte of 255 adds 255 bytes
+	maxRatio = 255
+)
+
+var (
+	// ErrCorrupt is returned when a block is not valid LZ4
+	ErrCorrupt = fmt.Errorf("lz4: corrupt input")
+	// ErrTooLarge is returned when the data is too large for a block
+	ErrTooLarge = fmt.Errorf("lz4: input too large")
+)
+
+// Encode returns the LZ4 block of src
+func Encode(src []byte) ([]byte, error) {
+	if len(src) > maxInputLength {
+		return nil, ErrTooLarge
+	}
+	dst := make([]byte, 0, len(src)+len(src)/255+16)
+
+	var table [1 << hashLog]int32
+	anchor := 0
+	position := 0
+	// the last match must start at least 12 bytes before the end and the last 5 bytes are always literals
+	limit := len(src) - matchLimit
+
+	for position < limit {
+		sequence := binary.LittleEndian.Uint32(src[position:])
+		hash := (sequence * 2654435761) >> (32 - hashLog)
+		candidate := int(table[hash]) - 1
+		table[hash] = int32(position + 1)
+
+		if candidate < 0 || position-candidate > maxOffset || binary.LittleEndian.Uint32(src[candidate:]) != sequence {
+			position++
+			continue
+		}
+
+		// extend the match backwards over pending literals
+		for position > anchor && candidate > 0 && src[position-1] == src[candidate-1] {
+			position--
+			candidate--
+		}
+
+		length := minMatch
+		for position+length < len(src)-lastLiterals && src[position+length] == src[candidate+length] {
+			length++
+		}
+
+		dst = appendSequence(dst, src[anchor:position], position-candidate, length)
+		position += length
+		anchor = position
+	}
+
+	return appendSequence(dst, src[anchor:], 0, 0), nil
+}
+
+// appendSequence appends a sequence of literals followed by a match, a match length of 0 ends the block
+func appendSequence(dst []byte, literals []byte, offset int, length int) []byte {
+	token := byte(0)
+	if len(literals) >= 15 {
+		token = 0xF0
+	} else {
+		token = byte(len(literals) << 4)
+	}
+	if length > 0 {
+		if length-minMatch >= 15 {
+			token |= 0x0F
+		} else {
+			token |= byte(length - minMatch)
+		}
+	}
+
+	dst = append(dst, token)
+	if len(literals) >= 15 {
+		dst = appendLength(dst, len(literals)-15)
+	}
+	dst = append(dst, literals...)
+
+	if length > 0 {
+		dst = append(dst, byte(offset), byte(offset>>8))
+		if length-minMatch >= 15 {
+			dst = appendLength(dst, length-minMatch-15)
+		}
+	}
+	return dst
+}
+
+// appendLength appends an extended length as a run of 255 bytes and a final byte
+func appendLength(dst []byte, length int) []byte {
+	for length >= 255 {
+		dst = append(dst, 255)
+		length -= 255
+	}
+	return append(dst, byte(length))
+}
+
+// Decode returns the data of the LZ4 block src, size is the length of the data.
+// The size is checked against the most src can decode to before the data is allocated.
+func Decode(src []byte, size int) ([]byte, error) {
+	if size < 0 || size > maxInputLength {
+		return nil, ErrTooLarge
+	}
+	if size > len(src)*maxRatio {
+		return nil, ErrCorrupt
+	}
+	dst := make([]byte, 0, size)
+
+	position := 0
+	for position < len(src) {
+		token := src[position]
+		position++
+
+		length := int(token >> 4)
+		if length == 15 {
+			var err error
+			length, position, err = readLength(src, position, length)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if position+length > len(src) || len(dst)+length > size {
+			return nil, ErrCorrupt
+		}
+		dst = append(dst, src[position:position+length]...)
+		position += length
+
+		if position == len(src) {
+			break
+		}
+
+		if position+2 > len(src) {
+			return nil, ErrCorrupt
+		}
+		offset := int(src[position]) | int(src[position+1])<<8
+		position += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, ErrCorrupt
+		}
+
+		length = int(token & 0x0F)
+		if length == 15 {
+			var err error
+			length, position, err = readLength(src, position, length)
+			if err != nil {
+				return nil, err
+			}
+		}
+		length += minMatch
+		if len(dst)+length > size {
+			return nil, ErrCorrupt
+		}
+
+		// byte by byte since the match can overlap the bytes it is copying
+		start := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, ErrCorrupt
+	}
+	return dst, nil
+}
+
+// readLength reads the extended part of a length
+func readLength(src []byte, position int, length int) (int, int, error) {
+	for {
+		if position >= len(src) {
+			return 0, 0, ErrCorrupt
+		}
+		value := src[position]
+		position++
+		length += int(value)
+		if length > maxInputLength {
+			return 0, 0, ErrCorrupt
+		}
+		if value != 255 {
+			return length, position, nil
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package lz4
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	random := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		info string
+		data []byte
+	}{
+		{info: "empty", data: []byte{}},
+		{info: "one byte", data: []byte("a")},
+		{info: "short", data: []byte("hello hello hello hello hello")},
+		{info: "run", data: bytes.Repeat([]byte("a"), 1000)},
+		{info: "repeated", data: bytes.Repeat([]byte("abcabcabcd"), 6000)},
+		{info: "random", data: random},
+		{info: "mixed", data: append(append(bytes.Repeat([]byte("select * from table where "), 100), random...), bytes.Repeat([]byte{0}, 70000)...)},
+	}
+	for _, test := range tests {
+		block, err := Encode(test.data)
+		if err != nil {
+			t.Fatalf("Encode error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+		data, err := Decode(block, len(test.data))
+		if err != nil {
+			t.Fatalf("Decode error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+		if !bytes.Equal(data, test.data) {
+			t.Fatalf("Decode - received: %v - expected: %v - info: %v", len(data), len(test.data), test.info)
+		}
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	tests := []struct {
+		info  string
+		block []byte
+		size  int
+	}{
+		{info: "literals past end", block: []byte{0x50, 'a', 'b'}, size: 5},
+		{info: "size too small", block: []byte{0x30, 'a', 'b', 'c'}, size: 2},
+		{info: "size too large", block: []byte{0x30, 'a', 'b', 'c'}, size: 4},
+		{info: "missing offset", block: []byte{0x14, 'a', 0x01}, size: 5},
+		{info: "zero offset", block: []byte{0x14, 'a', 0x00, 0x00, 0x00}, size: 6},
+		{info: "offset past start", block: []byte{0x14, 'a', 0x02, 0x00, 0x00}, size: 6},
+		{info: "missing length", block: []byte{0xF0, 0xFF}, size: 300},
+		{info: "negative size", block: []byte{0x00}, size: -1},
+		{info: "size larger than block can decode to", block: []byte{0x10, 'a'}, size: 0x7E000000},
+	}
+	for _, test := range tests {
+		_, err := Decode(test.block, test.size)
+		if err == nil {
+			t.Fatalf("Decode error - received: %v - expected: %v - info: %v", err, "error", test.info)
+		}
+	}
+
+	// a large declared size is rejected before it is allocated
+	allocations := testing.AllocsPerRun(10, func() {
+		Decode([]byte{0x10, 'a'}, 0x7E000000)
+	})
+	if allocations != 0 {
+		t.Fatalf("allocations - received: %v - expected: %v ", allocations, 0)
+	}
+
+	data, err := Decode([]byte{0x10, 'a', 0x01, 0x00, 0x20, 'b', 'c'}, 7)
+	if err != nil || string(data) != "aaaaabc" {
+		t.Fatalf("Decode - received: %q, %v - expected: %q ", data, err, "aaaaabc")
+	}
+}
@@ -0,0 +1,157 @@
+package cql
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// newPreparedCache returns a prepared statement metadata cache that holds up to maxSize statements for up to maxAge,
+// a maxSize less than 1 disables the cache and a maxAge less than 1 keeps statements until they are removed
+func newPreparedCache(maxSize int, maxAge time.Duration) *preparedCacheStruct {
+	return &preparedCacheStruct{
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		list:    list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached metadata of the statement, statements older than maxAge are removed
+func (cache *preparedCacheStruct) get(statement string) (*gocql.QueryInfo, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[statement]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*preparedEntryStruct)
+	if cache.maxAge > 0 && time.Since(entry.added) > cache.maxAge {
+		cache.list.Remove(element)
+		delete(cache.entries, statement)
+		return nil, false
+	}
+	cache.list.MoveToFront(element)
+	return entry.queryInfo, true
+}
+
+// put caches the metadata of the statement, removing the least recently used statement when the cache is full
+func (cache *preparedCacheStruct) put(statement string, queryInfo *gocql.QueryInfo) {
+	if cache.maxSize < 1 {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, ok := cache.entries[statement]; ok {
+		entry := element.Value.(*preparedEntryStruct)
+		entry.queryInfo = queryInfo
+		entry.added = time.Now()
+		cache.list.MoveToFront(element)
+		return
+	}
+
+	cache.entries[statement] = cache.list.PushFront(&preparedEntryStruct{statement: statement, queryInfo: queryInfo, added: time.Now()})
+	for cache.list.Len() > cache.maxSize {
+		element := cache.list.Back()
+		cache.list.Remove(element)
+		delete(cache.entries, element.Value.(*preparedEntryStruct).statement)
+	}
+}
+
+// remove removes the statement from the cache
+func (cache *preparedCacheStruct) remove(statement string) {
+	cache.mutex.Lock()
+	if element, ok := cache.entries[statement]; ok {
+		cache.list.Remove(element)
+		delete(cache.entries, statement)
+	}
+	cache.mutex.Unlock()
+}
+
+// clear removes all the statements from the cache
+func (cache *preparedCacheStruct) clear() {
+	cache.mutex.Lock()
+	cache.list.Init()
+	cache.entries = make(map[string]*list.Element)
+	cache.mutex.Unlock()
+}
+
+// len returns the number of cached statements
+func (cache *preparedCacheStruct) len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.list.Len()
+}
+
+// prepare returns the prepared statement metadata of the statement, from the cache or by preparing it on the cluster.
+// Returns nil metadata if the statement type can not be prepared.
+func (cqlConn *cqlConnStruct) prepare(ctx context.Context, statement string) (*gocql.QueryInfo, error) {
+	if !isPreparable(statement) {
+		return nil, nil
+	}
+
+	prepared := cqlConn.sharedSession.prepared
+	if queryInfo, ok := prepared.get(statement); ok {
+		return queryInfo, nil
+	}
+
+	// gocql prepares the statement before calling the binding, returning an error from the binding stops the execute
+	var queryInfo *gocql.QueryInfo
+	err := cqlConn.session.Bind(statement, func(info *gocql.QueryInfo) ([]interface{}, error) {
+		queryInfo = info
+		return nil, errPrepared
+	}).WithContext(ctx).RetryPolicy(nil).Exec()
+	if err != errPrepared {
+		return nil, err
+	}
+
+	prepared.put(statement, queryInfo)
+	return queryInfo, nil
+}
+
+// KeyspaceChanged clears the prepared cache and passes the keyspace schema change event to the wrapped policy
+func (policy *preparedPolicyStruct) KeyspaceChanged(event gocql.KeyspaceUpdateEvent) {
+	policy.prepared.clear()
+	policy.HostSelectionPolicy.KeyspaceChanged(event)
+}
+
+// AddHosts adds the hosts to the wrapped policy, gocql adds the initial hosts with AddHosts when the policy has it
+func (policy *preparedPolicyStruct) AddHosts(hosts []*gocql.HostInfo) {
+	if wrapped, ok := policy.HostSelectionPolicy.(interface{ AddHosts([]*gocql.HostInfo) }); ok {
+		wrapped.AddHosts(hosts)
+		return
+	}
+	for _, host := range hosts {
+		policy.HostSelectionPolicy.AddHost(host)
+	}
+}
+
+// invalidatePrepared removes cached prepared statement metadata that is no longer valid:
+// the statement on an unprepared error and all statements after a schema change statement.
+// Keyspace changes made by other clients clear the cache with preparedPolicyStruct,
+// gocql does not pass on table schema change events so those are found within PreparedMaxAge or by the unprepared error.
+func (cqlStmt *CqlStmt) invalidatePrepared(err error) {
+	if cqlStmt.conn == nil || cqlStmt.conn.sharedSession == nil || cqlStmt.CqlQuery == nil {
+		return
+	}
+	statement := cqlStmt.CqlQuery.Statement()
+
+	if err != nil {
+		var unprepared *gocql.RequestErrUnprepared
+		if errors.As(err, &unprepared) {
+			cqlStmt.conn.sharedSession.prepared.remove(statement)
+		}
+		return
+	}
+
+	switch statementType(statement) {
+	case "create", "alter", "drop":
+		cqlStmt.conn.sharedSession.prepared.clear()
+	}
+}
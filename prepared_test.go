@@ -0,0 +1,227 @@
+package cql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/MichaelS11/go-cql-driver/cqltest"
+	"github.com/gocql/gocql"
+)
+
+func TestPreparedCache(t *testing.T) {
+	prepared := newPreparedCache(2, 0)
+	queryInfoA := &gocql.QueryInfo{Id: []byte("a")}
+	queryInfoB := &gocql.QueryInfo{Id: []byte("b")}
+	queryInfoC := &gocql.QueryInfo{Id: []byte("c")}
+
+	prepared.put("a", queryInfoA)
+	prepared.put("b", queryInfoB)
+	queryInfo, ok := prepared.get("a")
+	if !ok || queryInfo != queryInfoA {
+		t.Fatalf("get a - received: %v - expected: %v ", queryInfo, queryInfoA)
+	}
+
+	// b is the least recently used
+	prepared.put("c", queryInfoC)
+	if prepared.len() != 2 {
+		t.Fatalf("len - received: %v - expected: %v ", prepared.len(), 2)
+	}
+	_, ok = prepared.get("b")
+	if ok {
+		t.Fatal("b is cached")
+	}
+	queryInfo, ok = prepared.get("c")
+	if !ok || queryInfo != queryInfoC {
+		t.Fatalf("get c - received: %v - expected: %v ", queryInfo, queryInfoC)
+	}
+
+	prepared.remove("a")
+	_, ok = prepared.get("a")
+	if ok {
+		t.Fatal("a is cached")
+	}
+
+	prepared.put("a", queryInfoA)
+	prepared.clear()
+	if prepared.len() != 0 {
+		t.Fatalf("len - received: %v - expected: %v ", prepared.len(), 0)
+	}
+
+	prepared = newPreparedCache(0, 0)
+	prepared.put("a", queryInfoA)
+	if prepared.len() != 0 {
+		t.Fatalf("len - received: %v - expected: %v ", prepared.len(), 0)
+	}
+
+	// statements older than maxAge are prepared again
+	prepared = newPreparedCache(2, 10*time.Millisecond)
+	prepared.put("a", queryInfoA)
+	_, ok = prepared.get("a")
+	if !ok {
+		t.Fatal("a is not cached")
+	}
+	time.Sleep(20 * time.Millisecond)
+	_, ok = prepared.get("a")
+	if ok {
+		t.Fatal("a is cached")
+	}
+	if prepared.len() != 0 {
+		t.Fatalf("len - received: %v - expected: %v ", prepared.len(), 0)
+	}
+}
+
+func TestConnectionPrepareContextPrepared(t *testing.T) {
+	conn := testGetConnectionHostValid(t)
+	if conn == nil {
+		t.Fatal("conn is nil")
+	}
+	cqlConn := conn.(*cqlConnStruct)
+
+	tests := []struct {
+		info      string
+		query     string
+		queryInfo bool
+		err       bool
+	}{
+		{info: "not preparable", query: "use system"},
+		{info: "select", query: "select cql_version from system.local where key = ?", queryInfo: true},
+		{info: "syntax error", query: "select from system.local", err: true},
+		{info: "invalid table", query: "select key from system.does_not_exist", err: true},
+	}
+
+	for _, test := range tests {
+		stmt, err := cqlConn.PrepareContext(context.Background(), test.query)
+		if test.err {
+			if err == nil {
+				t.Fatalf("PrepareContext error - received: %v - expected: %v - info: %v", err, "error", test.info)
+			}
+			if stmt != nil {
+				t.Fatalf("stmt is not nil - info: %v", test.info)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("PrepareContext error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+		cqlStmt := stmt.(*CqlStmt)
+		if (cqlStmt.QueryInfo != nil) != test.queryInfo {
+			t.Fatalf("QueryInfo - received: %v - expected: %v - info: %v", cqlStmt.QueryInfo, test.queryInfo, test.info)
+		}
+		if test.queryInfo {
+			if len(cqlStmt.QueryInfo.Args) != 1 || cqlStmt.QueryInfo.Args[0].Name != "key" {
+				t.Fatalf("QueryInfo Args - received: %v - expected: %v - info: %v", cqlStmt.QueryInfo.Args, "key", test.info)
+			}
+			if len(cqlStmt.QueryInfo.Rval) != 1 || cqlStmt.QueryInfo.Rval[0].Name != "cql_version" {
+				t.Fatalf("QueryInfo Rval - received: %v - expected: %v - info: %v", cqlStmt.QueryInfo.Rval, "cql_version", test.info)
+			}
+			_, ok := cqlConn.sharedSession.prepared.get(test.query)
+			if !ok {
+				t.Fatalf("query is not cached - info: %v", test.info)
+			}
+		}
+		err = stmt.Close()
+		if err != nil {
+			t.Fatalf("Close error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+	}
+
+	err := conn.Close()
+	if err != nil {
+		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
+	}
+}
+
+func TestSqlPreparedInvalidation(t *testing.T) {
+	server, err := cqltest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+
+	db, err := sql.Open("cql", server.Addr()+"?timeout=2s&connectTimeout=2s&maxPreparedStmts=2")
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	defer cancel()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn error - received: %v - expected: %v ", err, nil)
+	}
+	defer conn.Close()
+
+	var prepared *preparedCacheStruct
+	err = conn.Raw(func(driverConn interface{}) error {
+		cqlConn := driverConn.(*cqlConnStruct)
+		if cqlConn.session == nil {
+			if err := cqlConn.Ping(ctx); err != nil {
+				return err
+			}
+		}
+		prepared = cqlConn.sharedSession.prepared
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Raw error - received: %v - expected: %v ", err, nil)
+	}
+
+	prepare := func(queries ...string) {
+		for _, query := range queries {
+			stmt, err := conn.PrepareContext(ctx, query)
+			if err != nil {
+				t.Fatalf("PrepareContext error - received: %v - expected: %v ", err, nil)
+			}
+			stmt.Close()
+		}
+	}
+
+	prepare("select key from system.local", "select cql_version from system.local", "select release_version from system.local")
+	if prepared.len() != 2 {
+		t.Fatalf("len - received: %v - expected: %v ", prepared.len(), 2)
+	}
+
+	// schema change statement run by the driver
+	_, err = conn.ExecContext(ctx, "create keyspace prepared_test with replication = { 'class' : 'SimpleStrategy', 'replication_factor' : 1 }")
+	if err != nil {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+	}
+	if prepared.len() != 0 {
+		t.Fatalf("len - received: %v - expected: %v ", prepared.len(), 0)
+	}
+
+	// unprepared error removes the statement
+	prepare("select key from system.local")
+	err = conn.Raw(func(driverConn interface{}) error {
+		stmt, err := driverConn.(*cqlConnStruct).PrepareContext(ctx, "select key from system.local")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		stmt.(*CqlStmt).invalidatePrepared(&gocql.RequestErrUnprepared{})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Raw error - received: %v - expected: %v ", err, nil)
+	}
+	if prepared.len() != 0 {
+		t.Fatalf("len - received: %v - expected: %v ", prepared.len(), 0)
+	}
+	// keyspace schema change made by another client
+	prepare("select key from system.local")
+	err = server.Exec("drop keyspace prepared_test")
+	if err != nil {
+		t.Fatalf("Exec error - received: %v - expected: %v ", err, nil)
+	}
+	// gocql debounces schema change events for a second
+	deadline := time.Now().Add(5 * time.Second)
+	for prepared.len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if prepared.len() != 0 {
+		t.Fatalf("len - received: %v - expected: %v ", prepared.len(), 0)
+	}
+}
@@ -5,7 +5,11 @@ func (cqlResult cqlResultStruct) LastInsertId() (int64, error) {
 	return -1, ErrNotSupported
 }
 
-// RowsAffected not supported
+// RowsAffected returns 1 if a lightweight transaction was applied or 0 if it was not.
+// Not supported for other statements.
 func (cqlResult cqlResultStruct) RowsAffected() (int64, error) {
-	return -1, ErrNotSupported
+	if !cqlResult.conditional {
+		return -1, ErrNotSupported
+	}
+	return cqlResult.rowsAffected, nil
 }
@@ -4,6 +4,10 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"io"
+	"math"
+	"reflect"
+
+	"github.com/gocql/gocql"
 )
 
 // Close the rows
@@ -11,8 +15,17 @@ func (cqlRows *cqlRowsStruct) Close() error {
 	if cqlRows.iter == nil {
 		return nil
 	}
+	if cqlRows.pageResult != nil {
+		cqlRows.pageResult.PageState = nil
+		if pageState := cqlRows.iter.PageState(); len(pageState) > 0 {
+			cqlRows.pageResult.PageState = append([]byte(nil), pageState...)
+		}
+	}
 	err := cqlRows.iter.Close()
 	cqlRows.iter = nil
+	if cqlRows.cancel != nil {
+		cqlRows.cancel()
+	}
 	return err
 }
 
@@ -36,19 +49,80 @@ func (cqlRows *cqlRowsStruct) Next(dest []driver.Value) error {
 		return io.EOF
 	}
 
-	if !cqlRows.iter.Scan(rowData.Values...) {
-		return io.EOF
+	// scan into pointers to pointers so gocql can set nil for null values
+	nullableValues := make([]interface{}, length)
+	for i := 0; i < length; i++ {
+		nullableValues[i] = reflect.New(reflect.TypeOf(rowData.Values[i])).Interface()
 	}
 
-	if len(dest) < length {
-		length = len(dest)
+	if !cqlRows.iter.Scan(nullableValues...) {
+		return io.EOF
 	}
-	for i := 0; i < length; i++ {
-		dest[i], err = interfaceToValue(rowData.Values[i])
-		if err != nil {
-			return fmt.Errorf("interfaceToValue error: %v", err)
+
+	// tuple columns are scanned as one value per element, combine them into one value per column
+	index := 0
+	for i := 0; i < len(cqlRows.columnInfo) && i < len(dest); i++ {
+		tuple, ok := cqlRows.columnInfo[i].TypeInfo.(gocql.TupleTypeInfo)
+		if !ok {
+			dest[i], err = interfaceToValue(nullableValues[index])
+			if err != nil {
+				return fmt.Errorf("interfaceToValue error: %v", err)
+			}
+			index++
+			continue
+		}
+
+		elements := make([]interface{}, len(tuple.Elems))
+		isNull := true
+		for j := 0; j < len(elements); j++ {
+			elements[j], err = interfaceToValue(nullableValues[index+j])
+			if err != nil {
+				return fmt.Errorf("interfaceToValue error: %v", err)
+			}
+			if elements[j] != nil {
+				isNull = false
+			}
+		}
+		index += len(elements)
+		if isNull {
+			dest[i] = nil
+		} else {
+			dest[i] = elements
 		}
 	}
 
 	return nil
 }
+
+// ColumnTypeScanType returns the Go type that the column value is returned as
+func (cqlRows *cqlRowsStruct) ColumnTypeScanType(index int) reflect.Type {
+	return typeInfoToScanType(cqlRows.columnInfo[index].TypeInfo)
+}
+
+// ColumnTypeDatabaseTypeName returns the CQL type name of the column, for example TEXT or MAP<TEXT, INT>
+func (cqlRows *cqlRowsStruct) ColumnTypeDatabaseTypeName(index int) string {
+	return typeInfoToDatabaseTypeName(cqlRows.columnInfo[index].TypeInfo)
+}
+
+// ColumnTypeNullable returns true, false for unknown, any CQL column can be null except primary key columns
+// and the result metadata does not say which columns are primary key columns
+func (cqlRows *cqlRowsStruct) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return true, false
+}
+
+// ColumnTypeLength returns math.MaxInt64 for variable length text and blob columns
+func (cqlRows *cqlRowsStruct) ColumnTypeLength(index int) (length int64, ok bool) {
+	switch cqlRows.columnInfo[index].TypeInfo.Type() {
+	case gocql.TypeAscii, gocql.TypeText, gocql.TypeVarchar, gocql.TypeBlob:
+		return math.MaxInt64, true
+	}
+	return 0, false
+}
+
+// ColumnTypePrecisionScale returns math.MaxInt64 precision and scale for arbitrary precision decimal columns
+func (cqlRows *cqlRowsStruct) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if cqlRows.columnInfo[index].TypeInfo.Type() == gocql.TypeDecimal {
+		return math.MaxInt64, math.MaxInt64, true
+	}
+	return 0, 0, false
+}
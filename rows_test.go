@@ -3,7 +3,14 @@ package cql
 import (
 	"database/sql/driver"
 	"io"
+	"math"
+	"math/big"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
 )
 
 func TestRowsColumns(t *testing.T) {
@@ -86,3 +93,63 @@ func TestRowsNext(t *testing.T) {
 		t.Fatalf("Close error - received: %v - expected: %v ", err, nil)
 	}
 }
+
+func TestRowsColumnTypes(t *testing.T) {
+	text := gocql.NewNativeType(4, gocql.TypeVarchar, "")
+	integer := gocql.NewNativeType(4, gocql.TypeInt, "")
+	udt := gocql.UDTTypeInfo{NativeType: gocql.NewNativeType(4, gocql.TypeUDT, ""), KeySpace: "ks", Name: "address"}
+	list := gocql.CollectionType{NativeType: gocql.NewNativeType(4, gocql.TypeList, ""), Elem: text}
+
+	tests := []struct {
+		info      string
+		typeInfo  gocql.TypeInfo
+		name      string
+		scanType  reflect.Type
+		length    int64
+		lengthOk  bool
+		precision int64
+		scale     int64
+		decimalOk bool
+	}{
+		{info: "text", typeInfo: text, name: "TEXT", scanType: reflect.TypeOf(""), length: math.MaxInt64, lengthOk: true},
+		{info: "blob", typeInfo: gocql.NewNativeType(4, gocql.TypeBlob, ""), name: "BLOB", scanType: reflect.TypeOf([]byte{}), length: math.MaxInt64, lengthOk: true},
+		{info: "int", typeInfo: integer, name: "INT", scanType: reflect.TypeOf(0)},
+		{info: "bigint", typeInfo: gocql.NewNativeType(4, gocql.TypeBigInt, ""), name: "BIGINT", scanType: reflect.TypeOf(int64(0))},
+		{info: "timestamp", typeInfo: gocql.NewNativeType(4, gocql.TypeTimestamp, ""), name: "TIMESTAMP", scanType: reflect.TypeOf(time.Time{})},
+		{info: "varint", typeInfo: gocql.NewNativeType(4, gocql.TypeVarint, ""), name: "VARINT", scanType: reflect.TypeOf(&big.Int{})},
+		{info: "decimal", typeInfo: gocql.NewNativeType(4, gocql.TypeDecimal, ""), name: "DECIMAL", scanType: reflect.TypeOf(&inf.Dec{}), precision: math.MaxInt64, scale: math.MaxInt64, decimalOk: true},
+		{info: "custom", typeInfo: gocql.NewNativeType(4, gocql.TypeCustom, "org.example.Custom"), name: "org.example.Custom", scanType: reflect.TypeOf((*interface{})(nil)).Elem()},
+		{info: "list", typeInfo: list, name: "LIST<TEXT>", scanType: reflect.TypeOf([]string{})},
+		{info: "set", typeInfo: gocql.CollectionType{NativeType: gocql.NewNativeType(4, gocql.TypeSet, ""), Elem: integer}, name: "SET<INT>", scanType: reflect.TypeOf([]int{})},
+		{info: "map", typeInfo: gocql.CollectionType{NativeType: gocql.NewNativeType(4, gocql.TypeMap, ""), Key: text, Elem: integer}, name: "MAP<TEXT, INT>", scanType: reflect.TypeOf(map[string]int{})},
+		{info: "map frozen", typeInfo: gocql.CollectionType{NativeType: gocql.NewNativeType(4, gocql.TypeMap, ""), Key: text, Elem: list}, name: "MAP<TEXT, FROZEN<LIST<TEXT>>>", scanType: reflect.TypeOf(map[string][]string{})},
+		{info: "udt", typeInfo: udt, name: "address", scanType: reflect.TypeOf(map[string]interface{}{})},
+		{info: "list udt", typeInfo: gocql.CollectionType{NativeType: gocql.NewNativeType(4, gocql.TypeList, ""), Elem: udt}, name: "LIST<FROZEN<address>>", scanType: reflect.TypeOf([]map[string]interface{}{})},
+		{info: "tuple", typeInfo: gocql.TupleTypeInfo{NativeType: gocql.NewNativeType(4, gocql.TypeTuple, ""), Elems: []gocql.TypeInfo{integer, text}}, name: "TUPLE<INT, TEXT>", scanType: reflect.TypeOf([]interface{}{})},
+	}
+
+	for _, test := range tests {
+		cqlRows := &cqlRowsStruct{columnInfo: []gocql.ColumnInfo{{Name: "a", TypeInfo: test.typeInfo}}}
+
+		name := cqlRows.ColumnTypeDatabaseTypeName(0)
+		if name != test.name {
+			t.Errorf("ColumnTypeDatabaseTypeName - received: %v - expected: %v - info: %v", name, test.name, test.info)
+		}
+		scanType := cqlRows.ColumnTypeScanType(0)
+		if scanType != test.scanType {
+			t.Errorf("ColumnTypeScanType - received: %v - expected: %v - info: %v", scanType, test.scanType, test.info)
+		}
+		nullable, ok := cqlRows.ColumnTypeNullable(0)
+		if !nullable || ok {
+			t.Errorf("ColumnTypeNullable - received: %v %v - expected: %v %v - info: %v", nullable, ok, true, false, test.info)
+		}
+		length, ok := cqlRows.ColumnTypeLength(0)
+		if length != test.length || ok != test.lengthOk {
+			t.Errorf("ColumnTypeLength - received: %v %v - expected: %v %v - info: %v", length, ok, test.length, test.lengthOk, test.info)
+		}
+		precision, scale, ok := cqlRows.ColumnTypePrecisionScale(0)
+		if precision != test.precision || scale != test.scale || ok != test.decimalOk {
+			t.Errorf("ColumnTypePrecisionScale - received: %v %v %v - expected: %v %v %v - info: %v", precision, scale, ok, test.precision, test.scale, test.decimalOk, test.info)
+		}
+	}
+}
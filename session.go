@@ -0,0 +1,226 @@
+package cql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gocql/gocql"
+)
+
+// acquire returns the shared session for the cluster config, creating it if needed.
+// Waiting for the session to be created stops when ctx is done, the session creation continues for other users.
+// Every successful acquire must be matched with a release.
+func (registry *sessionRegistryStruct) acquire(ctx context.Context, clusterConfig *gocql.ClusterConfig) (*sharedSessionStruct, error) {
+	key := sessionKey(clusterConfig)
+
+	registry.mutex.Lock()
+	sharedSession, ok := registry.sessions[key]
+	if ok && !sharedSession.closed() {
+		sharedSession.refs++
+		registry.mutex.Unlock()
+	} else {
+		sharedSession = &sharedSessionStruct{
+			key:      key,
+			refs:     1,
+			ready:    make(chan struct{}),
+			prepared: newPreparedCache(clusterConfig.MaxPreparedStmts, PreparedMaxAge),
+		}
+		registry.sessions[key] = sharedSession
+		registry.mutex.Unlock()
+
+		go registry.create(sharedSession, clusterConfig)
+	}
+
+	select {
+	case <-sharedSession.ready:
+	case <-ctx.Done():
+		registry.release(sharedSession)
+		return nil, ctx.Err()
+	}
+	if sharedSession.err != nil {
+		registry.release(sharedSession)
+		return nil, sharedSession.err
+	}
+	return sharedSession, nil
+}
+
+// create creates the gocql session of a shared session,
+// the session is closed if every user stopped waiting for it
+func (registry *sessionRegistryStruct) create(sharedSession *sharedSessionStruct, clusterConfig *gocql.ClusterConfig) {
+	sessionConfig := *clusterConfig
+	if clusterConfig.SslOpts != nil {
+		// gocql changes the ssl options and tls config when creating a session
+		sslOpts := *clusterConfig.SslOpts
+		sslOpts.Config = sslOpts.Config.Clone()
+		sessionConfig.SslOpts = &sslOpts
+	}
+	// keyspace schema change events from the cluster clear the prepared cache
+	policy := sessionConfig.PoolConfig.HostSelectionPolicy
+	if policy == nil {
+		policy = gocql.RoundRobinHostPolicy()
+	}
+	sessionConfig.PoolConfig.HostSelectionPolicy = &preparedPolicyStruct{HostSelectionPolicy: policy, prepared: sharedSession.prepared}
+	var session *gocql.Session
+	err := verifyHostNames(&sessionConfig)
+	if err == nil {
+		session, err = sessionConfig.CreateSession()
+	}
+
+	registry.mutex.Lock()
+	sharedSession.session = session
+	sharedSession.err = err
+	released := sharedSession.refs < 1
+	registry.mutex.Unlock()
+	close(sharedSession.ready)
+
+	if released && session != nil {
+		session.Close()
+	}
+}
+
+// release drops a reference to the shared session, closing the session when it was the last one
+func (registry *sessionRegistryStruct) release(sharedSession *sharedSessionStruct) {
+	registry.mutex.Lock()
+	sharedSession.refs--
+	if sharedSession.refs > 0 {
+		registry.mutex.Unlock()
+		return
+	}
+	if registry.sessions[sharedSession.key] == sharedSession {
+		delete(registry.sessions, sharedSession.key)
+	}
+	session := sharedSession.session
+	registry.mutex.Unlock()
+
+	if session != nil {
+		session.Close()
+	}
+}
+
+// closed returns true if the session has finished being created and is not usable
+func (sharedSession *sharedSessionStruct) closed() bool {
+	select {
+	case <-sharedSession.ready:
+		return sharedSession.session == nil || sharedSession.session.Closed()
+	default:
+		return false
+	}
+}
+
+// keyspaceClusterConfig returns a copy of the cluster config with the keyspace for a use statement.
+// The policies are created again since gocql policies hold the state of the session using them,
+// so only cluster configs the config string fully describes are supported.
+func keyspaceClusterConfig(clusterConfig *gocql.ClusterConfig, keyspace string) (*gocql.ClusterConfig, error) {
+	if !configStringComplete(clusterConfig) {
+		return nil, ErrUseNotSupported
+	}
+
+	keyspaceConfig := *clusterConfig
+	keyspaceConfig.Keyspace = keyspace
+	if policy := clusterConfig.PoolConfig.HostSelectionPolicy; policy != nil {
+		hostSelection, localDC, shuffleReplicas, _ := hostSelectionPolicyToConfig(policy)
+		var err error
+		keyspaceConfig.PoolConfig.HostSelectionPolicy, err = newHostSelectionPolicy(hostSelection, localDC, shuffleReplicas)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch policy := clusterConfig.RetryPolicy.(type) {
+	case *gocql.SimpleRetryPolicy:
+		retryPolicy := *policy
+		keyspaceConfig.RetryPolicy = &retryPolicy
+	case *gocql.ExponentialBackoffRetryPolicy:
+		retryPolicy := *policy
+		keyspaceConfig.RetryPolicy = &retryPolicy
+	case *gocql.DowngradingConsistencyRetryPolicy:
+		retryPolicy := *policy
+		keyspaceConfig.RetryPolicy = &retryPolicy
+	}
+	switch policy := clusterConfig.ReconnectionPolicy.(type) {
+	case *gocql.ConstantReconnectionPolicy:
+		reconnectionPolicy := *policy
+		keyspaceConfig.ReconnectionPolicy = &reconnectionPolicy
+	case *gocql.ExponentialReconnectionPolicy:
+		reconnectionPolicy := *policy
+		keyspaceConfig.ReconnectionPolicy = &reconnectionPolicy
+	}
+	if clusterConfig.ConvictionPolicy != nil {
+		keyspaceConfig.ConvictionPolicy = &gocql.SimpleConvictionPolicy{}
+	}
+
+	return &keyspaceConfig, nil
+}
+
+// sessionKey returns the session registry key of the cluster config, a hash of the config string
+// so passwords and auth params are not kept in the registry.
+// A cluster config with settings the config string does not hold also uses its pointer,
+// so its session is only shared by connections using that cluster config.
+func sessionKey(clusterConfig *gocql.ClusterConfig) string {
+	key := ClusterConfigToConfigString(clusterConfig)
+	// the config string leaves out values that are the gocql default or not valid in a config string
+	key += fmt.Sprintf("#%v,%v,%v,%v,%v,%q", clusterConfig.NumConns, clusterConfig.Timeout, clusterConfig.ConnectTimeout,
+		clusterConfig.Port, clusterConfig.MaxPreparedStmts, clusterConfig.CQLVersion)
+	if !configStringComplete(clusterConfig) {
+		key += fmt.Sprintf("#%p", clusterConfig)
+	}
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// configStringComplete returns false if the cluster config has settings the config string does not hold,
+// like custom authenticators, policies, tls configs, filters, and observers
+func configStringComplete(clusterConfig *gocql.ClusterConfig) bool {
+	clusterConfigDefault := gocql.NewCluster()
+	if clusterConfig.AuthProvider != nil || clusterConfig.HostFilter != nil || clusterConfig.AddressTranslator != nil || clusterConfig.Dialer != nil ||
+		clusterConfig.QueryObserver != nil || clusterConfig.BatchObserver != nil || clusterConfig.ConnectObserver != nil || clusterConfig.FrameHeaderObserver != nil {
+		return false
+	}
+	if clusterConfig.SocketKeepalive != clusterConfigDefault.SocketKeepalive ||
+		clusterConfig.MaxRoutingKeyInfo != clusterConfigDefault.MaxRoutingKeyInfo ||
+		clusterConfig.PageSize != clusterConfigDefault.PageSize ||
+		clusterConfig.SerialConsistency != clusterConfigDefault.SerialConsistency ||
+		clusterConfig.DefaultTimestamp != clusterConfigDefault.DefaultTimestamp ||
+		clusterConfig.ReconnectInterval != clusterConfigDefault.ReconnectInterval ||
+		clusterConfig.MaxWaitSchemaAgreement != clusterConfigDefault.MaxWaitSchemaAgreement ||
+		clusterConfig.Events != clusterConfigDefault.Events ||
+		clusterConfig.DisableSkipMetadata != clusterConfigDefault.DisableSkipMetadata ||
+		clusterConfig.DefaultIdempotence != clusterConfigDefault.DefaultIdempotence {
+		return false
+	}
+
+	switch clusterConfig.Authenticator.(type) {
+	case nil, gocql.PasswordAuthenticator, *registeredAuthenticatorStruct:
+	default:
+		return false
+	}
+	switch clusterConfig.Compressor.(type) {
+	case nil, gocql.SnappyCompressor, LZ4Compressor:
+	default:
+		return false
+	}
+	switch clusterConfig.ConvictionPolicy.(type) {
+	case nil, *gocql.SimpleConvictionPolicy:
+	default:
+		return false
+	}
+	if policy := clusterConfig.PoolConfig.HostSelectionPolicy; policy != nil {
+		if _, _, _, ok := hostSelectionPolicyToConfig(policy); !ok {
+			return false
+		}
+	}
+	if clusterConfig.RetryPolicy != nil && retryPolicyToConfig(clusterConfig.RetryPolicy) == "" {
+		return false
+	}
+	if clusterConfig.ReconnectionPolicy != nil && reconnectionPolicyToConfig(clusterConfig.ReconnectionPolicy) == "" {
+		return false
+	}
+	if clusterConfig.SslOpts != nil && clusterConfig.SslOpts.Config != nil {
+		if _, ok := tlsConfigSettings(clusterConfig.SslOpts.Config); !ok {
+			return false
+		}
+	}
+
+	return true
+}
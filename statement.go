@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql/driver"
 	"reflect"
+
+	"github.com/gocql/gocql"
 )
 
 // Close a statement
@@ -15,37 +17,51 @@ func (cqlStmt *CqlStmt) Close() error {
 	return nil
 }
 
-// NumInput not supported
+// NumInput returns the number of bind markers in the statement.
+// Returns -1 if the statement does not support bind markers or has named bind markers.
 func (cqlStmt *CqlStmt) NumInput() int {
-	return -1
+	return cqlStmt.numInput
 }
 
-// Exec executes a statement with background context
+// Exec executes a statement with the connection context
 func (cqlStmt *CqlStmt) Exec(args []driver.Value) (driver.Result, error) {
-	return cqlStmt.execContext(context.Background(), valuesToInterface(args))
+	return cqlStmt.execContext(cqlStmt.conn.baseContext(), valuesToNamedValues(args))
 }
 
 // ExecContext executes a statement with context
 func (cqlStmt *CqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	values, err := namedValuesToInterface(args)
+	return cqlStmt.execContext(ctx, args)
+}
+
+// execContext executes a statement with context, in a transaction the statement is added to the transaction batch
+func (cqlStmt *CqlStmt) execContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if cqlStmt.sessionReleased() {
+		return nil, driver.ErrBadConn
+	}
+	if cqlStmt.conn != nil && cqlStmt.conn.tx != nil {
+		if cqlStmt.CqlQuery == nil {
+			return nil, ErrQueryIsNil
+		}
+		return cqlStmt.conn.tx.exec(cqlStmt.CqlQuery.Statement(), args)
+	}
+
+	ctx, cancel := cqlStmt.conn.queryTimeoutContext(ctx)
+	defer cancel()
+	query, binder, err := cqlStmt.bindQuery(ctx, args)
 	if err != nil {
 		return nil, err
 	}
-	return cqlStmt.execContext(ctx, values)
-}
+	cqlStmt.applyTrace(ctx, query)
 
-// execContext executes a statement with context
-func (cqlStmt *CqlStmt) execContext(ctx context.Context, values []interface{}) (driver.Result, error) {
-	query := cqlStmt.CqlQuery
-	if query == nil {
-		return nil, ErrQueryIsNil
+	if cqlStmt.conditional {
+		return cqlStmt.execConditional(ctx, query, binder)
 	}
 
-	query = query.WithContext(ctx)
-	if len(values) > 0 {
-		query = query.Bind(values...)
+	err = query.Exec()
+	if binder != nil && binder.err != nil {
+		return nil, binder.err
 	}
-	err := query.Exec()
+	cqlStmt.invalidatePrepared(err)
 	if err != nil {
 		return nil, err
 	}
@@ -53,37 +69,117 @@ func (cqlStmt *CqlStmt) execContext(ctx context.Context, values []interface{}) (
 	return cqlResultStruct{}, nil
 }
 
-// Query queries a statement with background context
+// execConditional executes a lightweight transaction, rows affected is 1 if applied or 0 if not applied
+func (cqlStmt *CqlStmt) execConditional(ctx context.Context, query *gocql.Query, binder *namedBinderStruct) (driver.Result, error) {
+	existing := make(map[string]interface{})
+	applied, err := query.MapScanCAS(existing)
+	if binder != nil && binder.err != nil {
+		return nil, binder.err
+	}
+	cqlStmt.invalidatePrepared(err)
+	if err != nil {
+		return nil, err
+	}
+
+	if casResult := queryOptionsFromContext(ctx).casResult; casResult != nil {
+		casResult.Applied = applied
+		casResult.Existing = existing
+	}
+
+	cqlResult := cqlResultStruct{
+		conditional: true,
+	}
+	if applied {
+		cqlResult.rowsAffected = 1
+	}
+	return cqlResult, nil
+}
+
+// sessionReleased returns true if the connection released the statement session,
+// like when ResetSession changes the keyspace back after a use statement
+func (cqlStmt *CqlStmt) sessionReleased() bool {
+	return cqlStmt.conn != nil && cqlStmt.session != nil && cqlStmt.session != cqlStmt.conn.session
+}
+
+// Query queries a statement with the connection context
 func (cqlStmt *CqlStmt) Query(args []driver.Value) (driver.Rows, error) {
-	return cqlStmt.queryContext(context.Background(), valuesToInterface(args))
+	return cqlStmt.queryContext(cqlStmt.conn.baseContext(), valuesToNamedValues(args))
 }
 
 // QueryContext queries a statement with context
 func (cqlStmt *CqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	values, err := namedValuesToInterface(args)
+	return cqlStmt.queryContext(ctx, args)
+}
+
+// queryContext queries a statement with context
+func (cqlStmt *CqlStmt) queryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if cqlStmt.sessionReleased() {
+		return nil, driver.ErrBadConn
+	}
+	if cqlStmt.conn != nil && cqlStmt.conn.tx != nil {
+		return nil, ErrTxStatementNotSupported
+	}
+
+	// the timeout context is canceled when the rows are closed
+	ctx, cancel := cqlStmt.conn.queryTimeoutContext(ctx)
+	query, binder, err := cqlStmt.bindQuery(ctx, args)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	return cqlStmt.queryContext(ctx, values)
+	cqlStmt.applyTrace(ctx, query)
+
+	iter := query.Iter()
+	if binder != nil && binder.err != nil {
+		iter.Close()
+		cancel()
+		return nil, binder.err
+	}
+
+	columnInfo := iter.Columns()
+	return &cqlRowsStruct{
+		iter:       iter,
+		columns:    columnInfoToString(columnInfo),
+		columnInfo: columnInfo,
+		cancel:     cancel,
+		pageResult: queryOptionsFromContext(ctx).pageResult,
+	}, nil
 }
 
-// queryContext queries a statement with context
-func (cqlStmt *CqlStmt) queryContext(ctx context.Context, values []interface{}) (driver.Rows, error) {
+// bindQuery returns a copy of the statement query with context, context query options, and values bound.
+// When any value is named, the values are bound by the prepared statement bind marker names
+// and the returned binder holds any binding error after the query is executed.
+func (cqlStmt *CqlStmt) bindQuery(ctx context.Context, args []driver.NamedValue) (*gocql.Query, *namedBinderStruct, error) {
 	query := cqlStmt.CqlQuery
 	if query == nil {
-		return nil, ErrQueryIsNil
+		return nil, nil, ErrQueryIsNil
 	}
 
-	query = query.WithContext(ctx)
-	if len(values) > 0 {
-		query = query.Bind(values...)
+	if !hasNamedValues(args) {
+		values, err := namedValuesToInterface(args)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = query.WithContext(ctx)
+		if len(values) > 0 {
+			query = query.Bind(values...)
+		}
+		applyQueryOptions(ctx, query)
+		return query, nil, nil
 	}
 
-	iter := query.Iter()
-	return &cqlRowsStruct{
-		iter:    iter,
-		columns: columnInfoToString(iter.Columns()),
-	}, nil
+	if cqlStmt.session == nil || !isPreparable(query.Statement()) {
+		return nil, nil, ErrNamedValuesNotSupported
+	}
+
+	binder := &namedBinderStruct{
+		namedValues: args,
+	}
+	bindQuery := cqlStmt.session.Bind(query.Statement(), binder.bind).WithContext(ctx)
+	bindQuery.SetConsistency(query.GetConsistency())
+	applyQueryOptions(ctx, bindQuery)
+
+	return bindQuery, binder, nil
 }
 
 // ColumnConverter provides driver ValueConverter for statement
@@ -91,16 +187,47 @@ func (cqlStmt *CqlStmt) ColumnConverter(index int) driver.ValueConverter {
 	return converter{}
 }
 
-// ConvertValue coverts interface value to driver Value
+// CheckNamedValue converts named values with the driver ValueConverter,
+// which allows values such as collections that the database/sql default converter does not
+func (cqlStmt *CqlStmt) CheckNamedValue(namedValue *driver.NamedValue) error {
+	var err error
+	namedValue.Value, err = converter{}.ConvertValue(namedValue.Value)
+	return err
+}
+
+// ConvertValue coverts interface value to driver Value.
+// Slices, arrays, maps, List, Set, Map, Tuple, UDT, and gocql Marshaler values are passed to gocql as is.
 func (c converter) ConvertValue(valueInterface interface{}) (driver.Value, error) {
+	switch value := valueInterface.(type) {
+	case List:
+		return value.Value()
+	case Set:
+		return value.Value()
+	case Map:
+		return value.Value()
+	case Tuple:
+		return value.Value()
+	case UDT:
+		return value.Value()
+	case gocql.Marshaler, gocql.UDTMarshaler:
+		return value, nil
+	}
+
 	valueDriver, err := driver.DefaultParameterConverter.ConvertValue(valueInterface)
 	if err == nil {
 		return valueDriver, nil
 	}
 
 	rv := reflect.ValueOf(valueInterface)
-	if rv.Kind() == reflect.Uint64 {
+	switch rv.Kind() {
+	case reflect.Uint64:
 		return rv.Uint(), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return valueInterface, nil
+	case reflect.Ptr:
+		if !rv.IsNil() {
+			return c.ConvertValue(rv.Elem().Interface())
+		}
 	}
 
 	return valueDriver, err
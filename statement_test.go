@@ -2,7 +2,9 @@ package cql
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"testing"
 )
 
@@ -220,8 +222,8 @@ func TestStatementQueryContext(t *testing.T) {
 	}
 
 	rows, err = cqlStmt.QueryContext(context.Background(), []driver.NamedValue{{Name: "a"}})
-	if err == nil || err != ErrNamedValuesNotSupported {
-		t.Fatalf("QueryContext error - received: %v - expected: %v ", err, ErrNamedValuesNotSupported)
+	if err == nil || !errors.Is(err, ErrNamedValueUnknown) {
+		t.Fatalf("QueryContext error - received: %v - expected: %v ", err, ErrNamedValueUnknown)
 	}
 	if rows != nil {
 		t.Fatal("rows is not nil")
@@ -281,3 +283,94 @@ func testGetRowsHostValid(t *testing.T, query string) (driver.Conn, driver.Stmt,
 	}
 	return conn, stmt, rows
 }
+
+func benchmarkSqlDB(b *testing.B) *sql.DB {
+	openString := TestHostValid + "?timeout=" + TimeoutValidString + "&connectTimeout=" + ConnectTimeoutValidString
+	if EnableAuthentication {
+		openString += "&username=" + Username + "&password=" + Password
+	}
+	db, err := sql.Open("cql", openString)
+	if err != nil {
+		b.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	db.SetMaxIdleConns(1)
+	return db
+}
+
+// BenchmarkSqlQueryDirect queries with the connection QueryerContext
+func BenchmarkSqlQueryDirect(b *testing.B) {
+	db := benchmarkSqlDB(b)
+	defer db.Close()
+	ctx := context.Background()
+
+	var key string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := db.QueryRowContext(ctx, "select key from system.local where key = ?", "local").Scan(&key)
+		if err != nil {
+			b.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+		}
+	}
+}
+
+// BenchmarkSqlQueryPrepared queries with a prepared statement created for every query,
+// which is what database/sql does when the connection does not implement QueryerContext
+func BenchmarkSqlQueryPrepared(b *testing.B) {
+	db := benchmarkSqlDB(b)
+	defer db.Close()
+	ctx := context.Background()
+
+	var key string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stmt, err := db.PrepareContext(ctx, "select key from system.local where key = ?")
+		if err != nil {
+			b.Fatalf("PrepareContext error - received: %v - expected: %v ", err, nil)
+		}
+		err = stmt.QueryRowContext(ctx, "local").Scan(&key)
+		if err != nil {
+			b.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+		}
+		stmt.Close()
+	}
+}
+
+// BenchmarkSqlExecDirect executes with the connection ExecerContext
+func BenchmarkSqlExecDirect(b *testing.B) {
+	db := benchmarkSqlDB(b)
+	defer db.Close()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := db.ExecContext(ctx, "select key from system.local where key = ?", "local")
+		if err != nil {
+			b.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+		}
+	}
+}
+
+// BenchmarkSqlExecPrepared executes with a prepared statement created for every exec,
+// which is what database/sql does when the connection does not implement ExecerContext
+func BenchmarkSqlExecPrepared(b *testing.B) {
+	db := benchmarkSqlDB(b)
+	defer db.Close()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stmt, err := db.PrepareContext(ctx, "select key from system.local where key = ?")
+		if err != nil {
+			b.Fatalf("PrepareContext error - received: %v - expected: %v ", err, nil)
+		}
+		_, err = stmt.ExecContext(ctx, "local")
+		if err != nil {
+			b.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+		}
+		stmt.Close()
+	}
+}
@@ -0,0 +1,170 @@
+package cql
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// RegisterTLSConfig registers a tls config that config strings can use with tlsConfig=name.
+// The config is cloned, changes made to it after registering are not used.
+func RegisterTLSConfig(name string, tlsConfig *tls.Config) error {
+	if name == "" || name == "true" || name == "false" {
+		return ErrTLSConfigNameInvalid
+	}
+	if tlsConfig == nil {
+		return ErrTLSConfigIsNil
+	}
+
+	tlsConfigRegistry.mutex.Lock()
+	tlsConfigRegistry.configs[name] = tlsConfig.Clone()
+	tlsConfigRegistry.deleteBuilt(name)
+	tlsConfigRegistry.mutex.Unlock()
+	return nil
+}
+
+// DeregisterTLSConfig removes a tls config registered with RegisterTLSConfig
+func DeregisterTLSConfig(name string) {
+	tlsConfigRegistry.mutex.Lock()
+	delete(tlsConfigRegistry.configs, name)
+	tlsConfigRegistry.deleteBuilt(name)
+	tlsConfigRegistry.mutex.Unlock()
+}
+
+// deleteBuilt removes the tls configs built from a registered tls config, the mutex must be locked.
+// Cluster configs still using them are no longer converted to tlsConfig=name.
+func (registry *tlsConfigRegistryStruct) deleteBuilt(name string) {
+	for settings, tlsConfig := range registry.built {
+		if settings.name == name {
+			delete(registry.built, settings)
+			delete(registry.settings, tlsConfig)
+		}
+	}
+}
+
+// newTLSConfig returns the tls config for the tls config string keys.
+// The tls config is shared by all cluster configs with the same tls settings, clone it before changing it.
+func newTLSConfig(name string, serverName string, minVersion uint16, insecureSkipVerify bool) (*tls.Config, error) {
+	settings := tlsSettingsStruct{name: name, serverName: serverName, minVersion: minVersion, insecureSkipVerify: insecureSkipVerify}
+
+	tlsConfigRegistry.mutex.Lock()
+	defer tlsConfigRegistry.mutex.Unlock()
+
+	if tlsConfig, ok := tlsConfigRegistry.built[settings]; ok {
+		return tlsConfig, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if name != "" {
+		registered, ok := tlsConfigRegistry.configs[name]
+		if !ok {
+			return nil, fmt.Errorf("failed for: tlsConfig = %v", name)
+		}
+		tlsConfig = registered.Clone()
+	}
+
+	if serverName != "" {
+		tlsConfig.ServerName = serverName
+	}
+	if minVersion != 0 {
+		tlsConfig.MinVersion = minVersion
+	}
+	if insecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	tlsConfigRegistry.built[settings] = tlsConfig
+	tlsConfigRegistry.settings[tlsConfig] = settings
+	return tlsConfig, nil
+}
+
+// tlsConfigSettings returns the tls settings a tls config was built from by newTLSConfig
+func tlsConfigSettings(tlsConfig *tls.Config) (tlsSettingsStruct, bool) {
+	tlsConfigRegistry.mutex.RLock()
+	settings, ok := tlsConfigRegistry.settings[tlsConfig]
+	tlsConfigRegistry.mutex.RUnlock()
+	return settings, ok
+}
+
+// verifyHostNames moves the tls handshake of a cluster config that verifies hosts without a tls server name to a hostTLSDialerStruct.
+// gocql does not set the tls server name from the host, so the handshake would fail for every host.
+func verifyHostNames(clusterConfig *gocql.ClusterConfig) error {
+	sslOpts := clusterConfig.SslOpts
+	if sslOpts == nil || !sslOpts.EnableHostVerification || (sslOpts.Config != nil && sslOpts.Config.ServerName != "") {
+		return nil
+	}
+
+	// same as the gocql ssl options setup
+	tlsConfig := &tls.Config{}
+	if sslOpts.Config != nil {
+		tlsConfig = sslOpts.Config.Clone()
+	}
+	if sslOpts.CaPath != "" {
+		if tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(sslOpts.CaPath)
+		if err != nil {
+			return fmt.Errorf("unable to open CA certs: %v", err)
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("failed parsing CA certs")
+		}
+	}
+	if sslOpts.CertPath != "" || sslOpts.KeyPath != "" {
+		certificate, err := tls.LoadX509KeyPair(sslOpts.CertPath, sslOpts.KeyPath)
+		if err != nil {
+			return fmt.Errorf("unable to load X509 key pair: %v", err)
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certificate)
+	}
+	tlsConfig.InsecureSkipVerify = false
+
+	dialer := clusterConfig.Dialer
+	if dialer == nil {
+		netDialer := &net.Dialer{Timeout: clusterConfig.ConnectTimeout}
+		if clusterConfig.SocketKeepalive > 0 {
+			netDialer.KeepAlive = clusterConfig.SocketKeepalive
+		}
+		dialer = netDialer
+	}
+
+	clusterConfig.Dialer = &hostTLSDialerStruct{dialer: dialer, tlsConfig: tlsConfig}
+	clusterConfig.SslOpts = nil
+	// gocql does not coalesce writes with tls
+	clusterConfig.WriteCoalesceWaitTime = 0
+	return nil
+}
+
+// DialContext dials the address and does the tls handshake, verifying the host against the address host name
+func (hostTLSDialer *hostTLSDialerStruct) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := hostTLSDialer.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := hostTLSDialer.tlsConfig.Clone()
+	tlsConfig.ServerName = host
+	tlsConn := tls.Client(conn, tlsConfig)
+	if deadline, ok := ctx.Deadline(); ok {
+		tlsConn.SetDeadline(deadline)
+	}
+	err = tlsConn.Handshake()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
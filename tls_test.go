@@ -0,0 +1,316 @@
+package cql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MichaelS11/go-cql-driver/cqltest"
+)
+
+// testNewCertificates returns a ca pool and a server certificate for the hosts, a host is a host name or an ip address
+func testNewCertificates(t *testing.T, hosts ...string) (*x509.CertPool, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey error - received: %v - expected: %v ", err, nil)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cqltest ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate error - received: %v - expected: %v ", err, nil)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("ParseCertificate error - received: %v - expected: %v ", err, nil)
+	}
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey error - received: %v - expected: %v ", err, nil)
+	}
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			serverTemplate.IPAddresses = append(serverTemplate.IPAddresses, ip)
+		} else {
+			serverTemplate.DNSNames = append(serverTemplate.DNSNames, host)
+		}
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTemplate, ca, &serverKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate error - received: %v - expected: %v ", err, nil)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	return roots, tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}
+}
+
+func TestRegisterTLSConfig(t *testing.T) {
+	err := RegisterTLSConfig("", &tls.Config{})
+	if err != ErrTLSConfigNameInvalid {
+		t.Fatalf("RegisterTLSConfig error - received: %v - expected: %v ", err, ErrTLSConfigNameInvalid)
+	}
+	err = RegisterTLSConfig("true", &tls.Config{})
+	if err != ErrTLSConfigNameInvalid {
+		t.Fatalf("RegisterTLSConfig error - received: %v - expected: %v ", err, ErrTLSConfigNameInvalid)
+	}
+	err = RegisterTLSConfig("test", nil)
+	if err != ErrTLSConfigIsNil {
+		t.Fatalf("RegisterTLSConfig error - received: %v - expected: %v ", err, ErrTLSConfigIsNil)
+	}
+
+	tlsConfig := &tls.Config{ServerName: "one"}
+	err = RegisterTLSConfig("test", tlsConfig)
+	if err != nil {
+		t.Fatalf("RegisterTLSConfig error - received: %v - expected: %v ", err, nil)
+	}
+	defer DeregisterTLSConfig("test")
+	tlsConfig.ServerName = "changed"
+
+	clusterConfig, err := ConfigStringToClusterConfig("?tlsConfig=test")
+	if err != nil {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, nil)
+	}
+	if clusterConfig.SslOpts == nil || clusterConfig.SslOpts.Config == nil {
+		t.Fatalf("SslOpts - received: %v - expected: %v ", clusterConfig.SslOpts, "tls config")
+	}
+	if clusterConfig.SslOpts.ServerName != "one" {
+		t.Fatalf("ServerName - received: %v - expected: %v ", clusterConfig.SslOpts.ServerName, "one")
+	}
+	if !clusterConfig.SslOpts.EnableHostVerification {
+		t.Fatalf("EnableHostVerification - received: %v - expected: %v ", clusterConfig.SslOpts.EnableHostVerification, true)
+	}
+	configString := ClusterConfigToConfigString(clusterConfig)
+	expected := "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&tls=true&tlsConfig=test"
+	if configString != expected {
+		t.Fatalf("configString - received: %v - expected: %v ", configString, expected)
+	}
+
+	// registering the name again builds a new tls config
+	err = RegisterTLSConfig("test", &tls.Config{ServerName: "two"})
+	if err != nil {
+		t.Fatalf("RegisterTLSConfig error - received: %v - expected: %v ", err, nil)
+	}
+	clusterConfigTwo, err := ConfigStringToClusterConfig("?tlsConfig=test")
+	if err != nil {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, nil)
+	}
+	if clusterConfigTwo.SslOpts.Config == clusterConfig.SslOpts.Config || clusterConfigTwo.SslOpts.ServerName != "two" {
+		t.Fatalf("ServerName - received: %v - expected: %v ", clusterConfigTwo.SslOpts.ServerName, "two")
+	}
+	configString = ClusterConfigToConfigString(clusterConfig)
+	expected = "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&tls=true&tlsServerName=one"
+	if configString != expected {
+		t.Fatalf("configString - received: %v - expected: %v ", configString, expected)
+	}
+
+	DeregisterTLSConfig("test")
+	_, err = ConfigStringToClusterConfig("?tlsConfig=test")
+	expectedError := "failed for: tlsConfig = test"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("ConfigStringToClusterConfig error - received: %v - expected: %v ", err, expectedError)
+	}
+}
+
+func TestConfigStringTLS(t *testing.T) {
+	err := RegisterTLSConfig("configString", &tls.Config{ServerName: "two"})
+	if err != nil {
+		t.Fatalf("RegisterTLSConfig error - received: %v - expected: %v ", err, nil)
+	}
+	defer DeregisterTLSConfig("configString")
+
+	tests := []struct {
+		info                   string
+		configString           string
+		serverName             string
+		minVersion             uint16
+		insecureSkipVerify     bool
+		enableHostVerification bool
+		toConfigString         string
+		err                    error
+	}{
+		{info: "invalid tls", configString: "?tls=foobar", err: fmt.Errorf("failed for: tls = foobar")},
+		{info: "empty tlsServerName", configString: "?tlsServerName=", err: fmt.Errorf("failed for: tlsServerName = ")},
+		{info: "invalid tlsMinVersion", configString: "?tlsMinVersion=1.4&tlsServerName=one", err: fmt.Errorf("failed for: tlsMinVersion = 1.4")},
+		{info: "invalid tlsInsecureSkipVerify", configString: "?tlsInsecureSkipVerify=foobar", err: fmt.Errorf("failed for: tlsInsecureSkipVerify = foobar")},
+		{info: "empty tlsConfig", configString: "?tlsConfig=", err: fmt.Errorf("failed for: tlsConfig = ")},
+		{info: "tls false with settings", configString: "?tls=false&tlsServerName=one", err: fmt.Errorf("tls settings require tls=true")},
+
+		{info: "tls without tlsServerName", configString: "?tls=true", enableHostVerification: true,
+			toConfigString: "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&tls=true"},
+		{info: "tlsServerName", configString: "?tls=true&tlsServerName=cassandra.example.com", serverName: "cassandra.example.com", enableHostVerification: true,
+			toConfigString: "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&tls=true&tlsServerName=cassandra.example.com"},
+		{info: "tlsMinVersion", configString: "?tlsMinVersion=1.2&tlsServerName=one", serverName: "one", minVersion: tls.VersionTLS12, enableHostVerification: true,
+			toConfigString: "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&tls=true&tlsServerName=one&tlsMinVersion=1.2"},
+		{info: "tlsConfig", configString: "?tlsConfig=configString", serverName: "two", enableHostVerification: true,
+			toConfigString: "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&tls=true&tlsConfig=configString"},
+		{info: "tlsConfig tlsServerName", configString: "?tlsConfig=configString&tlsServerName=one", serverName: "one", enableHostVerification: true,
+			toConfigString: "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&tls=true&tlsServerName=one&tlsConfig=configString"},
+		{info: "tlsInsecureSkipVerify", configString: "?tls=true&tlsInsecureSkipVerify=true&tlsServerName=one", serverName: "one", insecureSkipVerify: true,
+			toConfigString: "127.0.0.1?timeout=600ms&connectTimeout=600ms&numConns=2&tls=true&tlsServerName=one&tlsInsecureSkipVerify=true"},
+	}
+
+	for _, test := range tests {
+		clusterConfig, err := ConfigStringToClusterConfig(test.configString)
+		if err == nil || test.err == nil {
+			if err != test.err {
+				t.Errorf("error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+				continue
+			}
+		} else if err.Error() != test.err.Error() {
+			t.Errorf("error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+			continue
+		}
+		if test.err != nil {
+			continue
+		}
+
+		sslOpts := clusterConfig.SslOpts
+		if sslOpts == nil || sslOpts.Config == nil {
+			t.Errorf("SslOpts - received: %v - expected: %v - info: %v", sslOpts, "tls config", test.info)
+			continue
+		}
+		if sslOpts.ServerName != test.serverName {
+			t.Errorf("ServerName - received: %v - expected: %v - info: %v", sslOpts.ServerName, test.serverName, test.info)
+		}
+		if sslOpts.MinVersion != test.minVersion {
+			t.Errorf("MinVersion - received: %v - expected: %v - info: %v", sslOpts.MinVersion, test.minVersion, test.info)
+		}
+		if sslOpts.InsecureSkipVerify != test.insecureSkipVerify {
+			t.Errorf("InsecureSkipVerify - received: %v - expected: %v - info: %v", sslOpts.InsecureSkipVerify, test.insecureSkipVerify, test.info)
+		}
+		if sslOpts.EnableHostVerification != test.enableHostVerification {
+			t.Errorf("EnableHostVerification - received: %v - expected: %v - info: %v", sslOpts.EnableHostVerification, test.enableHostVerification, test.info)
+		}
+		configString := ClusterConfigToConfigString(clusterConfig)
+		if configString != test.toConfigString {
+			t.Errorf("configString - received: %v - expected: %v - info: %v", configString, test.toConfigString, test.info)
+		}
+	}
+}
+
+func TestSqlTLS(t *testing.T) {
+	roots, certificate := testNewCertificates(t, "localhost", "127.0.0.1")
+	server, err := cqltest.NewTLSServer(&tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		t.Fatalf("NewTLSServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+
+	err = RegisterTLSConfig("cqltest", &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("RegisterTLSConfig error - received: %v - expected: %v ", err, nil)
+	}
+	defer DeregisterTLSConfig("cqltest")
+
+	tests := []struct {
+		info     string
+		settings string
+		ok       bool
+	}{
+		{info: "tlsConfig tlsServerName", settings: "tlsConfig=cqltest&tlsServerName=localhost", ok: true},
+		{info: "tlsConfig insecure", settings: "tlsConfig=cqltest&tlsInsecureSkipVerify=true", ok: true},
+		{info: "tlsConfig tlsMinVersion", settings: "tlsConfig=cqltest&tlsServerName=localhost&tlsMinVersion=1.3", ok: true},
+		{info: "tlsConfig wrong tlsServerName", settings: "tlsConfig=cqltest&tlsServerName=other", ok: false},
+		{info: "tlsConfig host verification", settings: "tlsConfig=cqltest", ok: true},
+		{info: "tls system roots host verification", settings: "tls=true", ok: false},
+		{info: "tls system roots", settings: "tls=true&tlsServerName=localhost", ok: false},
+		{info: "tls insecure", settings: "tls=true&tlsInsecureSkipVerify=true", ok: true},
+		{info: "no tls", settings: "tls=false", ok: false},
+	}
+
+	for _, test := range tests {
+		db, err := sql.Open("cql", server.Addr()+"?timeout=2s&connectTimeout=2s&"+test.settings)
+		if err != nil {
+			t.Fatalf("Open error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+		var key string
+		err = db.QueryRowContext(ctx, "select key from system.local where key = ?", "local").Scan(&key)
+		cancel()
+		if test.ok && (err != nil || key != "local") {
+			t.Errorf("QueryRowContext - received: %v, %v - expected: %v - info: %v", key, err, "local", test.info)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("QueryRowContext error - received: %v - expected: %v - info: %v", err, "error", test.info)
+		}
+
+		err = db.Close()
+		if err != nil {
+			t.Fatalf("Close error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+	}
+}
+
+func TestSqlTLSHostVerification(t *testing.T) {
+	roots, certificate := testNewCertificates(t, "cassandra.example.com")
+	server, err := cqltest.NewTLSServer(&tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		t.Fatalf("NewTLSServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+
+	err = RegisterTLSConfig("cqltestHost", &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("RegisterTLSConfig error - received: %v - expected: %v ", err, nil)
+	}
+	defer DeregisterTLSConfig("cqltestHost")
+
+	tests := []struct {
+		info     string
+		settings string
+		ok       bool
+	}{
+		{info: "host address not in certificate", settings: "tlsConfig=cqltestHost", ok: false},
+		{info: "tlsServerName", settings: "tlsConfig=cqltestHost&tlsServerName=cassandra.example.com", ok: true},
+	}
+
+	for _, test := range tests {
+		db, err := sql.Open("cql", server.Addr()+"?timeout=2s&connectTimeout=2s&"+test.settings)
+		if err != nil {
+			t.Fatalf("Open error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+		err = db.PingContext(ctx)
+		cancel()
+		if test.ok && err != nil {
+			t.Errorf("PingContext error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("PingContext error - received: %v - expected: %v - info: %v", err, "error", test.info)
+		}
+
+		err = db.Close()
+		if err != nil {
+			t.Fatalf("Close error - received: %v - expected: %v - info: %v", err, nil, test.info)
+		}
+	}
+}
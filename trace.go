@@ -0,0 +1,101 @@
+package cql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// applyTrace sets a tracer on the query when the context has a trace collector or the connection traces every query
+func (cqlStmt *CqlStmt) applyTrace(ctx context.Context, query *gocql.Query) {
+	var collector TraceCollector
+	if traceCollector := queryOptionsFromContext(ctx).traceCollector; traceCollector != nil {
+		collector = *traceCollector
+	}
+	if collector == nil && (cqlStmt.conn == nil || !cqlStmt.conn.trace) {
+		return
+	}
+	if cqlStmt.session == nil {
+		return
+	}
+
+	tracer := &tracerStruct{
+		ctx:       ctx,
+		session:   cqlStmt.session,
+		collector: collector,
+	}
+	if cqlStmt.conn != nil {
+		tracer.logger = cqlStmt.conn.logger
+	}
+	query.Trace(tracer)
+}
+
+// Trace reads the tracing session from system_traces and passes it to the collector, or logs it when there is no collector
+func (tracer *tracerStruct) Trace(traceID []byte) {
+	trace, err := tracer.readTrace(traceID)
+	if err != nil {
+		if tracer.logger != nil {
+			tracer.logger.Print("Trace error: ", err)
+		}
+		return
+	}
+
+	if tracer.collector != nil {
+		tracer.collector(trace)
+		return
+	}
+	if tracer.logger != nil {
+		tracer.logger.Print(trace)
+	}
+}
+
+// readTrace returns the tracing session and events of the trace id.
+// The session is only partly filled in when the cluster has not finished writing it.
+func (tracer *tracerStruct) readTrace(traceID []byte) (*Trace, error) {
+	sessionID, err := gocql.UUIDFromBytes(traceID)
+	if err != nil {
+		return nil, err
+	}
+	trace := &Trace{
+		SessionID: sessionID,
+	}
+
+	var duration int
+	err = tracer.session.Query("select client, command, coordinator, duration, parameters, request, started_at from system_traces.sessions where session_id = ?",
+		sessionID).WithContext(tracer.ctx).Consistency(gocql.One).Scan(
+		&trace.Client, &trace.Command, &trace.Coordinator, &duration, &trace.Parameters, &trace.Request, &trace.StartedAt)
+	if err != nil && err != gocql.ErrNotFound {
+		return nil, err
+	}
+	trace.Duration = time.Duration(duration) * time.Microsecond
+
+	iter := tracer.session.Query("select event_id, activity, source, source_elapsed, thread from system_traces.events where session_id = ?",
+		sessionID).WithContext(tracer.ctx).Consistency(gocql.One).Iter()
+	var event TraceEvent
+	var sourceElapsed int
+	for iter.Scan(&event.EventID, &event.Activity, &event.Source, &sourceElapsed, &event.Thread) {
+		event.Time = event.EventID.Time()
+		event.SourceElapsed = time.Duration(sourceElapsed) * time.Microsecond
+		trace.Events = append(trace.Events, event)
+	}
+	err = iter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return trace, nil
+}
+
+// String returns the trace in a readable form with one line per event
+func (trace *Trace) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Tracing session %v (coordinator: %v, duration: %v): %v", trace.SessionID, trace.Coordinator, trace.Duration, trace.Parameters["query"])
+	for _, event := range trace.Events {
+		fmt.Fprintf(&builder, "\n%v: %v (source: %v, elapsed: %v, thread: %v)",
+			event.Time.UTC().Format("2006/01/02 15:04:05.999999"), event.Activity, event.Source, event.SourceElapsed, event.Thread)
+	}
+	return builder.String()
+}
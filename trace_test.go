@@ -0,0 +1,127 @@
+package cql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MichaelS11/go-cql-driver/cqltest"
+	"github.com/gocql/gocql"
+)
+
+func TestTraceString(t *testing.T) {
+	eventID := gocql.UUIDFromTime(time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC))
+	trace := &Trace{
+		SessionID:   gocql.UUID{1},
+		Coordinator: "127.0.0.1",
+		Duration:    1500 * time.Microsecond,
+		Parameters:  map[string]string{"query": "select key from system.local"},
+		Events: []TraceEvent{
+			{EventID: eventID, Time: eventID.Time(), Activity: "Parsing", Source: "127.0.0.1", SourceElapsed: 10 * time.Microsecond, Thread: "thread-1"},
+		},
+	}
+
+	expected := "Tracing session 01000000-0000-0000-0000-000000000000 (coordinator: 127.0.0.1, duration: 1.5ms): select key from system.local\n" +
+		"2020/01/02 03:04:05.000006: Parsing (source: 127.0.0.1, elapsed: 10µs, thread: thread-1)"
+	if trace.String() != expected {
+		t.Fatalf("String - received: %v - expected: %v ", trace.String(), expected)
+	}
+}
+
+func TestSqlTrace(t *testing.T) {
+	server, err := cqltest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+
+	db, err := sql.Open("cql", server.Addr()+"?timeout=2s&connectTimeout=2s")
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	defer cancel()
+
+	// the statement is prepared first so only the execute request is traced
+	stmt, err := db.PrepareContext(ctx, "select key from system.local where key = ?")
+	if err != nil {
+		t.Fatalf("PrepareContext error - received: %v - expected: %v ", err, nil)
+	}
+	defer stmt.Close()
+
+	var traces []*Trace
+	var key string
+	err = stmt.QueryRowContext(WithTrace(ctx, func(trace *Trace) {
+		traces = append(traces, trace)
+	}), "local").Scan(&key)
+	if err != nil {
+		t.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+	}
+	if len(traces) != 1 {
+		t.Fatalf("traces - received: %v - expected: %v ", len(traces), 1)
+	}
+	trace := traces[0]
+	if trace.SessionID == (gocql.UUID{}) {
+		t.Fatalf("SessionID - received: %v - expected: %v ", trace.SessionID, "not empty")
+	}
+	if trace.Parameters["query"] != "select key from system.local where key = ?" {
+		t.Fatalf("Parameters query - received: %v - expected: %v ", trace.Parameters["query"], "select key from system.local where key = ?")
+	}
+	if trace.Coordinator == "" || trace.StartedAt.IsZero() {
+		t.Fatalf("Coordinator StartedAt - received: %v %v - expected: %v ", trace.Coordinator, trace.StartedAt, "not empty")
+	}
+	if len(trace.Events) != 4 {
+		t.Fatalf("Events - received: %v - expected: %v ", len(trace.Events), 4)
+	}
+	if trace.Events[0].Activity == "" || trace.Events[0].Time.IsZero() {
+		t.Fatalf("Events[0] - received: %+v - expected: %v ", trace.Events[0], "activity and time")
+	}
+
+	// without WithTrace the query is not traced
+	traces = nil
+	_, err = stmt.ExecContext(ctx, "local")
+	if err != nil {
+		t.Fatalf("ExecContext error - received: %v - expected: %v ", err, nil)
+	}
+	if len(traces) != 0 {
+		t.Fatalf("traces - received: %v - expected: %v ", len(traces), 0)
+	}
+}
+
+func TestSqlTraceConfigString(t *testing.T) {
+	server, err := cqltest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error - received: %v - expected: %v ", err, nil)
+	}
+	defer server.Close()
+
+	var buffer bytes.Buffer
+	logger := CqlDriver.Logger
+	CqlDriver.Logger = log.New(&buffer, "", 0)
+	defer func() {
+		CqlDriver.Logger = logger
+	}()
+
+	db, err := sql.Open("cql", server.Addr()+"?timeout=2s&connectTimeout=2s&trace=true")
+	if err != nil {
+		t.Fatalf("Open error - received: %v - expected: %v ", err, nil)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutValid)
+	defer cancel()
+
+	var version string
+	err = db.QueryRowContext(ctx, "select release_version from system.local").Scan(&version)
+	if err != nil {
+		t.Fatalf("Scan error - received: %v - expected: %v ", err, nil)
+	}
+	if !strings.Contains(buffer.String(), "Tracing session") || !strings.Contains(buffer.String(), "select release_version from system.local") {
+		t.Fatalf("log - received: %v - expected: %v ", buffer.String(), "Tracing session")
+	}
+}
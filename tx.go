@@ -0,0 +1,62 @@
+package cql
+
+import (
+	"database/sql/driver"
+)
+
+// Commit sends the buffered transaction statements as one batch
+func (cqlTx *cqlTxStruct) Commit() error {
+	cqlTx.done()
+
+	if len(cqlTx.batch.Entries) < 1 {
+		return nil
+	}
+
+	err := cqlTx.session.ExecuteBatch(cqlTx.batch)
+	for i := 0; i < len(cqlTx.binders); i++ {
+		if cqlTx.binders[i].err != nil {
+			return cqlTx.binders[i].err
+		}
+	}
+
+	return err
+}
+
+// Rollback discards the buffered transaction statements
+func (cqlTx *cqlTxStruct) Rollback() error {
+	cqlTx.done()
+	return nil
+}
+
+// done removes the transaction from the connection
+func (cqlTx *cqlTxStruct) done() {
+	if cqlTx.conn.tx == cqlTx {
+		cqlTx.conn.tx = nil
+	}
+}
+
+// exec adds a statement to the transaction batch
+func (cqlTx *cqlTxStruct) exec(statement string, args []driver.NamedValue) (driver.Result, error) {
+	switch statementType(statement) {
+	case "insert", "update", "delete":
+	default:
+		return nil, ErrTxStatementNotSupported
+	}
+
+	if hasNamedValues(args) {
+		binder := &namedBinderStruct{
+			namedValues: args,
+		}
+		cqlTx.batch.Bind(statement, binder.bind)
+		cqlTx.binders = append(cqlTx.binders, binder)
+		return cqlResultStruct{}, nil
+	}
+
+	values, err := namedValuesToInterface(args)
+	if err != nil {
+		return nil, err
+	}
+	cqlTx.batch.Query(statement, values...)
+
+	return cqlResultStruct{}, nil
+}
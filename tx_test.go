@@ -0,0 +1,77 @@
+package cql
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+func TestTxExec(t *testing.T) {
+	cqlConn := &cqlConnStruct{}
+	cqlTx := &cqlTxStruct{
+		conn:  cqlConn,
+		batch: gocql.NewBatch(gocql.LoggedBatch),
+	}
+	cqlConn.tx = cqlTx
+
+	tests := []struct {
+		info      string
+		statement string
+		args      []driver.NamedValue
+		err       error
+	}{
+		{info: "select", statement: "select a from b", err: ErrTxStatementNotSupported},
+		{info: "create", statement: "create table a (b text primary key)", err: ErrTxStatementNotSupported},
+		{info: "batch", statement: "begin batch insert into a (b) values (1) apply batch", err: ErrTxStatementNotSupported},
+		{info: "ordinal out of range", statement: "insert into a (b) values (?)", args: []driver.NamedValue{{Ordinal: 2, Value: 1}}, err: ErrOrdinalOutOfRange},
+		{info: "insert", statement: "insert into a (b) values (?)", args: []driver.NamedValue{{Ordinal: 1, Value: 1}}},
+		{info: "update named", statement: "update a set b = :b where c = :c", args: []driver.NamedValue{{Name: "c", Ordinal: 1, Value: 1}, {Name: "b", Ordinal: 2, Value: 2}}},
+		{info: "delete", statement: "delete from a where b = 1"},
+	}
+
+	for _, test := range tests {
+		result, err := cqlTx.exec(test.statement, test.args)
+		if err != test.err {
+			t.Fatalf("exec error - received: %v - expected: %v - info: %v", err, test.err, test.info)
+		}
+		if err != nil && result != nil {
+			t.Fatalf("result is not nil - info: %v", test.info)
+		}
+	}
+
+	if len(cqlTx.batch.Entries) != 3 {
+		t.Fatalf("Entries len - received: %v - expected: %v ", len(cqlTx.batch.Entries), 3)
+	}
+	if len(cqlTx.binders) != 1 {
+		t.Fatalf("binders len - received: %v - expected: %v ", len(cqlTx.binders), 1)
+	}
+	if cqlTx.batch.Entries[0].Stmt != "insert into a (b) values (?)" {
+		t.Fatalf("Entries[0] Stmt - received: %v - expected: %v ", cqlTx.batch.Entries[0].Stmt, "insert into a (b) values (?)")
+	}
+
+	err := cqlTx.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback error - received: %v - expected: %v ", err, nil)
+	}
+	if cqlConn.tx != nil {
+		t.Fatal("cqlConn.tx is not nil")
+	}
+}
+
+func TestTxCommitEmpty(t *testing.T) {
+	cqlConn := &cqlConnStruct{}
+	cqlTx := &cqlTxStruct{
+		conn:  cqlConn,
+		batch: gocql.NewBatch(gocql.LoggedBatch),
+	}
+	cqlConn.tx = cqlTx
+
+	err := cqlTx.Commit()
+	if err != nil {
+		t.Fatalf("Commit error - received: %v - expected: %v ", err, nil)
+	}
+	if cqlConn.tx != nil {
+		t.Fatal("cqlConn.tx is not nil")
+	}
+}
@@ -0,0 +1,140 @@
+package cql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+)
+
+// Scan implements the sql.Scanner interface, accepts any slice or array
+func (list *List) Scan(src interface{}) error {
+	values, err := scanSlice(src, "List")
+	if err != nil {
+		return err
+	}
+	*list = values
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (list List) Value() (driver.Value, error) {
+	if list == nil {
+		return nil, nil
+	}
+	return []interface{}(list), nil
+}
+
+// Scan implements the sql.Scanner interface, accepts any slice or array
+func (set *Set) Scan(src interface{}) error {
+	values, err := scanSlice(src, "Set")
+	if err != nil {
+		return err
+	}
+	*set = values
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (set Set) Value() (driver.Value, error) {
+	if set == nil {
+		return nil, nil
+	}
+	return []interface{}(set), nil
+}
+
+// Scan implements the sql.Scanner interface, accepts any map
+func (aMap *Map) Scan(src interface{}) error {
+	if src == nil {
+		*aMap = nil
+		return nil
+	}
+
+	rv := reflect.ValueOf(src)
+	if rv.Kind() != reflect.Map {
+		return fmt.Errorf("cannot scan %T into Map", src)
+	}
+
+	values := make(Map, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		values[iter.Key().Interface()] = iter.Value().Interface()
+	}
+	*aMap = values
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (aMap Map) Value() (driver.Value, error) {
+	if aMap == nil {
+		return nil, nil
+	}
+	return map[interface{}]interface{}(aMap), nil
+}
+
+// Scan implements the sql.Scanner interface, accepts any slice or array
+func (tuple *Tuple) Scan(src interface{}) error {
+	values, err := scanSlice(src, "Tuple")
+	if err != nil {
+		return err
+	}
+	*tuple = values
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (tuple Tuple) Value() (driver.Value, error) {
+	if tuple == nil {
+		return nil, nil
+	}
+	return []interface{}(tuple), nil
+}
+
+// Scan implements the sql.Scanner interface, accepts a map with string keys
+func (udt *UDT) Scan(src interface{}) error {
+	if src == nil {
+		*udt = nil
+		return nil
+	}
+
+	rv := reflect.ValueOf(src)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("cannot scan %T into UDT", src)
+	}
+
+	values := make(UDT, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		values[iter.Key().String()] = iter.Value().Interface()
+	}
+	*udt = values
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (udt UDT) Value() (driver.Value, error) {
+	if udt == nil {
+		return nil, nil
+	}
+	return map[string]interface{}(udt), nil
+}
+
+// scanSlice converts a slice or array to a slice of interface
+func scanSlice(src interface{}, name string) ([]interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(src)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot scan %T into %v", src, name)
+	}
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
+		return nil, nil
+	}
+
+	values := make([]interface{}, rv.Len())
+	for i := 0; i < len(values); i++ {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, nil
+}
@@ -0,0 +1,98 @@
+package cql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+func TestTypesScan(t *testing.T) {
+	tests := []struct {
+		info    string
+		scanner sql.Scanner
+		src     interface{}
+		dest    interface{}
+		err     bool
+	}{
+		{info: "List nil", scanner: &List{1}, src: nil, dest: &List{}},
+		{info: "List strings", scanner: &List{}, src: []string{"a", "b"}, dest: &List{"a", "b"}},
+		{info: "List array", scanner: &List{}, src: [2]int{1, 2}, dest: &List{1, 2}},
+		{info: "List error", scanner: &List{}, src: "a", err: true},
+		{info: "Set ints", scanner: &Set{}, src: []int{1, 2}, dest: &Set{1, 2}},
+		{info: "Set error", scanner: &Set{}, src: 1, err: true},
+		{info: "Map nil", scanner: &Map{1: 1}, src: nil, dest: &Map{}},
+		{info: "Map", scanner: &Map{}, src: map[string]int{"a": 1}, dest: &Map{"a": 1}},
+		{info: "Map error", scanner: &Map{}, src: []int{1}, err: true},
+		{info: "Tuple", scanner: &Tuple{}, src: []interface{}{1, "a", nil}, dest: &Tuple{1, "a", nil}},
+		{info: "Tuple error", scanner: &Tuple{}, src: 1, err: true},
+		{info: "UDT", scanner: &UDT{}, src: map[string]interface{}{"a": 1}, dest: &UDT{"a": 1}},
+		{info: "UDT nil", scanner: &UDT{"a": 1}, src: nil, dest: &UDT{}},
+		{info: "UDT error", scanner: &UDT{}, src: map[int]interface{}{1: 1}, err: true},
+	}
+
+	for _, test := range tests {
+		err := test.scanner.Scan(test.src)
+		if (err != nil) != test.err {
+			t.Errorf("Scan error - received: %v - expected error: %v - info: %v", err, test.err, test.info)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if test.src == nil {
+			if !reflect.ValueOf(test.scanner).Elem().IsNil() {
+				t.Errorf("Scan - received: %#v - expected: nil - info: %v", test.scanner, test.info)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(test.scanner, test.dest) {
+			t.Errorf("Scan - received: %#v - expected: %#v - info: %v", test.scanner, test.dest, test.info)
+		}
+	}
+}
+
+func TestConverterConvertValue(t *testing.T) {
+	uuid := gocql.TimeUUID()
+	aList := List{1}
+
+	tests := []struct {
+		info  string
+		value interface{}
+		want  driver.Value
+		err   bool
+	}{
+		{info: "nil", value: nil, want: nil},
+		{info: "int", value: 1, want: int64(1)},
+		{info: "string", value: "a", want: "a"},
+		{info: "uint64", value: uint64(1<<63 + 1), want: uint64(1<<63 + 1)},
+		{info: "NullString", value: sql.NullString{String: "a", Valid: true}, want: "a"},
+		{info: "slice", value: []string{"a"}, want: []string{"a"}},
+		{info: "map", value: map[string]int{"a": 1}, want: map[string]int{"a": 1}},
+		{info: "uuid", value: uuid, want: uuid},
+		{info: "List", value: List{1}, want: []interface{}{1}},
+		{info: "List pointer", value: &aList, want: []interface{}{1}},
+		{info: "List nil", value: List(nil), want: nil},
+		{info: "Set", value: Set{1}, want: []interface{}{1}},
+		{info: "Map", value: Map{"a": 1}, want: map[interface{}]interface{}{"a": 1}},
+		{info: "Tuple", value: Tuple{1, "a"}, want: []interface{}{1, "a"}},
+		{info: "UDT", value: UDT{"a": 1}, want: map[string]interface{}{"a": 1}},
+		{info: "struct", value: struct{}{}, err: true},
+	}
+
+	for _, test := range tests {
+		value, err := converter{}.ConvertValue(test.value)
+		if (err != nil) != test.err {
+			t.Errorf("ConvertValue error - received: %v - expected error: %v - info: %v", err, test.err, test.info)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(value, test.want) {
+			t.Errorf("ConvertValue - received: %#v - expected: %#v - info: %v", value, test.want, test.info)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gocql/gocql"
 )
@@ -18,13 +20,29 @@ func valuesToInterface(args []driver.Value) []interface{} {
 	return values
 }
 
-// namedValuesToInterface coverts driver.NamedValue to interface
-func namedValuesToInterface(namedValues []driver.NamedValue) ([]interface{}, error) {
-	values := make([]interface{}, len(namedValues))
+// valuesToNamedValues coverts driver.Value to driver.NamedValue
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	namedValues := make([]driver.NamedValue, len(args))
+	for i := 0; i < len(args); i++ {
+		namedValues[i] = driver.NamedValue{Ordinal: i + 1, Value: args[i]}
+	}
+	return namedValues
+}
+
+// hasNamedValues returns true if any of the driver.NamedValue has a name
+func hasNamedValues(namedValues []driver.NamedValue) bool {
 	for i := 0; i < len(namedValues); i++ {
 		if len(namedValues[i].Name) > 0 {
-			return []interface{}{}, ErrNamedValuesNotSupported
+			return true
 		}
+	}
+	return false
+}
+
+// namedValuesToInterface coverts driver.NamedValue to interface by ordinal, names are ignored
+func namedValuesToInterface(namedValues []driver.NamedValue) ([]interface{}, error) {
+	values := make([]interface{}, len(namedValues))
+	for i := 0; i < len(namedValues); i++ {
 		if namedValues[i].Ordinal < 1 || namedValues[i].Ordinal > len(namedValues) {
 			return []interface{}{}, ErrOrdinalOutOfRange
 		}
@@ -33,6 +51,189 @@ func namedValuesToInterface(namedValues []driver.NamedValue) ([]interface{}, err
 	return values, nil
 }
 
+// statementType returns the lower case statement type, for example select or insert.
+// Batch statements return batch.
+func statementType(statement string) string {
+	statement = strings.TrimLeftFunc(strings.TrimRightFunc(statement, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ';'
+	}), unicode.IsSpace)
+
+	var stmtType string
+	if n := strings.IndexFunc(statement, unicode.IsSpace); n >= 0 {
+		stmtType = strings.ToLower(statement[:n])
+	}
+	if stmtType == "begin" {
+		if n := strings.LastIndexFunc(statement, unicode.IsSpace); n >= 0 {
+			stmtType = strings.ToLower(statement[n+1:])
+		}
+	}
+	return stmtType
+}
+
+// useStatementKeyspace returns the keyspace of a use statement,
+// quoted names keep their case and unquoted names are lower case
+func useStatementKeyspace(statement string) string {
+	statement = strings.TrimLeftFunc(strings.TrimRightFunc(statement, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ';'
+	}), unicode.IsSpace)
+	if len(statement) < 3 {
+		return ""
+	}
+
+	keyspace := strings.TrimSpace(statement[3:])
+	if len(keyspace) > 1 && keyspace[0] == '"' && keyspace[len(keyspace)-1] == '"' {
+		return strings.Replace(keyspace[1:len(keyspace)-1], `""`, `"`, -1)
+	}
+	return strings.ToLower(keyspace)
+}
+
+// isPreparable returns true if gocql will prepare the statement, same logic as gocql Query shouldPrepare
+func isPreparable(statement string) bool {
+	switch statementType(statement) {
+	case "select", "insert", "update", "delete", "batch":
+		return true
+	}
+	return false
+}
+
+// bindMarkerCount returns the number of values needed by a statement, -1 if the statement is not preparable.
+// Every bind marker is counted, a named bind marker used twice needs two values, same as the prepared statement metadata.
+func bindMarkerCount(statement string) int {
+	if !isPreparable(statement) {
+		return -1
+	}
+	positional, named := bindMarkers(statement)
+	return positional + named
+}
+
+// numInput returns the statement NumInput, the bind marker count or -1 if the statement is not preparable.
+// Statements with named bind markers return -1, a named value binds every marker with the name
+// so database/sql must not check the number of args.
+func numInput(statement string) int {
+	if !isPreparable(statement) {
+		return -1
+	}
+	positional, named := bindMarkers(statement)
+	if named > 0 {
+		return -1
+	}
+	return positional
+}
+
+// bindMarkers returns the number of positional and named bind markers in a statement.
+// String literals, quoted identifiers, and comments are skipped.
+func bindMarkers(statement string) (positional int, named int) {
+	length := len(statement)
+	for i := 0; i < length; i++ {
+		if end := skippedEnd(statement, i); end >= 0 {
+			i = end
+			continue
+		}
+		switch {
+		case statement[i] == '?':
+			positional++
+		case statement[i] == ':' && i+1 < length && statement[i+1] == '"':
+			named++
+			i = quotedEnd(statement, i+1)
+		case statement[i] == ':' && i+1 < length && isIdentifierStart(statement[i+1]):
+			named++
+			end := i + 2
+			for end < length && isIdentifierPart(statement[end]) {
+				end++
+			}
+			i = end - 1
+		}
+	}
+
+	return positional, named
+}
+
+// isConditional returns true if the statement is an insert, update, or delete with an if clause,
+// which makes it a lightweight transaction
+func isConditional(statement string) bool {
+	switch statementType(statement) {
+	case "insert", "update", "delete":
+	default:
+		return false
+	}
+
+	length := len(statement)
+	for i := 0; i < length; i++ {
+		if end := skippedEnd(statement, i); end >= 0 {
+			i = end
+			continue
+		}
+		if !isIdentifierPart(statement[i]) {
+			continue
+		}
+		end := i + 1
+		for end < length && isIdentifierPart(statement[end]) {
+			end++
+		}
+		if strings.EqualFold(statement[i:end], "if") && (i == 0 || statement[i-1] != ':') {
+			return true
+		}
+		i = end - 1
+	}
+
+	return false
+}
+
+// skippedEnd returns the index of the last byte of the string literal, quoted identifier, or comment starting at start.
+// Returns -1 if none starts at start. Returns the last index if it is not terminated.
+func skippedEnd(statement string, start int) int {
+	switch {
+	case statement[start] == '\'' || statement[start] == '"':
+		return quotedEnd(statement, start)
+	case strings.HasPrefix(statement[start:], "$$"):
+		end := strings.Index(statement[start+2:], "$$")
+		if end < 0 {
+			return len(statement) - 1
+		}
+		return start + end + 3
+	case strings.HasPrefix(statement[start:], "--"), strings.HasPrefix(statement[start:], "//"):
+		end := strings.IndexByte(statement[start:], '\n')
+		if end < 0 {
+			return len(statement) - 1
+		}
+		return start + end
+	case strings.HasPrefix(statement[start:], "/*"):
+		end := strings.Index(statement[start+2:], "*/")
+		if end < 0 {
+			return len(statement) - 1
+		}
+		return start + end + 3
+	}
+	return -1
+}
+
+// quotedEnd returns the index of the closing quote for the quote at start.
+// A doubled quote is an escaped quote. Returns the last index if there is no closing quote.
+func quotedEnd(statement string, start int) int {
+	quote := statement[start]
+	for i := start + 1; i < len(statement); i++ {
+		if statement[i] != quote {
+			continue
+		}
+		if i+1 < len(statement) && statement[i+1] == quote {
+			i++
+			continue
+		}
+		return i
+	}
+	return len(statement) - 1
+}
+
+// isIdentifierStart returns true if the byte can start an unquoted identifier
+func isIdentifierStart(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// isIdentifierPart returns true if the byte can be part of an unquoted identifier
+func isIdentifierPart(b byte) bool {
+	return isIdentifierStart(b) || (b >= '0' && b <= '9') || b == '_'
+}
+
 // columnInfoToString coverts gocql.ColumnInfo to string
 func columnInfoToString(columnInfo []gocql.ColumnInfo) []string {
 	names := make([]string, len(columnInfo))
@@ -42,13 +243,95 @@ func columnInfoToString(columnInfo []gocql.ColumnInfo) []string {
 	return names
 }
 
-// interfaceToValue coverts interface to driver.Value
+// cqlTypeNames maps gocql native types to CQL type names
+var cqlTypeNames = map[gocql.Type]string{
+	gocql.TypeAscii:     "ASCII",
+	gocql.TypeBigInt:    "BIGINT",
+	gocql.TypeBlob:      "BLOB",
+	gocql.TypeBoolean:   "BOOLEAN",
+	gocql.TypeCounter:   "COUNTER",
+	gocql.TypeDecimal:   "DECIMAL",
+	gocql.TypeDouble:    "DOUBLE",
+	gocql.TypeFloat:     "FLOAT",
+	gocql.TypeInt:       "INT",
+	gocql.TypeText:      "TEXT",
+	gocql.TypeTimestamp: "TIMESTAMP",
+	gocql.TypeUUID:      "UUID",
+	gocql.TypeVarchar:   "TEXT",
+	gocql.TypeVarint:    "VARINT",
+	gocql.TypeTimeUUID:  "TIMEUUID",
+	gocql.TypeInet:      "INET",
+	gocql.TypeDate:      "DATE",
+	gocql.TypeTime:      "TIME",
+	gocql.TypeSmallInt:  "SMALLINT",
+	gocql.TypeTinyInt:   "TINYINT",
+	gocql.TypeDuration:  "DURATION",
+}
+
+// typeInfoToDatabaseTypeName converts gocql.TypeInfo to the CQL type name.
+// Collections, user defined types, and tuples inside of collections are frozen.
+func typeInfoToDatabaseTypeName(typeInfo gocql.TypeInfo) string {
+	switch info := typeInfo.(type) {
+	case gocql.CollectionType:
+		switch info.Type() {
+		case gocql.TypeList:
+			return "LIST<" + frozenTypeName(info.Elem) + ">"
+		case gocql.TypeSet:
+			return "SET<" + frozenTypeName(info.Elem) + ">"
+		case gocql.TypeMap:
+			return "MAP<" + frozenTypeName(info.Key) + ", " + frozenTypeName(info.Elem) + ">"
+		}
+	case gocql.TupleTypeInfo:
+		names := make([]string, len(info.Elems))
+		for i := 0; i < len(info.Elems); i++ {
+			names[i] = frozenTypeName(info.Elems[i])
+		}
+		return "TUPLE<" + strings.Join(names, ", ") + ">"
+	case gocql.UDTTypeInfo:
+		return info.Name
+	}
+
+	if typeInfo.Type() == gocql.TypeCustom {
+		return typeInfo.Custom()
+	}
+	return cqlTypeNames[typeInfo.Type()]
+}
+
+// frozenTypeName returns the CQL type name, wrapped in frozen if the type is a collection or user defined type
+func frozenTypeName(typeInfo gocql.TypeInfo) string {
+	switch typeInfo.Type() {
+	case gocql.TypeList, gocql.TypeSet, gocql.TypeMap, gocql.TypeUDT:
+		return "FROZEN<" + typeInfoToDatabaseTypeName(typeInfo) + ">"
+	}
+	return typeInfoToDatabaseTypeName(typeInfo)
+}
+
+// typeInfoToScanType converts gocql.TypeInfo to the Go type used for the column values
+func typeInfoToScanType(typeInfo gocql.TypeInfo) reflect.Type {
+	switch typeInfo.Type() {
+	case gocql.TypeCustom:
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	case gocql.TypeTuple:
+		return reflect.TypeOf([]interface{}{})
+	}
+	return reflect.TypeOf(typeInfo.New()).Elem()
+}
+
+// interfaceToValue coverts interface to driver.Value.
+// A pointer to a nil pointer is converted to a nil driver.Value
 func interfaceToValue(sourceInterface interface{}) (driver.Value, error) {
 	source := reflect.ValueOf(sourceInterface)
 	if source.Kind() != reflect.Ptr {
 		return driver.Value(nil), fmt.Errorf("source is not a pointer")
 	}
-	return driver.Value(source.Elem().Interface()), nil
+	source = source.Elem()
+	if source.Kind() == reflect.Ptr {
+		if source.IsNil() {
+			return driver.Value(nil), nil
+		}
+		source = source.Elem()
+	}
+	return driver.Value(source.Interface()), nil
 }
 
 // DurationToDuration converts gocql.Duration type to time.Duration.
@@ -0,0 +1,107 @@
+package cql
+
+import (
+	"testing"
+)
+
+func TestBindMarkerCount(t *testing.T) {
+	tests := []struct {
+		info      string
+		statement string
+		count     int
+	}{
+		{info: "empty", statement: "", count: -1},
+		{info: "not preparable", statement: "create table a (b text primary key, c text) where d = ?", count: -1},
+		{info: "none", statement: "select a from b", count: 0},
+		{info: "positional", statement: "insert into a (b, c) values (?, ?)", count: 2},
+		{info: "named", statement: "insert into a (b, c) values (:b, :c)", count: 2},
+		{info: "named repeated", statement: "select a from b where c = :c and d = :C", count: 2},
+		{info: "named quoted", statement: `select a from b where c = :"C" and d = :"C""d"`, count: 2},
+		{info: "positional and named", statement: "update a set b = ? where c = :c", count: 2},
+		{info: "string literal", statement: "select a from b where c = '?:d' and d = 'it''s ?' and e = ?", count: 1},
+		{info: "quoted identifier", statement: `select "?" from b where ":c" = ?`, count: 1},
+		{info: "dollar string", statement: "select a from b where c = $$?:d$$ and d = ?", count: 1},
+		{info: "line comments", statement: "select a from b -- c = ?\n where d = ? // e = ?\n and f = ?", count: 2},
+		{info: "block comment", statement: "select a from b /* c = ? */ where d = ?", count: 1},
+		{info: "map literal", statement: "insert into a (b, c) values (?, {'d': 'e', 'f':'g'})", count: 1},
+		{info: "unterminated comment", statement: "select a from b where c = ? /* d = ?", count: 1},
+		{info: "batch", statement: "begin batch insert into a (b) values (?); insert into a (b) values (?); apply batch", count: 2},
+	}
+
+	for _, test := range tests {
+		count := bindMarkerCount(test.statement)
+		if count != test.count {
+			t.Errorf("count - received: %v - expected: %v - info: %v", count, test.count, test.info)
+		}
+	}
+}
+
+func TestNumInput(t *testing.T) {
+	tests := []struct {
+		info      string
+		statement string
+		numInput  int
+	}{
+		{info: "not preparable", statement: "create table a (b text primary key)", numInput: -1},
+		{info: "positional", statement: "insert into a (b, c) values (?, ?)", numInput: 2},
+		{info: "named", statement: "insert into a (b, c) values (:b, :c)", numInput: -1},
+		{info: "named repeated", statement: "update a set b = :v where c = :v", numInput: -1},
+		{info: "positional and named", statement: "update a set b = ? where c = :c", numInput: -1},
+	}
+
+	for _, test := range tests {
+		count := numInput(test.statement)
+		if count != test.numInput {
+			t.Errorf("numInput - received: %v - expected: %v - info: %v", count, test.numInput, test.info)
+		}
+	}
+}
+
+func TestUseStatementKeyspace(t *testing.T) {
+	tests := []struct {
+		info      string
+		statement string
+		keyspace  string
+	}{
+		{info: "empty", statement: "use"},
+		{info: "unquoted", statement: "use One", keyspace: "one"},
+		{info: "upper case", statement: "  USE\tone ; ", keyspace: "one"},
+		{info: "quoted", statement: `use "One"`, keyspace: "One"},
+		{info: "quoted quote", statement: `use "a""b";`, keyspace: `a"b`},
+	}
+
+	for _, test := range tests {
+		keyspace := useStatementKeyspace(test.statement)
+		if keyspace != test.keyspace {
+			t.Errorf("keyspace - received: %v - expected: %v - info: %v", keyspace, test.keyspace, test.info)
+		}
+	}
+}
+
+func TestIsConditional(t *testing.T) {
+	tests := []struct {
+		info        string
+		statement   string
+		conditional bool
+	}{
+		{info: "empty", statement: ""},
+		{info: "select", statement: "select a from b where c = 'if'"},
+		{info: "create if not exists", statement: "create table if not exists a (b text primary key)"},
+		{info: "insert", statement: "insert into a (b) values (?)"},
+		{info: "insert if not exists", statement: "insert into a (b) values (?) if not exists", conditional: true},
+		{info: "insert IF NOT EXISTS", statement: "INSERT INTO a (b) VALUES (?) IF NOT EXISTS", conditional: true},
+		{info: "update if", statement: "update a set b = ? where c = ? if b = ?", conditional: true},
+		{info: "update if string", statement: "update a set b = 'if' where c = ?"},
+		{info: "update if quoted identifier", statement: `update a set "if" = ? where c = ?`},
+		{info: "update if comment", statement: "update a set b = ? where c = ? -- if b = ?"},
+		{info: "update if in name", statement: "update a set diff = ? where ifa = ?"},
+		{info: "delete if exists", statement: "delete from a where b = ? if exists", conditional: true},
+	}
+
+	for _, test := range tests {
+		conditional := isConditional(test.statement)
+		if conditional != test.conditional {
+			t.Errorf("conditional - received: %v - expected: %v - info: %v", conditional, test.conditional, test.info)
+		}
+	}
+}